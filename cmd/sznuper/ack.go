@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sznuper/sznuper/internal/control"
+)
+
+var ackCmd = &cobra.Command{
+	Use:   "ack <alert_name>",
+	Short: "Acknowledge an unhealthy alert in the running daemon",
+	Long:  "Acknowledges the current incident of an unhealthy alert. Notifications and escalation stop until the alert recovers or an unhealthy event of another type arrives. For keyed alerts, --key acknowledges one entity; without it every unhealthy entity is acknowledged.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags()
+		req := control.AckRequest{}
+		req.Key, _ = f.GetString("key")
+		req.By, _ = f.GetString("by")
+		req.Comment, _ = f.GetString("comment")
+		acks, err := controlClient(cmd).Acknowledge(cmd.Context(), args[0], req)
+		for _, a := range acks {
+			name := args[0]
+			if a.Key != "" {
+				name += "[" + a.Key + "]"
+			}
+			if a.EventType != "" {
+				name += " (" + a.EventType + ")"
+			}
+			if a.By != "" {
+				name += " as " + a.By
+			}
+			fmt.Printf("acknowledged %s\n", name)
+		}
+		return err
+	},
+}
+
+func init() {
+	f := ackCmd.Flags()
+	f.String("key", "", "entity key of a keyed alert (default: all unhealthy entities)")
+	f.String("by", os.Getenv("USER"), "who acknowledges")
+	f.String("comment", "", "reason or note for the acknowledgement")
+	registerControlFlags(ackCmd)
+	rootCmd.AddCommand(ackCmd)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the configuration as loaded",
+	Long: "Prints the config with ${VAR} references expanded and included files merged in, preceded by the list of files it was read from. " +
+		"Alerts are shown as written; with --resolved they are shown after defaults and alert_templates are applied, exactly as they run. Secrets are redacted.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envWarning, err := loadEnvFile()
+		if err != nil {
+			return err
+		}
+		if envWarning != "" {
+			fmt.Fprintln(os.Stderr, "warning: "+envWarning)
+		}
+
+		cfg, err := config.Resolve(cfgFile)
+		if err != nil {
+			return err
+		}
+		applyOptionFlags(cmd, cfg)
+
+		// Included files are already merged in.
+		out := *cfg
+		out.Include = nil
+		if resolved, _ := cmd.Flags().GetBool("resolved"); resolved {
+			out.Defaults, out.AlertTemplates = nil, nil
+		} else {
+			out.Alerts = cfg.Declared
+		}
+		data, err := config.Marshal(&out)
+		if err != nil {
+			return err
+		}
+		for _, f := range cfg.Files {
+			printf("# %s\n", f)
+		}
+		printf("\n%s", data)
+		return nil
+	},
+}
+
+func init() {
+	configShowCmd.Flags().Bool("resolved", false, "show alerts with defaults and alert_templates applied")
+	registerConfigFlags(configShowCmd)
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/control"
+)
+
+// registerControlFlags registers the flags of commands that talk to a
+// running daemon over its control socket.
+func registerControlFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cfgFile, "config", "", "config file path (to find options.control_socket)")
+	cmd.Flags().String("control-socket", "", "daemon control socket path")
+}
+
+// controlClient returns a client for the daemon's control socket: the
+// --control-socket flag, else options.control_socket from the config, else
+// the default path. The config is only consulted for the socket path, so a
+// broken config does not prevent reaching the daemon.
+func controlClient(cmd *cobra.Command) *control.Client {
+	path, _ := cmd.Flags().GetString("control-socket")
+	if path == "" {
+		if cfg, err := config.Resolve(cfgFile); err == nil {
+			path = cfg.Options.ControlSocket
+		}
+	}
+	if path == "" {
+		path = control.DefaultSocketPath()
+	}
+	return control.NewClient(path)
+}
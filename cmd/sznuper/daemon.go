@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/control"
+	"github.com/sznuper/sznuper/internal/cooldown"
+	"github.com/sznuper/sznuper/internal/runner"
+	"github.com/sznuper/sznuper/internal/scheduler"
+	"github.com/sznuper/sznuper/internal/secret"
+	"github.com/sznuper/sznuper/internal/silence"
+	"github.com/sznuper/sznuper/internal/state"
+)
+
+// daemon exposes the running `sznuper start` loop to the control socket.
+// The loop swaps in a new config and scheduler on every reload via setCurrent.
+type daemon struct {
+	cfgPath   string
+	dryRun    bool
+	store     *state.Store
+	silences  *silence.Store
+	startedAt time.Time
+
+	// reloads carries control-socket reload requests into the start loop,
+	// which replies with the load error (nil on success).
+	reloads chan chan error
+
+	mu         sync.Mutex
+	cfg        *config.Config
+	sched      *scheduler.Scheduler
+	schedCtx   context.Context
+	silenceSet *silence.Set
+	reloadedAt time.Time
+	last       map[string]lastRun
+}
+
+type lastRun struct {
+	at        time.Time
+	eventType string
+	err       string
+}
+
+func newDaemon(cfgPath string, dryRun bool, store *state.Store, silences *silence.Store) *daemon {
+	return &daemon{
+		cfgPath:   cfgPath,
+		dryRun:    dryRun,
+		store:     store,
+		silences:  silences,
+		startedAt: time.Now(),
+		reloads:   make(chan chan error),
+		last:      make(map[string]lastRun),
+	}
+}
+
+// setCurrent installs the config, scheduler and silences of the current
+// loop iteration. Last-run info for alerts that no longer exist is dropped.
+func (d *daemon) setCurrent(cfg *config.Config, sched *scheduler.Scheduler, schedCtx context.Context, silences *silence.Set, reloaded bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cfg, d.sched, d.schedCtx, d.silenceSet = cfg, sched, schedCtx, silences
+	if reloaded {
+		d.reloadedAt = time.Now()
+	}
+	keep := make(map[string]bool, len(cfg.Alerts))
+	for _, a := range cfg.Alerts {
+		keep[a.Name] = true
+	}
+	for name := range d.last {
+		if !keep[name] {
+			delete(d.last, name)
+		}
+	}
+}
+
+// record remembers the latest result of an alert for status.
+func (d *daemon) record(res runner.Result) {
+	if res.AlertName == "" {
+		return // digests are not alert runs
+	}
+	lr := lastRun{at: time.Now(), eventType: res.EventType}
+	if res.Err != nil {
+		lr.err = secret.Redact(fmt.Sprintf("%s: %v", res.ErrStage, res.Err))
+	}
+	d.mu.Lock()
+	d.last[res.AlertName] = lr
+	d.mu.Unlock()
+}
+
+func (d *daemon) Status() control.Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	st := control.Status{
+		StartedAt:  d.startedAt,
+		ReloadedAt: d.reloadedAt,
+		ConfigPath: d.cfgPath,
+		DryRun:     d.dryRun,
+		Alerts:     []control.AlertStatus{},
+	}
+	if d.cfg == nil {
+		return st
+	}
+	for i := range d.cfg.Alerts {
+		alert := &d.cfg.Alerts[i]
+		as := control.AlertStatus{Name: alert.Name}
+		for _, t := range alert.Triggers {
+			as.Triggers = append(as.Triggers, describeTrigger(t))
+		}
+		if lr, ok := d.last[alert.Name]; ok {
+			as.LastRun, as.LastEvent, as.LastError = lr.at, lr.eventType, lr.err
+		}
+
+		e := d.store.Entry(alert)
+		as.Healthy, as.Flapping, as.Ack = stateStatus(e.State)
+		as.Cooldowns = cooldownStatus(e.Cooldown)
+		if e.Entities != nil {
+			e.Entities.Each(func(key string, ent *runner.Entity) {
+				es := control.EntityStatus{Key: key, Cooldowns: cooldownStatus(ent.Cooldown)}
+				es.Healthy, es.Flapping, es.Ack = stateStatus(ent.State)
+				as.Entities = append(as.Entities, es)
+			})
+		}
+		st.Alerts = append(st.Alerts, as)
+	}
+	return st
+}
+
+func (d *daemon) Trigger(_ context.Context, name string) ([]control.RunResult, error) {
+	d.mu.Lock()
+	cfg, sched, schedCtx := d.cfg, d.sched, d.schedCtx
+	d.mu.Unlock()
+	if sched == nil {
+		return nil, errors.New("daemon is still starting")
+	}
+
+	var alert *config.Alert
+	for i := range cfg.Alerts {
+		if cfg.Alerts[i].Name == name {
+			alert = &cfg.Alerts[i]
+			break
+		}
+	}
+	if alert == nil {
+		return nil, fmt.Errorf("%w %q", control.ErrUnknownAlert, name)
+	}
+	if scheduler.HasLifecycleTrigger(alert.Triggers) {
+		return nil, fmt.Errorf("alert %q has a lifecycle trigger and only runs on daemon events", name)
+	}
+
+	// The run belongs to the current scheduler: a reload cancels it, a
+	// disconnecting client does not.
+	results := sched.Trigger(schedCtx, alert, scheduler.StartOpts{DryRun: d.dryRun, Store: d.store})
+	out := make([]control.RunResult, len(results))
+	for i, res := range results {
+		out[i] = runResult(res)
+	}
+	return out, nil
+}
+
+func (d *daemon) Acknowledge(_ context.Context, name string, req control.AckRequest) ([]control.Ack, error) {
+	d.mu.Lock()
+	cfg := d.cfg
+	d.mu.Unlock()
+	if cfg == nil {
+		return nil, errors.New("daemon is still starting")
+	}
+
+	var alert *config.Alert
+	for i := range cfg.Alerts {
+		if cfg.Alerts[i].Name == name {
+			alert = &cfg.Alerts[i]
+			break
+		}
+	}
+	if alert == nil {
+		return nil, fmt.Errorf("%w %q", control.ErrUnknownAlert, name)
+	}
+	if alert.Events == nil || len(alert.Events.Healthy) == 0 {
+		return nil, fmt.Errorf("alert %q has no events.healthy and cannot be acknowledged", name)
+	}
+
+	now := time.Now()
+	var acks []control.Ack
+	ack := func(key string, s *runner.AlertState) {
+		if s == nil {
+			return
+		}
+		if a, ok := s.Acknowledge(req.By, req.Comment, now); ok {
+			acks = append(acks, controlAck(key, a))
+		}
+	}
+	e := d.store.Entry(alert)
+	switch {
+	case e.Entities == nil:
+		if req.Key != "" {
+			return nil, fmt.Errorf("alert %q has no events.key", name)
+		}
+		ack("", e.State)
+	case req.Key != "":
+		if ent := e.Entities.Lookup(req.Key); ent != nil {
+			ack(req.Key, ent.State)
+		}
+	default:
+		e.Entities.Each(func(key string, ent *runner.Entity) { ack(key, ent.State) })
+	}
+	if len(acks) == 0 {
+		if req.Key != "" {
+			return nil, fmt.Errorf("%w for alert %q key %q", control.ErrNotUnhealthy, name, req.Key)
+		}
+		return nil, fmt.Errorf("%w for alert %q", control.ErrNotUnhealthy, name)
+	}
+	if err := d.store.Save(); err != nil {
+		return acks, fmt.Errorf("saving alert state: %w", err)
+	}
+	return acks, nil
+}
+
+func (d *daemon) Reload(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case d.reloads <- reply:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *daemon) Silences() []silence.Silence {
+	d.mu.Lock()
+	set := d.silenceSet
+	d.mu.Unlock()
+	return set.List(time.Now())
+}
+
+func (d *daemon) AddSilence(_ context.Context, s silence.Silence) (silence.Silence, error) {
+	return d.silences.Add(s, time.Now())
+}
+
+func (d *daemon) ExpireSilence(_ context.Context, id string) error {
+	return d.silences.Expire(id, time.Now())
+}
+
+func stateStatus(s *runner.AlertState) (*bool, bool, *control.Ack) {
+	if s == nil {
+		return nil, false, nil
+	}
+	v := s.View()
+	var ack *control.Ack
+	if v.Ack != nil {
+		a := controlAck("", *v.Ack)
+		ack = &a
+	}
+	return &v.Healthy, v.Flapping, ack
+}
+
+func controlAck(key string, a runner.Ack) control.Ack {
+	return control.Ack{Key: key, By: a.By, Comment: a.Comment, At: a.At, EventType: a.EventType}
+}
+
+func cooldownStatus(cd *cooldown.State) []control.Cooldown {
+	var out []control.Cooldown
+	for typ, t := range cd.Snapshot() {
+		out = append(out, control.Cooldown{EventType: typ, Until: t.Expiry})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].EventType < out[j].EventType })
+	return out
+}
+
+func runResult(res runner.Result) control.RunResult {
+	rr := control.RunResult{
+		EventType:      res.EventType,
+		Key:            res.EntityKey,
+		Notified:       res.Notified,
+		Failed:         res.Failed,
+		Grouped:        res.Grouped,
+		Suppressed:     res.Suppressed,
+		Pending:        res.Pending,
+		Flapping:       res.Flapping && res.FlapChange == "",
+		Dropped:        res.Dropped,
+		DropReason:     res.DropReason,
+		DryRun:         res.DryRun,
+		SilencedBy:     res.SilencedBy,
+		InhibitedBy:    res.InhibitedBy,
+		Acknowledged:   res.Acknowledged,
+		AcknowledgedBy: res.AcknowledgedBy,
+		Healthy:        res.Healthy,
+	}
+	if res.Err != nil {
+		rr.Error = secret.Redact(res.Err.Error())
+		rr.ErrStage = res.ErrStage
+	}
+	return rr
+}
+
+func describeTrigger(t config.Trigger) string {
+	switch {
+	case t.Lifecycle:
+		return "lifecycle"
+	case t.Pipe != "":
+		return "pipe: " + t.Pipe
+	case t.Watch != "":
+		return "watch: " + t.Watch
+	case t.Cron != "":
+		return "cron: " + t.Cron
+	default:
+		return "interval: " + t.Interval
+	}
+}
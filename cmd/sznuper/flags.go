@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 
@@ -8,26 +9,59 @@ import (
 	"github.com/sznuper/sznuper/internal/config"
 )
 
-// registerConfigFlags registers --config, --verbose, and all options override
-// flags on cmd. Call this in init() for commands that load a config file.
+// registerConfigFlags registers --config, --env-file, --verbose, and an
+// override flag for every string field of options. Call this in init() for
+// commands that load a config file.
 func registerConfigFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&cfgFile, "config", "", "config file path")
+	cmd.Flags().StringVar(&envFile, "env-file", "", "load environment variables from this file before reading the config")
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "enable debug logging")
 	t := reflect.TypeOf(config.Options{})
 	for i := range t.NumField() {
-		yamlTag := t.Field(i).Tag.Get("yaml")
+		if t.Field(i).Type.Kind() != reflect.String {
+			continue
+		}
+		yamlTag := optionName(t.Field(i))
 		flagName := strings.ReplaceAll(yamlTag, "_", "-")
 		cmd.Flags().String(flagName, "", "override "+yamlTag)
 	}
 }
 
+// loadEnvFile loads --env-file, if given, into the environment. Call it
+// before loading the config. It returns a warning for a world-readable
+// file, to be reported once a logger exists.
+func loadEnvFile() (string, error) {
+	if envFile == "" {
+		return "", nil
+	}
+	if err := config.LoadEnvFile(envFile); err != nil {
+		return "", err
+	}
+	if open, err := config.EnvFileWorldReadable(envFile); err == nil && open {
+		return fmt.Sprintf("env file %s is world-readable, restrict it with chmod 600", envFile), nil
+	}
+	return "", nil
+}
+
+// reloadEnvFile re-reads --env-file, if given, so a reload sees its
+// current values.
+func reloadEnvFile() error {
+	if envFile == "" {
+		return nil
+	}
+	return config.LoadEnvFile(envFile)
+}
+
 // applyOptionFlags overlays CLI flag values onto the config. Only flags
 // explicitly set by the user are applied.
 func applyOptionFlags(cmd *cobra.Command, cfg *config.Config) {
 	t := reflect.TypeOf(cfg.Options)
 	v := reflect.ValueOf(&cfg.Options).Elem()
 	for i := range t.NumField() {
-		yamlTag := t.Field(i).Tag.Get("yaml")
+		if t.Field(i).Type.Kind() != reflect.String {
+			continue
+		}
+		yamlTag := optionName(t.Field(i))
 		flagName := strings.ReplaceAll(yamlTag, "_", "-")
 		if cmd.Flags().Changed(flagName) {
 			val, _ := cmd.Flags().GetString(flagName)
@@ -35,3 +69,10 @@ func applyOptionFlags(cmd *cobra.Command, cfg *config.Config) {
 		}
 	}
 }
+
+// optionName returns the YAML key of an options field, without tag flags
+// such as omitempty.
+func optionName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	return name
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/history"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show past events and what happened to their notifications",
+	Long: "Lists events recorded by the daemon in options.state_dir, most recent last, with their outcome: " +
+		strings.Join(history.Outcomes, ", ") + ". Use --json for machine-readable output.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envWarning, err := loadEnvFile()
+		if err != nil {
+			return err
+		}
+		if envWarning != "" {
+			fmt.Fprintln(os.Stderr, "warning: "+envWarning)
+		}
+
+		cfg, err := config.Resolve(cfgFile)
+		if err != nil {
+			return err
+		}
+		applyOptionFlags(cmd, cfg)
+		if cfg.Options.StateDir == "" {
+			return errors.New("options.state_dir is not set, no history is recorded")
+		}
+
+		now := time.Now()
+		f, err := historyFilterFromFlags(cmd, now)
+		if err != nil {
+			return err
+		}
+		records, err := history.Read(cfg.Options.StateDir, f)
+		if err != nil {
+			return err
+		}
+		if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 && len(records) > limit {
+			records = records[len(records)-limit:]
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			if records == nil {
+				records = []history.Record{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(records)
+		}
+		printHistory(os.Stdout, records)
+		return nil
+	},
+}
+
+func init() {
+	f := historyCmd.Flags()
+	f.String("alert", "", "only events of this alert")
+	f.String("event-type", "", "only events of this type")
+	f.String("key", "", "only events of this events.key entity")
+	f.String("since", "", "only events at or after this time, RFC 3339 or a duration ago, e.g. 24h")
+	f.String("until", "", "only events before this time, RFC 3339 or a duration ago")
+	f.StringArray("outcome", nil, "only events with this outcome (repeatable)")
+	f.Int("limit", 50, "show at most this many of the most recent events, 0 for all")
+	f.Bool("json", false, "print events as JSON")
+	registerConfigFlags(historyCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+// historyFilterFromFlags builds a history filter from the flags of
+// `history`.
+func historyFilterFromFlags(cmd *cobra.Command, now time.Time) (history.Filter, error) {
+	fl := cmd.Flags()
+	var f history.Filter
+	f.Alert, _ = fl.GetString("alert")
+	f.EventType, _ = fl.GetString("event-type")
+	f.Key, _ = fl.GetString("key")
+	f.Outcomes, _ = fl.GetStringArray("outcome")
+	for _, o := range f.Outcomes {
+		if !slices.Contains(history.Outcomes, o) {
+			return f, fmt.Errorf("invalid --outcome %q: want one of %s", o, strings.Join(history.Outcomes, ", "))
+		}
+	}
+
+	var err error
+	since, _ := fl.GetString("since")
+	if f.Since, err = parseTimeArg("--since", since, now); err != nil {
+		return f, err
+	}
+	until, _ := fl.GetString("until")
+	if f.Until, err = parseTimeArg("--until", until, now); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// parseTimeArg parses an RFC 3339 time or a duration before now. An empty
+// value is the zero time.
+func parseTimeArg(flag, s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: want RFC 3339 or a duration", flag, s)
+	}
+	return t, nil
+}
+
+func printHistory(w io.Writer, records []history.Record) {
+	if len(records) == 0 {
+		fmt.Fprintln(w, "No events recorded.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tALERT\tKEY\tEVENT\tOUTCOME\tCHANNELS\tDETAIL")
+	for _, rec := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			rec.Time.Local().Format(time.DateTime), dash(rec.Alert), dash(rec.Key), dash(rec.EventType),
+			rec.Outcome, dash(historyChannels(rec)), dash(historyDetail(rec)))
+	}
+	_ = tw.Flush()
+}
+
+// historyChannels lists the channels the outcome applies to.
+func historyChannels(rec history.Record) string {
+	var chans []string
+	switch rec.Outcome {
+	case history.OutcomeFailed:
+		chans = append(slices.Clone(rec.Failed), rec.Notified...)
+	case history.OutcomeGrouped:
+		chans = rec.Grouped
+	case history.OutcomeNotified:
+		chans = append(slices.Clone(rec.Notified), rec.Grouped...)
+	default:
+		chans = rec.Withheld
+	}
+	return strings.Join(chans, ", ")
+}
+
+// historyDetail explains the outcome.
+func historyDetail(rec history.Record) string {
+	var parts []string
+	if rec.Digest != "" {
+		parts = append(parts, "digest "+rec.Digest)
+	}
+	switch rec.Outcome {
+	case history.OutcomeError:
+		parts = append(parts, rec.ErrStage+": "+rec.Error)
+	case history.OutcomeFailed:
+		parts = append(parts, rec.Error)
+	case history.OutcomeDropped:
+		parts = append(parts, rec.Dropped)
+	case history.OutcomeAcknowledged:
+		parts = append(parts, "by "+rec.AcknowledgedBy)
+	case history.OutcomeInhibited:
+		parts = append(parts, "by "+rec.InhibitedBy)
+	case history.OutcomeSilenced:
+		parts = append(parts, "by "+rec.SilencedBy)
+	}
+	if rec.Recovery {
+		parts = append(parts, "recovery")
+	}
+	if rec.FlapChange != "" {
+		parts = append(parts, "flapping "+rec.FlapChange)
+	}
+	return strings.Join(parts, ", ")
+}
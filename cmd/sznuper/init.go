@@ -25,6 +25,7 @@ var (
 	initAddChannel []string
 	initForce      bool
 	initOutput     string
+	initOffline    bool
 )
 
 func init() {
@@ -33,6 +34,7 @@ func init() {
 	initCmd.Flags().StringArrayVar(&initAddChannel, "add-channel", nil, "add channel as name:shoutrrr-url (repeatable)")
 	initCmd.Flags().BoolVar(&initForce, "force", false, "overwrite existing config file")
 	initCmd.Flags().StringVarP(&initOutput, "output", "o", "", "output path (overrides auto-detect)")
+	initCmd.Flags().BoolVar(&initOffline, "offline", false, "use builtin:// healthchecks so the config needs no downloads")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -73,7 +75,10 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 func loadBaseConfig() (*config.Config, error) {
 	if initFrom == "" {
-		return initcmd.DefaultConfig()
+		return initcmd.DefaultConfig(initOffline)
+	}
+	if initOffline {
+		return nil, fmt.Errorf("--offline cannot be combined with --from")
 	}
 
 	data, err := readSource(initFrom)
@@ -114,6 +119,9 @@ func applyDefaults(cfg *config.Config) {
 	if cfg.Options.LogsDir == "" {
 		cfg.Options.LogsDir = defaults.LogsDir
 	}
+	if cfg.Options.StateDir == "" {
+		cfg.Options.StateDir = defaults.StateDir
+	}
 
 	if cfg.Globals == nil {
 		cfg.Globals = config.DefaultGlobals()
@@ -145,12 +153,7 @@ func runNonInteractive(cfg *config.Config, outPath string) error {
 		return fmt.Errorf("at least one notification channel is required (use --add-channel)")
 	}
 
-	for i := range cfg.Alerts {
-		for _, name := range added {
-			cfg.Alerts[i].Notify = append(cfg.Alerts[i].Notify,
-				config.NotifyTarget{Channel: name})
-		}
-	}
+	config.NotifyByDefault(cfg, added...)
 
 	if err := config.Write(cfg, outPath); err != nil {
 		return err
@@ -1,9 +1,15 @@
 package main
 
-import "os"
+import (
+	"fmt"
+	"os"
+
+	"github.com/sznuper/sznuper/internal/secret"
+)
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", secret.Redact(err.Error()))
 		os.Exit(1)
 	}
 }
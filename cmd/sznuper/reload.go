@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var reloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload the running daemon's config",
+	Long:  "Asks the running daemon to reload its config file, like SIGHUP, and reports load errors. On error the daemon keeps running with its current config.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := controlClient(cmd).Reload(cmd.Context()); err != nil {
+			return fmt.Errorf("reload failed: %w", err)
+		}
+		fmt.Println("configuration reloaded")
+		return nil
+	},
+}
+
+func init() {
+	registerControlFlags(reloadCmd)
+	rootCmd.AddCommand(reloadCmd)
+}
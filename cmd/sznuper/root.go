@@ -1,14 +1,10 @@
 package main
 
-import (
-	"log/slog"
-	"os"
-
-	"github.com/spf13/cobra"
-)
+import "github.com/spf13/cobra"
 
 var (
 	cfgFile string
+	envFile string
 	verbose bool
 )
 
@@ -17,14 +13,8 @@ var rootCmd = &cobra.Command{
 	Short:   "A lightweight server monitor that runs healthchecks and sends notifications",
 	Long:    "A lightweight server monitor that runs healthchecks and sends notifications — Discord, Slack, Telegram, Teams, and more.",
 	Version: version,
+	// main prints errors itself, with secrets redacted.
+	SilenceErrors: true,
 }
 
 func init() {}
-
-func setupLogger() *slog.Logger {
-	level := slog.LevelInfo
-	if verbose {
-		level = slog.LevelDebug
-	}
-	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
-}
@@ -9,7 +9,9 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/logging"
 	"github.com/sznuper/sznuper/internal/runner"
+	"github.com/sznuper/sznuper/internal/secret"
 )
 
 var runCmd = &cobra.Command{
@@ -26,7 +28,15 @@ var runCmd = &cobra.Command{
 		if verbose {
 			level = slog.LevelDebug
 		}
-		logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+		logger := slog.New(logging.Redact(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+		envWarning, err := loadEnvFile()
+		if err != nil {
+			return err
+		}
+		if envWarning != "" {
+			logger.Warn(envWarning)
+		}
 
 		cfg, err := config.Resolve(cfgFile)
 		if err != nil {
@@ -35,6 +45,7 @@ var runCmd = &cobra.Command{
 		applyOptionFlags(cmd, cfg)
 
 		r := runner.New(cfg, logger)
+		r.SetSilences(compileSilences(logger, cfg, openSilenceStore(logger, cfg.Options.StateDir, false)))
 		ctx := context.Background()
 
 		hasError := false
@@ -73,25 +84,28 @@ func init() {
 
 func printResult(r runner.Result) {
 	if r.Err != nil {
-		fmt.Printf("✗ Healthcheck: %s\n", r.HealthcheckURI)
-		fmt.Printf("  Error (%s): %s\n", r.ErrStage, r.Err)
+		printf("✗ Healthcheck: %s\n", r.HealthcheckURI)
+		printf("  Error (%s): %s\n", r.ErrStage, r.Err)
 		if r.Stderr != "" {
-			fmt.Printf("  Stderr: %s\n", r.Stderr)
+			printf("  Stderr: %s\n", r.Stderr)
+		}
+		if len(r.Notified) > 0 {
+			printf("  Notified: %s\n", strings.Join(r.Notified, ", "))
 		}
 		return
 	}
 
-	fmt.Printf("✓ Healthcheck: %s\n", r.HealthcheckURI)
+	printf("✓ Healthcheck: %s\n", r.HealthcheckURI)
 	if len(r.Env) > 0 {
-		fmt.Println("  Env:")
+		printf("  Env:\n")
 		for _, e := range r.Env {
-			fmt.Printf("    %s\n", e)
+			printf("    %s\n", e)
 		}
 	}
 	if len(r.Fields) > 0 {
-		fmt.Println("  Fields:")
+		printf("  Fields:\n")
 		for k, v := range r.Fields {
-			fmt.Printf("    %s=%s\n", k, v)
+			printf("    %s=%s\n", k, v)
 		}
 	}
 
@@ -99,27 +113,39 @@ func printResult(r runner.Result) {
 	if len(r.Rendered) > 0 {
 		messages := uniqueMessages(r.Rendered)
 		if len(messages) == 1 {
-			fmt.Printf("  Rendered: %q\n", messages[0])
+			printf("  Rendered: %q\n", secret.Redact(messages[0]))
 		} else {
 			for svc, msg := range r.Rendered {
-				fmt.Printf("  Rendered (%s): %q\n", svc, msg)
+				printf("  Rendered (%s): %q\n", svc, secret.Redact(msg))
 			}
 		}
 	}
 
+	if r.Dropped {
+		printf("  Dropped: %s\n", r.DropReason)
+	}
+	if r.Silenced {
+		printf("  Silenced: %s\n", r.SilencedBy)
+	}
+
 	if len(r.Notified) > 0 {
 		label := "Notified"
 		if r.DryRun {
 			label = "Would notify"
 		}
-		fmt.Printf("  %s: %s\n", label, strings.Join(r.Notified, ", "))
+		printf("  %s: %s\n", label, strings.Join(r.Notified, ", "))
 	}
 
 	if r.SideEffectsRun > 0 {
-		fmt.Printf("  Side effects: %d run\n", r.SideEffectsRun)
+		printf("  Side effects: %d run\n", r.SideEffectsRun)
 	}
 }
 
+// printf prints to stdout with secrets redacted.
+func printf(format string, a ...any) {
+	fmt.Print(secret.Redact(fmt.Sprintf(format, a...)))
+}
+
 func uniqueMessages(rendered map[string]string) []string {
 	seen := make(map[string]bool)
 	var unique []string
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sznuper/sznuper/internal/silence"
+)
+
+var silenceCmd = &cobra.Command{
+	Use:   "silence",
+	Short: "Manage ad-hoc silences in the running daemon",
+	Long:  "Creates, lists and expires ad-hoc silences. Silenced events still update alert state, but send no notifications and run no side effects. Ad-hoc silences are kept in options.state_dir and survive restarts.",
+}
+
+var silenceAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Silence matching events for a time range",
+	Long:  "Silences events matching --alert, --event-type and --field (glob patterns, all must match) from --start (default now) until --end or for --duration.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := silenceFromFlags(cmd, time.Now())
+		if err != nil {
+			return err
+		}
+		created, err := controlClient(cmd).AddSilence(cmd.Context(), s)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("silence %s created, %s until %s\n", created.ID, describeMatcher(created.Match), created.End.Local().Format(time.DateTime))
+		return nil
+	},
+}
+
+var silenceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List active and scheduled silences",
+	Long:  "Lists maintenance windows from the config that are active now, and ad-hoc silences that have not ended. Use --json for machine-readable output.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		list, err := controlClient(cmd).Silences(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(list)
+		}
+		printSilences(os.Stdout, list, time.Now())
+		return nil
+	},
+}
+
+var silenceExpireCmd = &cobra.Command{
+	Use:   "expire <id>...",
+	Short: "End ad-hoc silences now",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c := controlClient(cmd)
+		for _, id := range args {
+			if err := c.ExpireSilence(cmd.Context(), id); err != nil {
+				return err
+			}
+			fmt.Printf("silence %s expired\n", id)
+		}
+		return nil
+	},
+}
+
+func init() {
+	f := silenceAddCmd.Flags()
+	f.String("alert", "", "alert name pattern")
+	f.String("event-type", "", "event type pattern")
+	f.StringArray("field", nil, "event field pattern as key=pattern (repeatable)")
+	f.String("start", "", "start time, RFC 3339 (default now)")
+	f.String("end", "", "end time, RFC 3339")
+	f.Duration("duration", 0, "length of the silence, e.g. 2h")
+	f.String("comment", "", "reason for the silence")
+	silenceListCmd.Flags().Bool("json", false, "print silences as JSON")
+
+	for _, c := range []*cobra.Command{silenceAddCmd, silenceListCmd, silenceExpireCmd} {
+		registerControlFlags(c)
+		silenceCmd.AddCommand(c)
+	}
+	rootCmd.AddCommand(silenceCmd)
+}
+
+// silenceFromFlags builds a silence from the flags of `silence add`.
+func silenceFromFlags(cmd *cobra.Command, now time.Time) (silence.Silence, error) {
+	f := cmd.Flags()
+	var s silence.Silence
+	s.Match.Alert, _ = f.GetString("alert")
+	s.Match.EventType, _ = f.GetString("event-type")
+	fields, _ := f.GetStringArray("field")
+	for _, kv := range fields {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return s, fmt.Errorf("invalid --field %q: want key=pattern", kv)
+		}
+		if s.Match.Fields == nil {
+			s.Match.Fields = make(map[string]string)
+		}
+		s.Match.Fields[k] = v
+	}
+	s.Comment, _ = f.GetString("comment")
+	s.CreatedBy = os.Getenv("USER")
+
+	s.Start = now
+	if start, _ := f.GetString("start"); start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return s, fmt.Errorf("invalid --start %q: want RFC 3339", start)
+		}
+		s.Start = t
+	}
+
+	end, _ := f.GetString("end")
+	duration, _ := f.GetDuration("duration")
+	switch {
+	case end != "" && duration != 0:
+		return s, errors.New("--end and --duration are mutually exclusive")
+	case end != "":
+		t, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return s, fmt.Errorf("invalid --end %q: want RFC 3339", end)
+		}
+		s.End = t
+	case duration > 0:
+		s.End = s.Start.Add(duration)
+	default:
+		return s, errors.New("one of --end or --duration is required")
+	}
+	return s, nil
+}
+
+func printSilences(w io.Writer, list []silence.Silence, now time.Time) {
+	if len(list) == 0 {
+		fmt.Fprintln(w, "no silences")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tMATCH\tSTATE\tEND\tCOMMENT")
+	for _, s := range list {
+		id := s.ID
+		if s.Config {
+			id += " (config)"
+		}
+		state := "active"
+		if !s.Active(now) {
+			state = "starts in " + ago(s.Start, now)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", id, describeMatcher(s.Match), state, s.End.Local().Format(time.DateTime), dash(s.Comment))
+	}
+	_ = tw.Flush()
+}
+
+// describeMatcher renders a matcher as space-separated key=pattern pairs.
+func describeMatcher(m silence.Matcher) string {
+	if m.Empty() {
+		return "all events"
+	}
+	var parts []string
+	if m.Alert != "" {
+		parts = append(parts, "alert="+m.Alert)
+	}
+	if m.EventType != "" {
+		parts = append(parts, "event_type="+m.EventType)
+	}
+	keys := make([]string, 0, len(m.Fields))
+	for k := range m.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, k+"="+m.Fields[k])
+	}
+	return strings.Join(parts, " ")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -10,8 +11,15 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/control"
+	"github.com/sznuper/sznuper/internal/group"
+	"github.com/sznuper/sznuper/internal/history"
+	"github.com/sznuper/sznuper/internal/logging"
+	"github.com/sznuper/sznuper/internal/metrics"
 	"github.com/sznuper/sznuper/internal/runner"
 	"github.com/sznuper/sznuper/internal/scheduler"
+	"github.com/sznuper/sznuper/internal/silence"
+	"github.com/sznuper/sznuper/internal/state"
 )
 
 var startCmd = &cobra.Command{
@@ -20,7 +28,11 @@ var startCmd = &cobra.Command{
 	Long:  "Starts the sznuper daemon, running each alert on its configured interval. Use --dry-run to skip sending notifications.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		dryRun, _ := cmd.Flags().GetBool("dry-run")
-		logger := setupLogger()
+
+		envWarning, err := loadEnvFile()
+		if err != nil {
+			return err
+		}
 
 		// Resolve config path once at startup; reused on every reload.
 		cfgPath, err := config.FindPath(cfgFile)
@@ -34,28 +46,111 @@ var startCmd = &cobra.Command{
 		}
 		applyOptionFlags(cmd, cfg)
 
+		// Log sinks are opened once at startup, like the metrics listener;
+		// reloads keep them.
+		logs, err := logging.New(cfg.Logging, cfg.Options.LogsDir, verbose)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = logs.Close() }()
+		logger := logs.Logger
+		if envWarning != "" {
+			logger.Warn(envWarning)
+		}
+
+		// Alert state is keyed by alert name and outlives each scheduler, so
+		// reloads and restarts don't forget ongoing incidents. The state
+		// directory is fixed at startup; dry runs never touch it.
+		store := openStateStore(logger, cfg.Options.StateDir, dryRun)
+		silences := openSilenceStore(logger, cfg.Options.StateDir, dryRun)
+		hist, err := openHistory(cfg.Options, dryRun)
+		if err != nil {
+			return err
+		}
+		if hist != nil {
+			defer func() { _ = hist.Close() }()
+		}
+
 		// SIGINT/SIGTERM for graceful shutdown.
 		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
 
+		// Metrics outlive reloads like the state store; the listen address
+		// is fixed at startup.
+		var m *metrics.Metrics
+		if addr := cfg.Options.MetricsListen; addr != "" {
+			m = metrics.New()
+			if err := m.Serve(ctx, addr, logger); err != nil {
+				return fmt.Errorf("metrics: %w", err)
+			}
+		}
+
+		// Grouped notifications wait across reloads; pending groups are
+		// flushed on shutdown.
+		grouper := group.New()
+
+		// The control socket serves status, trigger and reload requests for
+		// the lifetime of the daemon; its path is fixed at startup. Failing
+		// to bind a configured path is fatal. The default path is best
+		// effort, so a second daemon or a user who can't write there still
+		// runs, without the control commands.
+		d := newDaemon(cfgPath, dryRun, store, silences)
+		socketPath := cfg.Options.ControlSocket
+		if socketPath == "" {
+			socketPath = control.DefaultSocketPath()
+			if err := control.Serve(ctx, socketPath, d, logger); err != nil {
+				logger.Warn("control socket unavailable, status, trigger, ack, silence and reload commands will not reach this daemon", "path", socketPath, "error", err)
+				socketPath = ""
+			}
+		} else if err := control.Serve(ctx, socketPath, d, logger); err != nil {
+			return fmt.Errorf("control socket: %w", err)
+		}
+		if socketPath != "" {
+			defer func() { _ = os.Remove(socketPath) }()
+		}
+
 		// SIGHUP for config reload.
 		sighup := make(chan os.Signal, 1)
 		signal.Notify(sighup, syscall.SIGHUP)
 		defer signal.Stop(sighup)
 
 		firstStart := true
+		var reloadReply chan error // control-socket reload waiting for the new scheduler
 		for {
+			store.Prune(cfg.Alerts)
+			m.Prune(alertNames(cfg.Alerts))
 			r := runner.New(cfg, logger)
+			r.SetMetrics(m)
+			r.SetGrouper(grouper)
+			if hist != nil {
+				r.SetRecorder(hist)
+			}
+			if logs.Output != nil {
+				r.SetOutputLogger(logs.Output)
+			}
+			silenceSet := compileSilences(logger, cfg, silences)
+			r.SetSilences(silenceSet)
 			sched := scheduler.New(r, logger, func(res runner.Result) {
 				logResult(logger, res)
+				d.record(res)
+				// Only writes when the run changed state or cooldowns.
+				if err := store.Save(); err != nil {
+					logger.Warn("saving alert state failed", "error", err)
+				}
 			})
 
 			schedCtx, schedCancel := context.WithCancel(ctx)
+			d.setCurrent(cfg, sched, schedCtx, silenceSet, !firstStart)
+			if reloadReply != nil {
+				reloadReply <- nil
+				reloadReply = nil
+			}
 			schedDone := make(chan struct{})
 			go func() {
 				sched.Start(schedCtx, cfg.Alerts, scheduler.StartOpts{
 					DryRun:        dryRun,
 					SkipLifecycle: true,
+					Store:         store,
 				})
 				close(schedDone)
 			}()
@@ -63,14 +158,34 @@ var startCmd = &cobra.Command{
 			// Fire the appropriate lifecycle event.
 			lifecycleAlerts := filterLifecycleAlerts(cfg.Alerts)
 			if firstStart {
-				logger.Info("sznuper daemon starting", "alerts", len(cfg.Alerts))
+				logger.Info("sznuper daemon starting", "alerts", len(cfg.Alerts), "files", len(cfg.Files))
 				sched.FireLifecycle(schedCtx, lifecycleAlerts, "started", len(cfg.Alerts), dryRun)
 				firstStart = false
 			} else {
-				logger.Info("configuration reloaded", "alerts", len(cfg.Alerts))
+				logger.Info("configuration reloaded", "alerts", len(cfg.Alerts), "files", len(cfg.Files))
 				sched.FireLifecycle(schedCtx, lifecycleAlerts, "reload_success", len(cfg.Alerts), dryRun)
 			}
 
+			// reloadConfig loads the config file for a reload, re-reading
+			// its includes and conf.d drop-ins. On failure the current
+			// configuration keeps running.
+			reloadConfig := func() error {
+				logger.Info("reloading configuration")
+				var newCfg *config.Config
+				loadErr := reloadEnvFile()
+				if loadErr == nil {
+					newCfg, loadErr = config.Load(cfgPath)
+				}
+				if loadErr != nil {
+					logger.Error("reload failed: invalid config, keeping current configuration", "error", loadErr)
+					sched.FireLifecycle(schedCtx, lifecycleAlerts, "reload_failure", len(cfg.Alerts), dryRun)
+					return loadErr
+				}
+				applyOptionFlags(cmd, newCfg)
+				cfg = newCfg
+				return nil
+			}
+
 			// Wait for shutdown or reload signal.
 			reload := false
 		waitLoop:
@@ -81,22 +196,28 @@ var startCmd = &cobra.Command{
 					stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Second)
 					sched.FireLifecycle(stopCtx, lifecycleAlerts, "stopped", len(cfg.Alerts), dryRun)
 					stopCancel()
+					flushGroups(logger, grouper)
+					if err := store.Save(); err != nil {
+						logger.Warn("saving alert state failed", "error", err)
+					}
 					logger.Info("sznuper daemon stopped")
 					schedCancel()
 					return nil
 
 				case <-sighup:
 					drainSignals(sighup)
-					logger.Info("reloading configuration")
+					if reloadConfig() != nil {
+						continue
+					}
+					reload = true
+					break waitLoop
 
-					newCfg, loadErr := config.Load(cfgPath)
-					if loadErr != nil {
-						logger.Error("reload failed: invalid config, keeping current configuration", "error", loadErr)
-						sched.FireLifecycle(schedCtx, lifecycleAlerts, "reload_failure", len(cfg.Alerts), dryRun)
+				case reply := <-d.reloads:
+					if err := reloadConfig(); err != nil {
+						reply <- err
 						continue
 					}
-					applyOptionFlags(cmd, newCfg)
-					cfg = newCfg
+					reloadReply = reply
 					reload = true
 					break waitLoop
 				}
@@ -120,6 +241,76 @@ func init() {
 	rootCmd.AddCommand(startCmd)
 }
 
+// openStateStore opens the alert state store in dir. A missing dir or a
+// dry run yields an in-memory store; an unreadable snapshot is logged and
+// overwritten on the next save rather than blocking startup.
+func openStateStore(logger *slog.Logger, dir string, dryRun bool) *state.Store {
+	if dryRun {
+		dir = ""
+	}
+	store := state.New(dir)
+	if err := store.Load(); err != nil {
+		logger.Error("loading alert state failed, starting fresh", "error", err)
+	} else if store.Path() != "" {
+		logger.Debug("alert state loaded", "path", store.Path())
+	}
+	return store
+}
+
+// openSilenceStore opens the ad-hoc silence store in dir, like
+// openStateStore.
+func openSilenceStore(logger *slog.Logger, dir string, dryRun bool) *silence.Store {
+	if dryRun {
+		dir = ""
+	}
+	silences := silence.NewStore(dir)
+	if err := silences.Load(); err != nil {
+		logger.Error("loading silences failed, starting without ad-hoc silences", "error", err)
+	}
+	return silences
+}
+
+// openHistory returns the event history store in options.state_dir, or nil
+// when there is no state dir, history_max_size is 0, or on a dry run.
+func openHistory(opts config.Options, dryRun bool) (*history.Store, error) {
+	if dryRun || opts.StateDir == "" {
+		return nil, nil
+	}
+	maxSize := int64(history.DefaultMaxSize)
+	if opts.HistoryMaxSize != "" {
+		n, err := config.ParseSize(opts.HistoryMaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("options.history_max_size: %w", err)
+		}
+		maxSize = n
+	}
+	if maxSize == 0 {
+		return nil, nil
+	}
+	return history.NewStore(opts.StateDir, maxSize), nil
+}
+
+// compileSilences combines the config's maintenance windows with the
+// ad-hoc silences. Loading the config has checked the windows; any that
+// still fail to compile are logged and skipped.
+func compileSilences(logger *slog.Logger, cfg *config.Config, store *silence.Store) *silence.Set {
+	windows, err := silence.Compile(cfg.Silences)
+	if err != nil {
+		logger.Error("skipping invalid silences", "error", err)
+	}
+	return silence.NewSet(windows, store)
+}
+
+// flushGroups sends all pending group notifications before shutdown.
+func flushGroups(logger *slog.Logger, grouper *group.Grouper) {
+	if n := grouper.Pending(); n > 0 {
+		logger.Info("flushing pending group notifications", "events", n)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	grouper.Close(ctx)
+}
+
 // drainSignals discards any buffered signals from the channel.
 func drainSignals(ch <-chan os.Signal) {
 	for {
@@ -142,17 +333,39 @@ func filterLifecycleAlerts(alerts []config.Alert) []config.Alert {
 	return out
 }
 
+func alertNames(alerts []config.Alert) []string {
+	names := make([]string, len(alerts))
+	for i, a := range alerts {
+		names[i] = a.Name
+	}
+	return names
+}
+
 func logResult(logger *slog.Logger, res runner.Result) {
 	attrs := []any{
 		"alert", res.AlertName,
 		"event_type", res.EventType,
 		"duration", res.Duration,
 	}
+	if res.Digest != "" {
+		attrs[0], attrs[1] = "digest", res.Digest
+	}
+	if res.EntityKey != "" {
+		attrs = append(attrs, "key", res.EntityKey)
+	}
 	switch {
 	case res.Err != nil:
 		logger.Error("alert failed", append(attrs, "stage", res.ErrStage, "error", res.Err)...)
+	case res.Acknowledged:
+		logger.Info("notification withheld by acknowledgement", append(attrs, "acknowledged_by", res.AcknowledgedBy)...)
+	case res.Inhibited:
+		logger.Info("notification inhibited by dependency", append(attrs, "dependency", res.InhibitedBy)...)
+	case res.Silenced:
+		logger.Info("notification silenced", append(attrs, "silence", res.SilencedBy)...)
 	case res.Suppressed:
 		logger.Info("notification suppressed by cooldown", attrs...)
+	case res.Flapping && res.FlapChange == "":
+		logger.Info("notification suppressed while flapping", attrs...)
 	case res.IsRecovery:
 		logger.Info("recovery notification sent", attrs...)
 	default:
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sznuper/sznuper/internal/control"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the running daemon",
+	Long:  "Lists every alert of the running daemon with its last run, last event type, healthy state and active cooldowns. Use --json for machine-readable output.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := controlClient(cmd).Status(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(st)
+		}
+		printStatus(os.Stdout, st, time.Now())
+		return nil
+	},
+}
+
+func init() {
+	statusCmd.Flags().Bool("json", false, "print status as JSON")
+	registerControlFlags(statusCmd)
+	rootCmd.AddCommand(statusCmd)
+}
+
+func printStatus(w io.Writer, st *control.Status, now time.Time) {
+	fmt.Fprintf(w, "Daemon up %s, config %s", ago(now, st.StartedAt), st.ConfigPath)
+	if !st.ReloadedAt.IsZero() {
+		fmt.Fprintf(w, ", reloaded %s ago", ago(now, st.ReloadedAt))
+	}
+	if st.DryRun {
+		fmt.Fprint(w, " (dry run)")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ALERT\tSTATE\tLAST RUN\tLAST EVENT\tCOOLDOWNS")
+	for _, a := range st.Alerts {
+		lastRun := "never"
+		if !a.LastRun.IsZero() {
+			lastRun = ago(now, a.LastRun) + " ago"
+		}
+		lastEvent := a.LastEvent
+		if a.LastError != "" {
+			lastEvent = "error (" + a.LastError + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", a.Name, ackLabel(stateLabel(a.Healthy, a.Flapping), a.Ack), lastRun, dash(lastEvent), cooldownLabel(a.Cooldowns, now))
+		for _, e := range a.Entities {
+			fmt.Fprintf(tw, "  %s\t%s\t\t\t%s\n", e.Key, ackLabel(stateLabel(e.Healthy, e.Flapping), e.Ack), cooldownLabel(e.Cooldowns, now))
+		}
+	}
+	_ = tw.Flush()
+}
+
+func stateLabel(healthy *bool, flapping bool) string {
+	switch {
+	case healthy == nil:
+		return "-"
+	case flapping:
+		return "flapping"
+	case *healthy:
+		return "healthy"
+	default:
+		return "unhealthy"
+	}
+}
+
+// ackLabel appends the acknowledgement, if any, to a state label.
+func ackLabel(state string, ack *control.Ack) string {
+	switch {
+	case ack == nil:
+		return state
+	case ack.By == "":
+		return state + " (acked)"
+	default:
+		return state + " (acked by " + ack.By + ")"
+	}
+}
+
+func cooldownLabel(cds []control.Cooldown, now time.Time) string {
+	if len(cds) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(cds))
+	for i, c := range cds {
+		if c.Until.IsZero() {
+			parts[i] = c.EventType + " (until recovery)"
+		} else {
+			parts[i] = c.EventType + " (" + ago(c.Until, now) + " left)"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ago formats the time from then to now, rounded to the second.
+func ago(now, then time.Time) string {
+	return now.Sub(then).Round(time.Second).String()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
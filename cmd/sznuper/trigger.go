@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sznuper/sznuper/internal/control"
+)
+
+var triggerCmd = &cobra.Command{
+	Use:   "trigger <alert_name>",
+	Short: "Run an alert now in the running daemon",
+	Long:  "Asks the running daemon to run an alert immediately. Unlike `sznuper run`, the run goes through the daemon's cooldowns and healthy/unhealthy state, exactly like a scheduled run.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		results, err := controlClient(cmd).Trigger(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		hasError := false
+		for _, res := range results {
+			printRunResult(args[0], res)
+			if res.Error != "" {
+				hasError = true
+			}
+		}
+		if hasError {
+			os.Exit(1)
+		}
+		return nil
+	},
+}
+
+func init() {
+	registerControlFlags(triggerCmd)
+	rootCmd.AddCommand(triggerCmd)
+}
+
+func printRunResult(alert string, r control.RunResult) {
+	name := alert
+	if r.Key != "" {
+		name += "[" + r.Key + "]"
+	}
+	if r.Error != "" {
+		fmt.Printf("✗ %s\n", name)
+		fmt.Printf("  Error (%s): %s\n", r.ErrStage, r.Error)
+	} else {
+		fmt.Printf("✓ %s\n", name)
+	}
+	if r.EventType != "" {
+		fmt.Printf("  EventType: %s\n", r.EventType)
+	}
+	if r.Healthy != nil {
+		fmt.Printf("  State: %s\n", stateLabel(r.Healthy, r.Flapping))
+	}
+	switch {
+	case r.Dropped:
+		fmt.Printf("  Dropped: %s\n", r.DropReason)
+	case r.Acknowledged:
+		fmt.Printf("  Acknowledged by: %s\n", dash(r.AcknowledgedBy))
+	case r.InhibitedBy != "":
+		fmt.Printf("  Inhibited by: %s\n", r.InhibitedBy)
+	case r.SilencedBy != "":
+		fmt.Printf("  Silenced: %s\n", r.SilencedBy)
+	case r.Suppressed:
+		fmt.Println("  Suppressed: cooldown")
+	case r.Pending:
+		fmt.Println("  Pending: waiting for consecutive threshold")
+	case r.Flapping:
+		fmt.Println("  Suppressed: flapping")
+	}
+	if len(r.Notified) > 0 {
+		label := "Notified"
+		if r.DryRun {
+			label = "Would notify"
+		}
+		fmt.Printf("  %s: %s\n", label, strings.Join(r.Notified, ", "))
+	}
+	if len(r.Grouped) > 0 {
+		fmt.Printf("  Grouped: %s\n", strings.Join(r.Grouped, ", "))
+	}
+	if len(r.Failed) > 0 {
+		fmt.Printf("  Failed: %s\n", strings.Join(r.Failed, ", "))
+	}
+}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/sznuper/sznuper/internal/config"
 	"github.com/sznuper/sznuper/internal/healthcheck"
+	"github.com/sznuper/sznuper/internal/logging"
 	"github.com/sznuper/sznuper/internal/notify"
 )
 
@@ -15,7 +18,19 @@ var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate config and verify all healthchecks",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.Resolve(cfgFile)
+		envWarning, err := loadEnvFile()
+		if err != nil {
+			return err
+		}
+		if envWarning != "" {
+			fmt.Fprintln(os.Stderr, "warning: "+envWarning)
+		}
+
+		cfgPath, err := config.FindPath(cfgFile)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(cfgPath)
 		if err != nil {
 			return err
 		}
@@ -23,10 +38,39 @@ var validateCmd = &cobra.Command{
 
 		hasError := false
 
+		// Warn about ${VAR} references that expand to nothing. Load accepts
+		// them, so they don't fail validation.
+		for _, path := range cfg.Files {
+			unresolved, err := config.UnresolvedEnv(path)
+			if err != nil {
+				return err
+			}
+			for _, msg := range unresolved {
+				if path != cfgPath {
+					msg = path + ": " + msg
+				}
+				printf("! env: %s\n", msg)
+			}
+		}
+
+		if err := logging.Validate(cfg.Logging, cfg.Options.LogsDir); err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				printf("✗ %s\n", line)
+			}
+			hasError = true
+		}
+
+		if size := cfg.Options.HistoryMaxSize; size != "" {
+			if _, err := config.ParseSize(size); err != nil {
+				printf("✗ options.history_max_size: %s\n", err)
+				hasError = true
+			}
+		}
+
 		// Validate channel definitions (dry-run Shoutrrr sender creation).
 		for name, ch := range cfg.Channels {
 			if hasTemplateVar(ch.URL) {
-				fmt.Printf("~ %s (skipped: URL contains template variables)\n", name)
+				printf("~ %s (skipped: URL contains template variables)\n", name)
 				continue
 			}
 			t := notify.Target{
@@ -35,10 +79,10 @@ var validateCmd = &cobra.Command{
 				Params:      ch.Params,
 			}
 			if err := notify.Validate(t); err != nil {
-				fmt.Printf("✗ channel %s: %s\n", name, err)
+				printf("✗ channel %s: %s\n", name, err)
 				hasError = true
 			} else {
-				fmt.Printf("✓ channel %s\n", name)
+				printf("✓ channel %s\n", name)
 			}
 		}
 
@@ -50,18 +94,38 @@ var validateCmd = &cobra.Command{
 				ForceVerify:     true,
 			})
 			if err != nil {
-				fmt.Printf("✗ %s (%s): %s\n", alert.Name, alert.Healthcheck, err)
+				printf("✗ %s (%s): %s\n", alert.Name, alert.Healthcheck, err)
 				hasError = true
 			} else {
-				fmt.Printf("✓ %s (%s)\n", alert.Name, alert.Healthcheck)
+				printf("✓ %s (%s)\n", alert.Name, alert.Healthcheck)
 			}
 
 			for _, bad := range checkChannelRefs(alert, cfg.Channels) {
-				fmt.Printf("✗ %s: unknown channel %q\n", alert.Name, bad)
+				printf("✗ %s: unknown channel %q\n", alert.Name, bad)
+				hasError = true
+			}
+
+			for _, msg := range checkThresholds(alert) {
+				printf("✗ %s: %s\n", alert.Name, msg)
+				hasError = true
+			}
+
+			for _, msg := range checkExpressions(alert, cfg.Globals) {
+				printf("✗ %s: %s\n", alert.Name, msg)
 				hasError = true
 			}
 		}
 
+		for _, msg := range checkEscalations(cfg) {
+			printf("✗ %s\n", msg)
+			hasError = true
+		}
+
+		for _, msg := range checkDigests(cfg) {
+			printf("✗ %s\n", msg)
+			hasError = true
+		}
+
 		if hasError {
 			os.Exit(1)
 		}
@@ -90,6 +154,105 @@ func checkChannelRefs(alert config.Alert, channels map[string]config.Channel) []
 	return bad
 }
 
+// checkThresholds reports hysteresis on alerts without the events.healthy
+// state that remembers the current range. Loading the config has checked
+// the ranges.
+func checkThresholds(alert config.Alert) []string {
+	th := alert.Thresholds
+	if th == nil {
+		return nil
+	}
+	var msgs []string
+	hysteresis := th.Hysteresis > 0
+	for _, rg := range th.Ranges {
+		if rg.Hysteresis != nil && *rg.Hysteresis > 0 {
+			hysteresis = true
+		}
+	}
+	if hysteresis && (alert.Events == nil || len(alert.Events.Healthy) == 0) {
+		msgs = append(msgs, "thresholds: hysteresis requires events.healthy")
+	}
+	return msgs
+}
+
+// checkExpressions reports rule expressions of the alert that refer to args
+// or globals that are not configured; they would always be null. Syntax
+// errors already fail the config load.
+func checkExpressions(alert config.Alert, globals map[string]any) []string {
+	var msgs []string
+	check := func(where string, e *config.Expr) {
+		if e == nil {
+			return
+		}
+		for _, ref := range e.Refs() {
+			root, rest, _ := strings.Cut(ref, ".")
+			name, _, _ := strings.Cut(rest, ".")
+			switch {
+			case root == "args" && !hasKey(alert.Args, name):
+				msgs = append(msgs, fmt.Sprintf("%s: unknown arg %q", where, name))
+			case root == "globals" && !hasKey(globals, name):
+				msgs = append(msgs, fmt.Sprintf("%s: unknown global %q", where, name))
+			}
+		}
+	}
+	check("drop_if", alert.DropIf)
+	check("keep_if", alert.KeepIf)
+	if alert.Events != nil {
+		for _, typ := range slices.Sorted(maps.Keys(alert.Events.Override)) {
+			check("override "+typ+" when", alert.Events.Override[typ].When)
+		}
+	}
+	return msgs
+}
+
+func hasKey(m map[string]any, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+// checkEscalations reports escalation steps notifying unknown channels and
+// alerts lacking the events.healthy state an escalation follows. Loading
+// the config has checked the policies and the names alerts refer to.
+func checkEscalations(cfg *config.Config) []string {
+	var msgs []string
+	names := slices.Sorted(maps.Keys(cfg.Escalations))
+	for _, name := range names {
+		for i, st := range cfg.Escalations[name].Steps {
+			for _, nt := range st.Notify {
+				if _, ok := cfg.Channels[nt.Channel]; !ok {
+					msgs = append(msgs, fmt.Sprintf("escalation %s: step %d: unknown channel %q", name, i+1, nt.Channel))
+				}
+			}
+		}
+	}
+
+	for _, alert := range cfg.Alerts {
+		used := alert.Escalation != ""
+		if alert.Events != nil {
+			for _, ov := range alert.Events.Override {
+				used = used || ov.Escalation != ""
+			}
+		}
+		if used && (alert.Events == nil || len(alert.Events.Healthy) == 0) {
+			msgs = append(msgs, fmt.Sprintf("%s: escalation requires events.healthy", alert.Name))
+		}
+	}
+	return msgs
+}
+
+// checkDigests reports digests that notify unknown channels.
+func checkDigests(cfg *config.Config) []string {
+	var msgs []string
+	for _, name := range slices.Sorted(maps.Keys(cfg.Digests)) {
+		for _, nt := range cfg.Digests[name].Notify {
+			if _, ok := cfg.Channels[nt.Channel]; !ok {
+				msgs = append(msgs, fmt.Sprintf("digest %s: unknown channel %q", name, nt.Channel))
+			}
+		}
+	}
+	return msgs
+}
+
 func hasTemplateVar(s string) bool {
 	return strings.Contains(s, "{{")
 }
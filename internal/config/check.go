@@ -0,0 +1,280 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Built-in retry policy, used for the fields neither the alert nor
+// options.retry set.
+const (
+	DefaultRetryAttempts   = 3
+	DefaultRetryBackoff    = time.Second
+	DefaultRetryMaxBackoff = 30 * time.Second
+)
+
+// checkRetry checks the durations of a retry block, and that backoff does
+// not exceed max_backoff once over is merged onto base and the built-in
+// defaults. base has been checked already; a nil over is valid.
+func checkRetry(base, over *Retry) error {
+	if over == nil {
+		return nil
+	}
+	var errs []error
+	backoff, err := parseRetryDuration("backoff", over.Backoff)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	maxBackoff, err := parseRetryDuration("max_backoff", over.MaxBackoff)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if over.Backoff == "" {
+		backoff = DefaultRetryBackoff
+		if base != nil && base.Backoff != "" {
+			backoff, _ = time.ParseDuration(base.Backoff)
+		}
+	}
+	if over.MaxBackoff == "" {
+		maxBackoff = DefaultRetryMaxBackoff
+		if base != nil && base.MaxBackoff != "" {
+			maxBackoff, _ = time.ParseDuration(base.MaxBackoff)
+		}
+	}
+	if maxBackoff > 0 && backoff > maxBackoff {
+		return fmt.Errorf("backoff %s exceeds max_backoff %s", backoff, maxBackoff)
+	}
+	return nil
+}
+
+func parseRetryDuration(field, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q", field, s)
+	}
+	return d, nil
+}
+
+// checkFlap checks the window of a flap block; decoding has checked the
+// counts.
+func checkFlap(f *Flap) error {
+	if f == nil {
+		return nil
+	}
+	if d, err := time.ParseDuration(f.Window); err != nil || d <= 0 {
+		return fmt.Errorf("invalid window %q", f.Window)
+	}
+	return nil
+}
+
+// checkEscalation checks the step durations of an escalation policy: after
+// must not be negative or before the previous step's, and repeat must be
+// positive.
+func checkEscalation(e Escalation) error {
+	var errs []error
+	var prev time.Duration
+	for i, st := range e.Steps {
+		var after time.Duration
+		if st.After != "" {
+			d, err := time.ParseDuration(st.After)
+			if err != nil || d < 0 {
+				errs = append(errs, fmt.Errorf("step %d: invalid after %q", i+1, st.After))
+			}
+			after = d
+		}
+		if after < prev {
+			errs = append(errs, fmt.Errorf("step %d: after %s is before the previous step", i+1, after))
+		}
+		prev = after
+		if st.Repeat != "" {
+			if d, err := time.ParseDuration(st.Repeat); err != nil || d <= 0 {
+				errs = append(errs, fmt.Errorf("step %d: invalid repeat %q", i+1, st.Repeat))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkEscalationRefs checks that the escalation policies alert and its
+// overrides refer to exist.
+func checkEscalationRefs(alert *Alert, escalations map[string]Escalation) error {
+	refs := []string{alert.Escalation}
+	if alert.Events != nil {
+		for _, typ := range slices.Sorted(maps.Keys(alert.Events.Override)) {
+			refs = append(refs, alert.Events.Override[typ].Escalation)
+		}
+	}
+	var errs []error
+	for _, ref := range refs {
+		if _, ok := escalations[ref]; ref != "" && !ok {
+			errs = append(errs, fmt.Errorf("unknown escalation %q", ref))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkDependencies checks the depends_on entries of alerts: parents must
+// exist, be another alert, have events.healthy, and have as many events.key
+// fields as match lists, and dependencies must not form cycles, where alerts
+// would inhibit each other. origins holds the file of each alert.
+func checkDependencies(alerts []Alert, origins []string) error {
+	byName := make(map[string]*Alert, len(alerts))
+	origin := make(map[string]string, len(alerts))
+	for i := range alerts {
+		byName[alerts[i].Name] = &alerts[i]
+		origin[alerts[i].Name] = origins[i]
+	}
+
+	var errs []error
+	for i, alert := range alerts {
+		for _, dep := range alert.DependsOn {
+			var err error
+			parent, ok := byName[dep.Alert]
+			switch {
+			case !ok:
+				err = fmt.Errorf("depends_on unknown alert %q", dep.Alert)
+			case parent.Name == alert.Name:
+				err = errors.New("depends_on itself")
+			case parent.Events == nil || len(parent.Events.Healthy) == 0:
+				err = fmt.Errorf("depends_on %q, which has no events.healthy and is never unhealthy", dep.Alert)
+			case len(dep.Match) > 0 && len(dep.Match) != len(parent.Events.Key):
+				err = fmt.Errorf("depends_on %q matches %d field(s), but its events.key has %d", dep.Alert, len(dep.Match), len(parent.Events.Key))
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("config %s: alert %q: %w", origins[i], alert.Name, err))
+			}
+		}
+	}
+
+	// Depth-first search for cycles; each is reported once, from the
+	// alert where it was first entered.
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	mark := make(map[string]int, len(alerts))
+	var path []string
+	var visit func(name string)
+	visit = func(name string) {
+		mark[name] = visiting
+		path = append(path, name)
+		for _, dep := range byName[name].DependsOn {
+			if _, ok := byName[dep.Alert]; !ok || dep.Alert == name {
+				continue
+			}
+			switch mark[dep.Alert] {
+			case unvisited:
+				visit(dep.Alert)
+			case visiting:
+				i := slices.Index(path, dep.Alert)
+				cycle := append(slices.Clone(path[i:]), dep.Alert)
+				errs = append(errs, fmt.Errorf("config %s: depends_on cycle: %s", origin[dep.Alert], strings.Join(cycle, " -> ")))
+			}
+		}
+		path = path[:len(path)-1]
+		mark[name] = done
+	}
+	for _, alert := range alerts {
+		if mark[alert.Name] == unvisited {
+			visit(alert.Name)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkThresholds checks that every range of a thresholds block has min
+// below max; a missing bound is unbounded.
+func checkThresholds(t *Thresholds) error {
+	if t == nil {
+		return nil
+	}
+	var errs []error
+	for i, rg := range t.Ranges {
+		if rg.Min != nil && rg.Max != nil && *rg.Min >= *rg.Max {
+			errs = append(errs, fmt.Errorf("range %d (%s): min %g is not below max %g", i+1, rg.Type, *rg.Min, *rg.Max))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkGroups checks the group configs of alert, its notify targets and
+// its overrides' notify targets.
+func checkGroups(alert *Alert) error {
+	var errs []error
+	check := func(where string, g *Group) {
+		if err := checkGroup(g); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		}
+	}
+	check("group", alert.Group)
+	for _, nt := range alert.Notify {
+		check("notify "+nt.Channel+": group", nt.Group)
+	}
+	if alert.Events != nil {
+		for _, typ := range slices.Sorted(maps.Keys(alert.Events.Override)) {
+			for _, nt := range alert.Events.Override[typ].Notify {
+				check("events.override."+typ+": notify "+nt.Channel+": group", nt.Group)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkGroup checks the timing of a group block: a cron schedule, or a wait
+// and an interval. A nil group is valid.
+func checkGroup(g *Group) error {
+	if g == nil {
+		return nil
+	}
+	if g.Cron != "" {
+		if g.Wait != "" || g.Interval != "" {
+			return errors.New("cron cannot be combined with wait or interval")
+		}
+		if _, err := CronParser.Parse(g.Cron); err != nil {
+			return fmt.Errorf("invalid cron %q: %w", g.Cron, err)
+		}
+		return nil
+	}
+	if g.Wait != "" {
+		if d, err := time.ParseDuration(g.Wait); err != nil || d < 0 {
+			return fmt.Errorf("invalid wait %q", g.Wait)
+		}
+	}
+	if g.Interval != "" {
+		if d, err := time.ParseDuration(g.Interval); err != nil || d <= 0 {
+			return fmt.Errorf("invalid interval %q", g.Interval)
+		}
+	}
+	return nil
+}
+
+// checkDigest checks the schedule of a digest.
+func checkDigest(d Digest) error {
+	if _, err := CronParser.Parse(d.Cron); err != nil {
+		return fmt.Errorf("invalid cron %q: %w", d.Cron, err)
+	}
+	return nil
+}
+
+// checkDigestRefs checks that the alerts a digest collects exist.
+func checkDigestRefs(d Digest, alerts []Alert) error {
+	var errs []error
+	for _, name := range d.Alerts {
+		if !slices.ContainsFunc(alerts, func(a Alert) bool { return a.Name == name }) {
+			errs = append(errs, fmt.Errorf("unknown alert %q", name))
+		}
+	}
+	return errors.Join(errs...)
+}
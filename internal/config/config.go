@@ -2,25 +2,93 @@ package config
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 
-	"github.com/a8m/envsubst"
 	"github.com/go-playground/validator/v10"
 	"github.com/goccy/go-yaml"
+	"github.com/sznuper/sznuper/internal/expr"
 )
 
+// Config is the whole configuration. Defaults applies to every alert and
+// alerts extend AlertTemplates by name; see inherit.go for the merge rules.
 type Config struct {
-	Options  Options            `yaml:"options"`
-	Globals  map[string]any     `yaml:"globals,omitempty"`
-	Channels map[string]Channel `yaml:"channels,omitempty" validate:"dive"`
-	Alerts   []Alert            `yaml:"alerts,omitempty"   validate:"dive"`
+	Include        []string              `yaml:"include,omitempty"` // globs, relative to this file
+	Options        Options               `yaml:"options"`
+	Globals        map[string]any        `yaml:"globals,omitempty"`
+	Channels       map[string]Channel    `yaml:"channels,omitempty"        validate:"dive"`
+	Defaults       *Alert                `yaml:"defaults,omitempty"`
+	AlertTemplates map[string]Alert      `yaml:"alert_templates,omitempty" validate:"dive"`
+	Alerts         []Alert               `yaml:"alerts,omitempty"          validate:"dive"`
+	Silences       []Silence             `yaml:"silences,omitempty"        validate:"dive"`
+	Escalations    map[string]Escalation `yaml:"escalations,omitempty"     validate:"dive"`
+	Digests        map[string]Digest     `yaml:"digests,omitempty"         validate:"dive"`
+	Logging        *Logging              `yaml:"logging,omitempty"`
+
+	// Files lists the main config and the files it included, in load
+	// order. Set by Load.
+	Files []string `yaml:"-"`
+	// Declared holds the alerts as written, before Defaults and
+	// AlertTemplates were applied to Alerts. Set by Load.
+	Declared []Alert `yaml:"-"`
 }
 
 type Options struct {
 	HealthchecksDir string `yaml:"healthchecks_dir,omitempty"`
 	CacheDir        string `yaml:"cache_dir,omitempty"`
 	LogsDir         string `yaml:"logs_dir,omitempty"`
+	StateDir        string `yaml:"state_dir,omitempty"`
+	HistoryMaxSize  string `yaml:"history_max_size,omitempty"`
+	MetricsListen   string `yaml:"metrics_listen,omitempty"`
+	ControlSocket   string `yaml:"control_socket,omitempty"`
+	Retry           *Retry `yaml:"retry,omitempty"`
+}
+
+// Retry configures notification delivery retries. Unset fields on an alert
+// fall back to options.retry, then to the built-in defaults.
+type Retry struct {
+	Attempts   *int   `yaml:"attempts,omitempty"    validate:"omitempty,min=0"`
+	Backoff    string `yaml:"backoff,omitempty"`
+	MaxBackoff string `yaml:"max_backoff,omitempty"`
+}
+
+// Logging configures the daemon's logs. Without it, `sznuper start` logs
+// text to stderr.
+type Logging struct {
+	Format            string     `yaml:"format,omitempty" validate:"omitempty,oneof=text json"`
+	Level             string     `yaml:"level,omitempty"  validate:"omitempty,oneof=debug info warn error"`
+	Sinks             []LogSink  `yaml:"sinks,omitempty"  validate:"dive"`
+	HealthcheckOutput *LogStream `yaml:"healthcheck_output,omitempty"`
+}
+
+// LogSink is one destination of the daemon log. Unset format and level fall
+// back to the logging section's.
+type LogSink struct {
+	Type    string     `yaml:"type"              validate:"required,oneof=stderr file syslog"`
+	Format  string     `yaml:"format,omitempty"  validate:"omitempty,oneof=text json"`
+	Level   string     `yaml:"level,omitempty"   validate:"omitempty,oneof=debug info warn error"`
+	Path    string     `yaml:"path,omitempty"`    // file: relative to options.logs_dir
+	Address string     `yaml:"address,omitempty"` // syslog: socket path or udp://, tcp:// address
+	Tag     string     `yaml:"tag,omitempty"`     // syslog
+	Rotate  *LogRotate `yaml:"rotate,omitempty"`  // file
+}
+
+// LogStream routes healthcheck stdout and stderr to one file per alert.
+type LogStream struct {
+	Dir    string     `yaml:"dir,omitempty"` // relative to options.logs_dir
+	Format string     `yaml:"format,omitempty" validate:"omitempty,oneof=text json"`
+	Rotate *LogRotate `yaml:"rotate,omitempty"`
+}
+
+// LogRotate configures log file rotation and retention.
+type LogRotate struct {
+	MaxSize string `yaml:"max_size,omitempty"`
+	Every   string `yaml:"every,omitempty"`
+	Keep    int    `yaml:"keep,omitempty"    validate:"min=0"`
+	MaxAge  string `yaml:"max_age,omitempty"`
 }
 
 type Channel struct {
@@ -28,18 +96,167 @@ type Channel struct {
 	Params map[string]string `yaml:"params,omitempty"`
 }
 
+// Alert is a healthcheck run on triggers and the handling of its events.
+// Name, Healthcheck and Template are required once defaults and templates
+// are applied; Load checks them.
 type Alert struct {
-	Name        string         `yaml:"name"        validate:"required"`
-	Healthcheck string         `yaml:"healthcheck" validate:"required"`
+	Name        string         `yaml:"name,omitempty"`
+	Extends     NameList       `yaml:"extends,omitempty"`
+	Healthcheck string         `yaml:"healthcheck,omitempty"`
 	SHA256      SHA256         `yaml:"sha256,omitempty"`
-	Triggers    []Trigger      `yaml:"triggers"`
+	Triggers    []Trigger      `yaml:"triggers,omitempty"`
 	Timeout     string         `yaml:"timeout,omitempty"`
+	Output      string         `yaml:"output,omitempty"  validate:"omitempty,oneof=events json"`
 	Args        map[string]any `yaml:"args,omitempty"`
 	SideEffects []string       `yaml:"side_effects,omitempty"`
-	Template    string         `yaml:"template"    validate:"required"`
+	Template    string         `yaml:"template,omitempty"`
 	Cooldown    string         `yaml:"cooldown,omitempty"`
 	Notify      []NotifyTarget `yaml:"notify,omitempty" validate:"dive"`
 	Events      *Events        `yaml:"events,omitempty"`
+	Retry       *Retry         `yaml:"retry,omitempty"`
+	DependsOn   []Dependency   `yaml:"depends_on,omitempty" validate:"dive"`
+	Group       *Group         `yaml:"group,omitempty"`
+	Escalation  string         `yaml:"escalation,omitempty"`
+	DropIf      *Expr          `yaml:"drop_if,omitempty"`
+	KeepIf      *Expr          `yaml:"keep_if,omitempty"`
+	Thresholds  *Thresholds    `yaml:"thresholds,omitempty"`
+}
+
+// Thresholds derives an event's type from a numeric field: the first range
+// the value falls in sets the type. Hysteresis keeps an event in its
+// previous range until the value leaves it by more than that margin.
+type Thresholds struct {
+	Field      string           `yaml:"field"                validate:"required"`
+	Ranges     []ThresholdRange `yaml:"ranges"               validate:"required,min=1,dive"`
+	Hysteresis float64          `yaml:"hysteresis,omitempty" validate:"min=0"`
+}
+
+// ThresholdRange matches values from Min (inclusive) up to Max (exclusive).
+// A missing bound is unbounded. Hysteresis overrides the block's margin.
+type ThresholdRange struct {
+	Type       string   `yaml:"type"                 validate:"required"`
+	Min        *float64 `yaml:"min,omitempty"`
+	Max        *float64 `yaml:"max,omitempty"`
+	Hysteresis *float64 `yaml:"hysteresis,omitempty" validate:"omitempty,min=0"`
+}
+
+// Expr is a rule expression over event fields, args and globals (see
+// package expr). It is compiled when the config is decoded.
+type Expr struct {
+	*expr.Expr
+}
+
+func (e Expr) MarshalYAML() (any, error) {
+	if e.Expr == nil {
+		return nil, nil
+	}
+	return e.String(), nil
+}
+
+func (e *Expr) UnmarshalYAML(unmarshal func(any) error) error {
+	var src string
+	if err := unmarshal(&src); err != nil {
+		return fmt.Errorf("must be an expression string")
+	}
+	compiled, err := expr.Compile(src)
+	if err != nil {
+		return err
+	}
+	e.Expr = compiled
+	return nil
+}
+
+// Escalation routes an unhealthy alert's notifications through steps that
+// are reached one after another while it stays unhealthy.
+type Escalation struct {
+	Steps []EscalationStep `yaml:"steps" validate:"required,min=1,dive"`
+}
+
+// EscalationStep notifies its channels once the alert has been unhealthy
+// for After, and again every Repeat until the next step is reached.
+type EscalationStep struct {
+	After  string         `yaml:"after,omitempty"`
+	Notify []NotifyTarget `yaml:"notify" validate:"required,min=1,dive"`
+	Repeat string         `yaml:"repeat,omitempty"`
+}
+
+// Group batches an alert's notifications per channel: events are collected
+// for a while and sent as one message. Notify targets may override it.
+type Group struct {
+	// By lists the event fields whose values split events into separate
+	// groups. Empty means one group per alert and channel.
+	By FieldList `yaml:"by,omitempty"`
+	// Wait delays the first notification of a new group; Interval spaces
+	// the following ones.
+	Wait     string `yaml:"wait,omitempty"`
+	Interval string `yaml:"interval,omitempty"`
+	// Cron sends the collected events on a schedule instead.
+	Cron     string `yaml:"cron,omitempty"`
+	Template string `yaml:"template,omitempty"`
+}
+
+// Digest collects the events of every alert, or of the listed alerts, and
+// sends them as one summary on a cron schedule, e.g. a daily report.
+type Digest struct {
+	Cron     string         `yaml:"cron"             validate:"required"`
+	Alerts   []string       `yaml:"alerts,omitempty"` // empty = all alerts
+	Notify   []NotifyTarget `yaml:"notify"           validate:"required,min=1,dive"`
+	Template string         `yaml:"template,omitempty"`
+}
+
+// Dependency inhibits an alert's notifications while another alert is
+// unhealthy.
+//
+// YAML formats:
+//
+//   - uplink                      (plain alert name)
+//   - alert: systemd_units        (object)
+//     match: unit
+//     reevaluate: true
+type Dependency struct {
+	Alert string `yaml:"alert" validate:"required"`
+	// Match lists this alert's event fields whose values select the parent
+	// entity, in the order of the parent's events.key. Empty means the
+	// parent as a whole.
+	Match FieldList `yaml:"match,omitempty"`
+	// Reevaluate re-runs this alert when the parent recovers, if the parent
+	// inhibited it in the meantime.
+	Reevaluate bool `yaml:"reevaluate,omitempty"`
+}
+
+func (d *Dependency) UnmarshalYAML(unmarshal func(any) error) error {
+	var str string
+	if err := unmarshal(&str); err == nil {
+		d.Alert = str
+		return nil
+	}
+
+	type plain Dependency
+	if err := unmarshal((*plain)(d)); err != nil {
+		return fmt.Errorf("depends_on: must be an alert name or an object with alert, match and reevaluate")
+	}
+	return nil
+}
+
+// Silence suppresses notifications and side effects for matching events.
+// It is either a recurring maintenance window (cron + duration) or a one-off
+// window (start + end or duration). Times are RFC 3339.
+type Silence struct {
+	Name     string       `yaml:"name"     validate:"required"`
+	Match    SilenceMatch `yaml:"match"`
+	Cron     string       `yaml:"cron,omitempty"`
+	Duration string       `yaml:"duration,omitempty"`
+	Start    string       `yaml:"start,omitempty"`
+	End      string       `yaml:"end,omitempty"`
+	Comment  string       `yaml:"comment,omitempty"`
+}
+
+// SilenceMatch selects the events a silence applies to. Values are glob
+// patterns; empty matches anything.
+type SilenceMatch struct {
+	Alert     string            `yaml:"alert,omitempty"`
+	EventType string            `yaml:"event_type,omitempty"`
+	Fields    map[string]string `yaml:"fields,omitempty"`
 }
 
 type Trigger struct {
@@ -86,19 +303,90 @@ func (s *SHA256) UnmarshalYAML(unmarshal func(any) error) error {
 
 // Events configures per-event-type handling for an alert.
 type Events struct {
-	Healthy     []string                 `yaml:"healthy,omitempty"`
-	OnUnmatched string                   `yaml:"on_unmatched,omitempty"`
-	Override    map[string]EventOverride `yaml:"override,omitempty"`
+	Healthy            []string                 `yaml:"healthy,omitempty"`
+	OnUnmatched        string                   `yaml:"on_unmatched,omitempty"`
+	UnhealthyThreshold int                      `yaml:"unhealthy_threshold,omitempty" validate:"min=0"`
+	HealthyThreshold   int                      `yaml:"healthy_threshold,omitempty"   validate:"min=0"`
+	Flap               *Flap                    `yaml:"flap,omitempty"`
+	Key                FieldList                `yaml:"key,omitempty"`
+	Override           map[string]EventOverride `yaml:"override,omitempty"            validate:"dive"`
 }
 
-// EventOverride provides per-event-type overrides for template, cooldown, and notify.
+// Flap configures flap detection for the healthy/unhealthy state machine.
+// An alert is flapping once Threshold transitions happen within Window, and
+// settles once the transitions within Window drop to Settle or fewer.
+type Flap struct {
+	Window    string `yaml:"window"             validate:"required"`
+	Threshold int    `yaml:"threshold"          validate:"required,min=2"`
+	Settle    int    `yaml:"settle,omitempty"   validate:"min=0,ltfield=Threshold"`
+	Template  string `yaml:"template,omitempty"`
+}
+
+// FieldList names one or more event fields. It accepts a plain string or a
+// list of strings.
+type FieldList []string
+
+func (f FieldList) MarshalYAML() (any, error) {
+	if len(f) == 1 {
+		return f[0], nil
+	}
+	return []string(f), nil
+}
+
+func (f *FieldList) UnmarshalYAML(unmarshal func(any) error) error {
+	var str string
+	if err := unmarshal(&str); err == nil {
+		*f = FieldList{str}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return fmt.Errorf("must be a field name or a list of field names")
+	}
+	*f = list
+	return nil
+}
+
+// NameList names one or more alert templates. It accepts a plain string
+// or a list of strings.
+type NameList []string
+
+func (n NameList) MarshalYAML() (any, error) {
+	if len(n) == 1 {
+		return n[0], nil
+	}
+	return []string(n), nil
+}
+
+func (n *NameList) UnmarshalYAML(unmarshal func(any) error) error {
+	var str string
+	if err := unmarshal(&str); err == nil {
+		*n = NameList{str}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return fmt.Errorf("must be a template name or a list of template names")
+	}
+	*n = list
+	return nil
+}
+
+// EventOverride provides per-event-type overrides for template, cooldown,
+// notify, escalation, and the consecutive-event thresholds of the state
+// machine. With When set, the override only applies to events it matches.
 type EventOverride struct {
-	Template string         `yaml:"template,omitempty"`
-	Cooldown string         `yaml:"cooldown,omitempty"`
-	Notify   []NotifyTarget `yaml:"notify,omitempty"`
+	When               *Expr          `yaml:"when,omitempty"`
+	Template           string         `yaml:"template,omitempty"`
+	Cooldown           string         `yaml:"cooldown,omitempty"`
+	Notify             []NotifyTarget `yaml:"notify,omitempty"`
+	Escalation         string         `yaml:"escalation,omitempty"`
+	UnhealthyThreshold int            `yaml:"unhealthy_threshold,omitempty" validate:"min=0"`
+	HealthyThreshold   int            `yaml:"healthy_threshold,omitempty"   validate:"min=0"`
 }
 
-// NotifyTarget handles a plain channel name string or a channel object with params.
+// NotifyTarget handles a plain channel name string or a channel object with
+// params and an optional group override.
 //
 // YAML formats:
 //
@@ -106,20 +394,24 @@ type EventOverride struct {
 //   - telegram:                   (map with channel name as key)
 //     params:
 //     notification: "false"
+//     group:
+//     wait: 1m
 type NotifyTarget struct {
 	Channel string            `yaml:"-" validate:"required"`
 	Params  map[string]string `yaml:"params,omitempty"`
+	Group   *Group            `yaml:"group,omitempty"`
 }
 
 func (n NotifyTarget) MarshalYAML() (any, error) {
-	if len(n.Params) == 0 {
+	if len(n.Params) == 0 && n.Group == nil {
 		return n.Channel, nil
 	}
-	type paramsOnly struct {
-		Params map[string]string `yaml:"params"`
+	type object struct {
+		Params map[string]string `yaml:"params,omitempty"`
+		Group  *Group            `yaml:"group,omitempty"`
 	}
 	return map[string]any{
-		n.Channel: paramsOnly{Params: n.Params},
+		n.Channel: object{Params: n.Params, Group: n.Group},
 	}, nil
 }
 
@@ -131,9 +423,10 @@ func (n *NotifyTarget) UnmarshalYAML(unmarshal func(any) error) error {
 		return nil
 	}
 
-	// Try map: { telegram: { params: { ... } } }
+	// Try map: { telegram: { params: { ... }, group: { ... } } }
 	var obj map[string]struct {
 		Params map[string]string `yaml:"params"`
+		Group  *Group            `yaml:"group"`
 	}
 	if err := unmarshal(&obj); err != nil {
 		return fmt.Errorf("notify: must be a channel name string or a channel object")
@@ -144,6 +437,7 @@ func (n *NotifyTarget) UnmarshalYAML(unmarshal func(any) error) error {
 	for name, cfg := range obj {
 		n.Channel = name
 		n.Params = cfg.Params
+		n.Group = cfg.Group
 	}
 	return nil
 }
@@ -159,24 +453,76 @@ func LoadRaw(data []byte) (*Config, error) {
 	return &cfg, nil
 }
 
+// Load reads the config at path and merges in the channels and alerts of
+// the files it includes: those matching its include patterns and the
+// conf.d/*.yml drop-ins next to it. Alerts are then resolved against the
+// defaults and the templates they extend. Errors name the file they come
+// from.
 func Load(path string) (*Config, error) {
+	var cfg Config
+	if err := decodeFile(path, &cfg); err != nil {
+		return nil, err
+	}
+	cfg.Files = []string{path}
+	if err := checkRetry(nil, cfg.Options.Retry); err != nil {
+		return nil, fmt.Errorf("config %s: options.retry: %w", path, err)
+	}
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(cfg.Escalations)) {
+		if err := checkEscalation(cfg.Escalations[name]); err != nil {
+			errs = append(errs, fmt.Errorf("config %s: escalations.%s: %w", path, name, err))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Digests)) {
+		if err := checkDigest(cfg.Digests[name]); err != nil {
+			errs = append(errs, fmt.Errorf("config %s: digests.%s: %w", path, name, err))
+		}
+	}
+	for _, sil := range cfg.Silences {
+		if _, err := sil.Window(); err != nil {
+			errs = append(errs, fmt.Errorf("config %s: silence %q: %w", path, sil.Name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	origins, err := loadIncludes(&cfg, path)
+	if err != nil {
+		return nil, err
+	}
+	registerChannelSecrets(cfg.Channels)
+	if err := resolveAlerts(&cfg, path, origins); err != nil {
+		return nil, err
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Digests)) {
+		if err := checkDigestRefs(cfg.Digests[name], cfg.Alerts); err != nil {
+			errs = append(errs, fmt.Errorf("config %s: digests.%s: %w", path, name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// decodeFile expands env vars in the YAML file at path and decodes it into
+// v, strictly and with validation.
+func decodeFile(path string, v any) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("reading config: %w", err)
+		return fmt.Errorf("reading config: %w", err)
 	}
 
-	data, err = envsubst.Bytes(data)
+	data, err = expandEnv(data)
 	if err != nil {
-		return nil, fmt.Errorf("expanding env vars: %w", err)
+		return fmt.Errorf("config %s: expanding env vars: %w", path, err)
 	}
 
 	validate := validator.New(validator.WithRequiredStructEnabled())
 
-	var cfg Config
 	dec := yaml.NewDecoder(bytes.NewReader(data), yaml.Validator(validate), yaml.Strict())
-	if err := dec.Decode(&cfg); err != nil {
-		return nil, fmt.Errorf("config: %w", err)
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
 	}
-
-	return &cfg, nil
+	return nil
 }
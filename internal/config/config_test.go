@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -317,6 +318,747 @@ alerts:
 	}
 }
 
+func TestRetry(t *testing.T) {
+	yml := `
+options:
+  retry:
+    attempts: 5
+    backoff: 2s
+    max_backoff: 1m
+alerts:
+  - name: test
+    healthcheck: file://test
+    template: "test"
+    retry:
+      attempts: 0
+`
+	cfg := loadFromString(t, yml)
+	global := cfg.Options.Retry
+	if global == nil || global.Attempts == nil || *global.Attempts != 5 {
+		t.Fatalf("options.retry = %+v, want attempts 5", global)
+	}
+	if global.Backoff != "2s" || global.MaxBackoff != "1m" {
+		t.Errorf("options.retry backoff = %q/%q, want 2s/1m", global.Backoff, global.MaxBackoff)
+	}
+	alert := cfg.Alerts[0].Retry
+	if alert == nil || alert.Attempts == nil || *alert.Attempts != 0 {
+		t.Errorf("alert retry = %+v, want attempts 0", alert)
+	}
+}
+
+func TestValidation_RetryNegativeAttempts(t *testing.T) {
+	err := loadErr(t, `
+alerts:
+  - name: test
+    healthcheck: file://test
+    template: "test"
+    retry:
+      attempts: -1
+`)
+	if err == nil {
+		t.Fatal("expected validation error for negative retry attempts")
+	}
+}
+
+func TestValidation_RetryDurations(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		alert   string
+		want    string
+	}{
+		{"unparseable", "", "backoff: soon", `alert "test": retry: invalid backoff "soon"`},
+		{"negative", "", "max_backoff: -1s", `alert "test": retry: invalid max_backoff "-1s"`},
+		{"above default max", "", "backoff: 1m", "backoff 1m0s exceeds max_backoff 30s"},
+		{"above global max", "max_backoff: 5s", "backoff: 10s", "backoff 10s exceeds max_backoff 5s"},
+		{"global", "backoff: 2m", "", "options.retry: backoff 2m0s exceeds max_backoff 30s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yml := "alerts:\n  - name: test\n    healthcheck: file://test\n    template: test\n"
+			if tt.alert != "" {
+				yml += "    retry:\n      " + tt.alert + "\n"
+			}
+			if tt.options != "" {
+				yml = "options:\n  retry:\n    " + tt.options + "\n" + yml
+			}
+			err := loadErr(t, yml)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	loadFromString(t, `
+options:
+  retry:
+    max_backoff: 0s
+alerts:
+  - name: test
+    healthcheck: file://test
+    template: test
+    retry:
+      backoff: 1h
+`)
+}
+
+func TestEventThresholds(t *testing.T) {
+	yml := `
+alerts:
+  - name: cpu
+    healthcheck: file://cpu
+    template: "test"
+    events:
+      healthy: [ok]
+      unhealthy_threshold: 3
+      healthy_threshold: 2
+      override:
+        critical_usage:
+          unhealthy_threshold: 1
+`
+	cfg := loadFromString(t, yml)
+	ev := cfg.Alerts[0].Events
+	if ev.UnhealthyThreshold != 3 || ev.HealthyThreshold != 2 {
+		t.Errorf("thresholds = %d/%d, want 3/2", ev.UnhealthyThreshold, ev.HealthyThreshold)
+	}
+	if got := ev.Override["critical_usage"].UnhealthyThreshold; got != 1 {
+		t.Errorf("override unhealthy_threshold = %d, want 1", got)
+	}
+}
+
+func TestEventsFlap(t *testing.T) {
+	yml := `
+alerts:
+  - name: cpu
+    healthcheck: file://cpu
+    template: "test"
+    events:
+      healthy: [ok]
+      flap:
+        window: 10m
+        threshold: 4
+        settle: 1
+        template: "{{alert.name}} flapping={{state.flapping}}"
+`
+	cfg := loadFromString(t, yml)
+	f := cfg.Alerts[0].Events.Flap
+	if f == nil || f.Window != "10m" || f.Threshold != 4 || f.Settle != 1 {
+		t.Errorf("flap = %+v", f)
+	}
+}
+
+func TestValidation_FlapSettleNotBelowThreshold(t *testing.T) {
+	err := loadErr(t, `
+alerts:
+  - name: cpu
+    healthcheck: file://cpu
+    template: "test"
+    events:
+      healthy: [ok]
+      flap:
+        window: 10m
+        threshold: 3
+        settle: 3
+`)
+	if err == nil {
+		t.Fatal("expected validation error for settle >= threshold")
+	}
+}
+
+func TestValidation_FlapWindow(t *testing.T) {
+	for _, window := range []string{"ten minutes", "-10m", "0s"} {
+		err := loadErr(t, `
+alerts:
+  - name: cpu
+    healthcheck: file://cpu
+    template: "test"
+    events:
+      healthy: [ok]
+      flap:
+        window: `+window+`
+        threshold: 3
+`)
+		if err == nil || !strings.Contains(err.Error(), "events.flap: invalid window") {
+			t.Errorf("window %s: err = %v, want an invalid window error", window, err)
+		}
+	}
+}
+
+func TestEventsKey(t *testing.T) {
+	yml := `
+alerts:
+  - name: units
+    healthcheck: file://systemd_unit
+    template: "test"
+    events:
+      key: unit
+  - name: ssh
+    healthcheck: file://ssh_journal
+    template: "test"
+    events:
+      key: [host, user]
+`
+	cfg := loadFromString(t, yml)
+	if got := cfg.Alerts[0].Events.Key; len(got) != 1 || got[0] != "unit" {
+		t.Errorf("key = %v, want [unit]", got)
+	}
+	if got := cfg.Alerts[1].Events.Key; len(got) != 2 || got[0] != "host" || got[1] != "user" {
+		t.Errorf("key = %v, want [host user]", got)
+	}
+}
+
+func TestAlertOutput(t *testing.T) {
+	cfg := loadFromString(t, `
+alerts:
+  - name: units
+    healthcheck: file://units
+    template: "test"
+    output: json
+`)
+	if cfg.Alerts[0].Output != "json" {
+		t.Errorf("output = %q, want json", cfg.Alerts[0].Output)
+	}
+
+	err := loadErr(t, `
+alerts:
+  - name: units
+    healthcheck: file://units
+    template: "test"
+    output: xml
+`)
+	if err == nil {
+		t.Fatal("expected validation error for unknown output format")
+	}
+}
+
+func TestSilences(t *testing.T) {
+	cfg := loadFromString(t, `
+silences:
+  - name: nightly_backup
+    cron: "0 2 * * *"
+    duration: 1h
+    comment: backups saturate disk io
+    match:
+      alert: disk_*
+      event_type: high_usage
+      fields:
+        mount: /backup
+`)
+	if len(cfg.Silences) != 1 {
+		t.Fatalf("silences = %d, want 1", len(cfg.Silences))
+	}
+	s := cfg.Silences[0]
+	if s.Name != "nightly_backup" || s.Cron != "0 2 * * *" || s.Duration != "1h" {
+		t.Errorf("silence = %+v", s)
+	}
+	if s.Match.Alert != "disk_*" || s.Match.EventType != "high_usage" || s.Match.Fields["mount"] != "/backup" {
+		t.Errorf("match = %+v", s.Match)
+	}
+
+	err := loadErr(t, `
+silences:
+  - cron: "0 2 * * *"
+    duration: 1h
+`)
+	if err == nil {
+		t.Fatal("expected validation error for silence without name")
+	}
+}
+
+func TestValidation_SilenceWindows(t *testing.T) {
+	tests := []struct {
+		name   string
+		window string
+		want   string
+	}{
+		{"bad cron", `cron: "0 25 * * *"` + "\n    duration: 1h", `silence "w": invalid cron "0 25 * * *"`},
+		{"cron without duration", `cron: "@daily"`, "cron requires a duration"},
+		{"cron with start", `cron: "@daily"` + "\n    duration: 1h\n    start: 2026-03-01T00:00:00Z", "cron cannot be combined with start or end"},
+		{"bad duration", "start: 2026-03-01T00:00:00Z\n    duration: -1h", `invalid duration "-1h"`},
+		{"bad end", "end: tomorrow", `invalid end "tomorrow"`},
+		{"end before start", "start: 2026-03-02T00:00:00Z\n    end: 2026-03-01T00:00:00Z", "end must be after start"},
+		{"nothing", "comment: empty", "one of cron, end or duration is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadErr(t, "silences:\n  - name: w\n    "+tt.window+"\n")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependsOn(t *testing.T) {
+	cfg := loadFromString(t, `
+alerts:
+  - name: nginx
+    healthcheck: file://nginx
+    template: "test"
+    depends_on:
+      - uplink
+      - alert: units
+        match: service
+        reevaluate: true
+  - name: uplink
+    healthcheck: file://ping
+    template: "test"
+    events:
+      healthy: [ok]
+  - name: units
+    healthcheck: file://units
+    template: "test"
+    events:
+      healthy: [active]
+      key: unit
+`)
+	deps := cfg.Alerts[0].DependsOn
+	if len(deps) != 2 {
+		t.Fatalf("depends_on = %+v, want 2 entries", deps)
+	}
+	if deps[0].Alert != "uplink" || len(deps[0].Match) != 0 || deps[0].Reevaluate {
+		t.Errorf("deps[0] = %+v", deps[0])
+	}
+	if deps[1].Alert != "units" || len(deps[1].Match) != 1 || deps[1].Match[0] != "service" || !deps[1].Reevaluate {
+		t.Errorf("deps[1] = %+v", deps[1])
+	}
+
+	err := loadErr(t, `
+alerts:
+  - name: nginx
+    healthcheck: file://nginx
+    template: "test"
+    depends_on:
+      - match: unit
+`)
+	if err == nil {
+		t.Fatal("expected validation error for dependency without alert")
+	}
+}
+
+func TestGroup(t *testing.T) {
+	cfg := loadFromString(t, `
+alerts:
+  - name: ssh_journal
+    healthcheck: file://ssh_journal
+    template: "test"
+    group:
+      by: [user, host]
+      wait: 30s
+      interval: 5m
+      template: "{{group.count}} failures"
+    notify:
+      - logfile
+      - telegram:
+          params:
+            notification: "false"
+          group:
+            cron: "0 8 * * *"
+`)
+	alert := cfg.Alerts[0]
+	g := alert.Group
+	if g == nil || len(g.By) != 2 || g.By[1] != "host" || g.Wait != "30s" || g.Interval != "5m" || g.Template != "{{group.count}} failures" {
+		t.Errorf("group = %+v", g)
+	}
+	if alert.Notify[0].Group != nil {
+		t.Errorf("notify[0] group = %+v, want nil", alert.Notify[0].Group)
+	}
+	tg := alert.Notify[1]
+	if tg.Group == nil || tg.Group.Cron != "0 8 * * *" || tg.Params["notification"] != "false" {
+		t.Errorf("notify[1] = %+v", tg)
+	}
+}
+
+func TestValidation_Groups(t *testing.T) {
+	tests := []struct {
+		name  string
+		group string
+		want  string
+	}{
+		{"unparseable wait", "wait: soon", `alert "ssh": group: invalid wait "soon"`},
+		{"negative wait", "wait: -1s", `invalid wait "-1s"`},
+		{"zero interval", "interval: 0s", `invalid interval "0s"`},
+		{"bad cron", "cron: not a cron", `invalid cron "not a cron"`},
+		{"cron with wait", "cron: \"@daily\"\n      wait: 1m", "cron cannot be combined with wait or interval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadErr(t, `
+alerts:
+  - name: ssh
+    healthcheck: file://ssh
+    template: "test"
+    group:
+      `+tt.group+`
+`)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	err := loadErr(t, `
+alerts:
+  - name: ssh
+    healthcheck: file://ssh
+    template: "test"
+    events:
+      override:
+        failure:
+          notify:
+            - telegram:
+                group:
+                  interval: never
+`)
+	if err == nil || !strings.Contains(err.Error(), `events.override.failure: notify telegram: group: invalid interval "never"`) {
+		t.Errorf("override: err = %v, want an invalid interval error", err)
+	}
+}
+
+func TestDigests(t *testing.T) {
+	cfg := loadFromString(t, `
+digests:
+  daily:
+    cron: "0 8 * * *"
+    alerts: [ssh]
+    notify: [mail]
+alerts:
+  - name: ssh
+    healthcheck: file://ssh
+    template: "test"
+`)
+	d, ok := cfg.Digests["daily"]
+	if !ok || d.Cron != "0 8 * * *" || len(d.Alerts) != 1 || d.Alerts[0] != "ssh" || d.Notify[0].Channel != "mail" {
+		t.Fatalf("digests = %+v", cfg.Digests)
+	}
+
+	tests := []struct {
+		name   string
+		digest string
+		want   string
+	}{
+		{"bad cron", "cron: never\n    notify: [mail]", `digests.daily: invalid cron "never"`},
+		{"unknown alert", "cron: \"@daily\"\n    alerts: [sshd]\n    notify: [mail]", `digests.daily: unknown alert "sshd"`},
+		{"no notify", "cron: \"@daily\"", "Notify"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadErr(t, `
+digests:
+  daily:
+    `+tt.digest+`
+alerts:
+  - name: ssh
+    healthcheck: file://ssh
+    template: "test"
+`)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidation_Dependencies(t *testing.T) {
+	const parents = `
+  - name: uplink
+    healthcheck: file://ping
+    template: "test"
+    events:
+      healthy: [ok]
+  - name: units
+    healthcheck: file://units
+    template: "test"
+    events:
+      healthy: [active]
+      key: [unit, host]
+  - name: disk
+    healthcheck: file://disk
+    template: "test"
+`
+	tests := []struct {
+		name      string
+		dependsOn string
+		want      string
+	}{
+		{"unknown", "[uplnk]", `alert "web": depends_on unknown alert "uplnk"`},
+		{"itself", "[web]", `alert "web": depends_on itself`},
+		{"stateless parent", "[disk]", `depends_on "disk", which has no events.healthy`},
+		{"match mismatch", "\n      - alert: units\n        match: [unit]", `depends_on "units" matches 1 field(s), but its events.key has 2`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadErr(t, `
+alerts:`+parents+`
+  - name: web
+    healthcheck: file://web
+    template: "test"
+    events:
+      healthy: [ok]
+    depends_on: `+tt.dependsOn+`
+`)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	err := loadErr(t, `
+alerts:
+  - name: a
+    healthcheck: file://a
+    template: "test"
+    events:
+      healthy: [ok]
+    depends_on: [b]
+  - name: b
+    healthcheck: file://b
+    template: "test"
+    events:
+      healthy: [ok]
+    depends_on: [a]
+`)
+	if err == nil || !strings.Contains(err.Error(), "depends_on cycle: a -> b -> a") {
+		t.Errorf("cycle: err = %v, want a depends_on cycle error", err)
+	}
+}
+
+func TestEscalations(t *testing.T) {
+	cfg := loadFromString(t, `
+escalations:
+  oncall:
+    steps:
+      - notify: [ops-slack]
+      - after: 15m
+        notify: [telegram]
+        repeat: 30m
+alerts:
+  - name: uplink
+    healthcheck: file://ping
+    template: "test"
+    escalation: oncall
+    events:
+      healthy: [ok]
+      override:
+        critical:
+          escalation: oncall
+`)
+	esc, ok := cfg.Escalations["oncall"]
+	if !ok || len(esc.Steps) != 2 {
+		t.Fatalf("escalations = %+v", cfg.Escalations)
+	}
+	if esc.Steps[0].After != "" || esc.Steps[0].Notify[0].Channel != "ops-slack" {
+		t.Errorf("steps[0] = %+v", esc.Steps[0])
+	}
+	if esc.Steps[1].After != "15m" || esc.Steps[1].Repeat != "30m" || esc.Steps[1].Notify[0].Channel != "telegram" {
+		t.Errorf("steps[1] = %+v", esc.Steps[1])
+	}
+	alert := cfg.Alerts[0]
+	if alert.Escalation != "oncall" || alert.Events.Override["critical"].Escalation != "oncall" {
+		t.Errorf("alert escalation = %q, override = %q", alert.Escalation, alert.Events.Override["critical"].Escalation)
+	}
+
+	err := loadErr(t, `
+escalations:
+  oncall:
+    steps:
+      - after: 15m
+`)
+	if err == nil {
+		t.Fatal("expected validation error for step without notify")
+	}
+}
+
+func TestValidation_Escalations(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps string
+		ref   string
+		want  string
+	}{
+		{"unparseable after", "- after: soon\n        notify: [slack]", "oncall", `escalations.oncall: step 1: invalid after "soon"`},
+		{"decreasing after", "- after: 1h\n        notify: [slack]\n      - after: 15m\n        notify: [slack]", "oncall", "step 2: after 15m0s is before the previous step"},
+		{"zero repeat", "- notify: [slack]\n        repeat: 0s", "oncall", `step 1: invalid repeat "0s"`},
+		{"unknown policy", "- notify: [slack]", "pager", `alert "uplink": unknown escalation "pager"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadErr(t, `
+escalations:
+  oncall:
+    steps:
+      `+tt.steps+`
+alerts:
+  - name: uplink
+    healthcheck: file://ping
+    template: "test"
+    escalation: `+tt.ref+`
+    events:
+      healthy: [ok]
+`)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	err := loadErr(t, `
+escalations:
+  oncall:
+    steps:
+      - notify: [slack]
+alerts:
+  - name: uplink
+    healthcheck: file://ping
+    template: "test"
+    events:
+      healthy: [ok]
+      override:
+        critical:
+          escalation: pager
+`)
+	if err == nil || !strings.Contains(err.Error(), `unknown escalation "pager"`) {
+		t.Errorf("override: err = %v, want an unknown escalation error", err)
+	}
+}
+
+func TestExpressions(t *testing.T) {
+	cfg := loadFromString(t, `
+alerts:
+  - name: ssh
+    healthcheck: file://ssh.sh
+    template: "{{event.user}} from {{event.ip}}"
+    triggers:
+      - watch: /var/log/auth.log
+    drop_if: event.ip in ["10.0.0.5"] and event.user == 'deploy'
+    keep_if: event.type != "heartbeat"
+    events:
+      override:
+        login:
+          when: event.user == "root"
+          template: "root login from {{event.ip}}"
+`)
+	a := cfg.Alerts[0]
+	if a.DropIf == nil || a.KeepIf == nil || a.Events.Override["login"].When == nil {
+		t.Fatalf("expressions not loaded: %+v", a)
+	}
+	if got := a.Events.Override["login"].When.String(); got != `event.user == "root"` {
+		t.Errorf("when = %q", got)
+	}
+	out, err := Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `when: event.user == "root"`) {
+		t.Errorf("marshaled config lost the expression:\n%s", out)
+	}
+
+	err = loadErr(t, `
+alerts:
+  - name: disk
+    healthcheck: file://disk.sh
+    template: x
+    drop_if: event.usage > "high"
+`)
+	if err == nil || !strings.Contains(err.Error(), "needs numbers") {
+		t.Errorf("err = %v, want an expression error", err)
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	cfg := loadFromString(t, `
+alerts:
+  - name: disk
+    healthcheck: file://disk.sh
+    template: x
+    thresholds:
+      field: usage_percent
+      hysteresis: 2
+      ranges:
+        - type: critical_usage
+          min: 95
+        - type: high_usage
+          min: 80
+          hysteresis: 5
+        - type: ok
+    events:
+      healthy: [ok]
+`)
+	th := cfg.Alerts[0].Thresholds
+	if th == nil || th.Field != "usage_percent" || th.Hysteresis != 2 || len(th.Ranges) != 3 {
+		t.Fatalf("thresholds = %+v", th)
+	}
+	if r := th.Ranges[1]; r.Min == nil || *r.Min != 80 || r.Max != nil || r.Hysteresis == nil || *r.Hysteresis != 5 {
+		t.Errorf("range = %+v", r)
+	}
+
+	if err := loadErr(t, `
+alerts:
+  - name: disk
+    healthcheck: file://disk.sh
+    template: x
+    thresholds:
+      field: usage_percent
+`); err == nil {
+		t.Error("expected error for thresholds without ranges")
+	}
+
+	err := loadErr(t, `
+alerts:
+  - name: disk
+    healthcheck: file://disk.sh
+    template: x
+    thresholds:
+      field: usage_percent
+      ranges:
+        - type: high_usage
+          min: 95
+          max: 80
+`)
+	if want := `alert "disk": thresholds: range 1 (high_usage): min 95 is not below max 80`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	cfg := loadFromString(t, `
+logging:
+  format: json
+  sinks:
+    - type: stderr
+      format: text
+    - type: file
+      path: sznuper.log
+      level: debug
+      rotate:
+        max_size: 50MB
+        every: 24h
+        keep: 7
+    - type: syslog
+      address: udp://logs.internal:514
+      level: warn
+  healthcheck_output:
+    dir: healthchecks
+`)
+	lg := cfg.Logging
+	if lg == nil || lg.Format != "json" || len(lg.Sinks) != 3 || lg.HealthcheckOutput == nil {
+		t.Fatalf("logging = %+v", lg)
+	}
+	if s := lg.Sinks[1]; s.Rotate == nil || s.Rotate.MaxSize != "50MB" || s.Rotate.Keep != 7 || s.Level != "debug" {
+		t.Errorf("file sink = %+v", s)
+	}
+
+	for _, yml := range []string{
+		"logging:\n  sinks:\n    - type: kafka\n",
+		"logging:\n  format: xml\n",
+		"logging:\n  sinks:\n    - type: file\n      level: trace\n",
+	} {
+		if err := loadErr(t, yml); err == nil {
+			t.Errorf("expected error for %q", yml)
+		}
+	}
+}
+
 // helpers
 
 func loadErr(t *testing.T, yml string) error {
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/a8m/envsubst/parse"
+	"github.com/joho/godotenv"
+	"github.com/sznuper/sznuper/internal/secret"
+)
+
+var (
+	envMu sync.Mutex
+	// envFromFile holds the variables set by LoadEnvFile, which a later
+	// call may update. Everything else in the environment came from the
+	// process and wins over the file.
+	envFromFile = map[string]bool{}
+)
+
+// LoadEnvFile loads KEY=VALUE lines from path into the process environment,
+// for ${VAR} expansion in the config. Variables set in the process
+// environment take precedence over the file. Calling it again, e.g. on
+// reload, picks up changed values from the file. A missing file is an
+// error.
+func LoadEnvFile(path string) error {
+	vars, err := godotenv.Read(path)
+	if err != nil {
+		return fmt.Errorf("reading env file: %w", err)
+	}
+
+	envMu.Lock()
+	defer envMu.Unlock()
+	for k, v := range vars {
+		if _, set := os.LookupEnv(k); set && !envFromFile[k] {
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return fmt.Errorf("env file: setting %s: %w", k, err)
+		}
+		envFromFile[k] = true
+	}
+	return nil
+}
+
+// EnvFileWorldReadable reports whether the env file at path can be read by
+// every user.
+func EnvFileWorldReadable(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return info.Mode().Perm()&0o004 != 0, nil
+}
+
+// envRef matches the variable name of a $VAR or ${VAR} reference.
+var envRef = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// expandEnv substitutes ${VAR} references in config data.
+func expandEnv(data []byte) ([]byte, error) {
+	env, err := secretEnv(data)
+	if err != nil {
+		return nil, err
+	}
+	out, err := parse.New("config", env, parse.Relaxed).Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// secretEnv returns the process environment plus, for every variable the
+// config references that is unset but has a VAR_FILE variable, VAR set to
+// the contents of that file, e.g. a Docker secret or systemd credential.
+// Such values are registered as secrets. A trailing newline is dropped.
+func secretEnv(data []byte) ([]string, error) {
+	env := os.Environ()
+	seen := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, m := range envRef.FindAllStringSubmatch(line, -1) {
+			name := m[1]
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if _, set := os.LookupEnv(name); set {
+				continue
+			}
+			path, ok := os.LookupEnv(name + "_FILE")
+			if !ok {
+				continue
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("reading secret file for ${%s}: %w", name, err)
+			}
+			v := strings.TrimRight(string(b), "\r\n")
+			secret.Register(v)
+			env = append(env, name+"="+v)
+		}
+	}
+	return env, nil
+}
+
+// UnresolvedEnv returns the ${VAR} references in the config file at path
+// that expand to nothing: unset or empty variables without a default.
+// References in comment lines are ignored.
+func UnresolvedEnv(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			lines[i] = ""
+		}
+	}
+
+	env, err := secretEnv(data)
+	if err != nil {
+		return nil, err
+	}
+	p := parse.New("config", env, parse.Strict)
+	p.Mode = parse.AllErrors
+	_, err = p.Parse(strings.Join(lines, "\n"))
+	if err == nil {
+		return nil, nil
+	}
+	var out []string
+	for _, msg := range strings.Split(err.Error(), "\n") {
+		if !slices.Contains(out, msg) {
+			out = append(out, msg)
+		}
+	}
+	return out, nil
+}
+
+// registerChannelSecrets registers the credentials in channel URLs and the
+// values of channel params with credential names as secrets, whether they
+// came from ${VAR}, a VAR_FILE or the config itself. Values with template
+// variables are rendered per event and left out.
+func registerChannelSecrets(channels map[string]Channel) {
+	for _, ch := range channels {
+		if !strings.Contains(ch.URL, "{{") {
+			secret.RegisterURL(ch.URL)
+		}
+		for k, v := range ch.Params {
+			if secret.IsCredentialName(k) && !strings.Contains(v, "{{") {
+				secret.Register(v)
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+)
+
+// DropInDir is the directory next to the main config whose *.yml files are
+// included automatically.
+const DropInDir = "conf.d"
+
+// include is what an included file may define.
+type include struct {
+	Channels map[string]Channel `yaml:"channels,omitempty" validate:"dive"`
+	Alerts   []Alert            `yaml:"alerts,omitempty"   validate:"dive"`
+}
+
+// includePaths returns the files the main config at path includes: the
+// matches of its include patterns, resolved against its directory, then
+// conf.d/*.yml. Each file is returned once, and never the main config.
+func includePaths(path string, patterns []string) ([]string, error) {
+	dir := filepath.Dir(path)
+	seen := map[string]bool{filepath.Clean(path): true}
+	var files []string
+	for _, p := range append(slices.Clone(patterns), filepath.Join(DropInDir, "*.yml")) {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", p, err)
+		}
+		for _, m := range matches {
+			if m = filepath.Clean(m); !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+	return files, nil
+}
+
+// loadIncludes merges the channels and alerts of the files cfg includes
+// into cfg and returns the file each alert came from. Every alert needs a
+// name, and a channel or alert name defined twice, in any files, is an
+// error naming both.
+func loadIncludes(cfg *Config, path string) ([]string, error) {
+	files, err := includePaths(path, cfg.Include)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	var (
+		errs    []error
+		alerts  []Alert
+		origins []string
+	)
+	alertFile := make(map[string]string, len(cfg.Alerts))
+	addAlerts := func(file string, list []Alert) {
+		for i, a := range list {
+			if a.Name == "" {
+				errs = append(errs, fmt.Errorf("config %s: alerts[%d]: name is required", file, i))
+				continue
+			}
+			if prev, ok := alertFile[a.Name]; ok {
+				errs = append(errs, duplicate("alert", a.Name, prev, file))
+				continue
+			}
+			alertFile[a.Name] = file
+			alerts = append(alerts, a)
+			origins = append(origins, file)
+		}
+	}
+	addAlerts(path, cfg.Alerts)
+
+	channelFile := make(map[string]string, len(cfg.Channels))
+	for name := range cfg.Channels {
+		channelFile[name] = path
+	}
+	for _, f := range files {
+		var inc include
+		if err := decodeFile(f, &inc); errors.Is(err, io.EOF) {
+			// An empty drop-in, e.g. one with everything commented out.
+		} else if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, name := range slices.Sorted(maps.Keys(inc.Channels)) {
+			if prev, ok := channelFile[name]; ok {
+				errs = append(errs, duplicate("channel", name, prev, f))
+				continue
+			}
+			if cfg.Channels == nil {
+				cfg.Channels = make(map[string]Channel)
+			}
+			cfg.Channels[name] = inc.Channels[name]
+			channelFile[name] = f
+		}
+		addAlerts(f, inc.Alerts)
+		cfg.Files = append(cfg.Files, f)
+	}
+	cfg.Alerts = alerts
+	return origins, errors.Join(errs...)
+}
+
+func duplicate(kind, name, prev, file string) error {
+	if prev == file {
+		return fmt.Errorf("config %s: %s %q is defined twice", file, kind, name)
+	}
+	return fmt.Errorf("config %s: %s %q is already defined in %s", file, kind, name, prev)
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes name → content under dir, creating subdirectories.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const includeAlert = `
+  - name: %s
+    healthcheck: file://disk
+    template: "{{event.type}}"
+    triggers:
+      - interval: 1m
+`
+
+func alertYAML(names ...string) string {
+	var b strings.Builder
+	b.WriteString("alerts:")
+	for _, n := range names {
+		b.WriteString(strings.Replace(includeAlert, "%s", n, 1))
+	}
+	return b.String()
+}
+
+func TestLoad_Includes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yml": `include:
+  - services/*.yml
+  - conf.d/web.yml # also a drop-in, loaded once
+channels:
+  slack:
+    url: slack://token@channel
+` + alertYAML("main"),
+		"services/db.yml": `channels:
+  pager:
+    url: generic://pager.example.com
+` + alertYAML("db"),
+		"conf.d/web.yml":         alertYAML("web"),
+		"conf.d/empty.yml":       "# nothing here yet\n",
+		"conf.d/ignored.yml.bak": alertYAML("ignored"),
+	})
+
+	cfg, err := Load(filepath.Join(dir, "config.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, a := range cfg.Alerts {
+		names = append(names, a.Name)
+	}
+	if want := []string{"main", "db", "web"}; !slices.Equal(names, want) {
+		t.Errorf("alerts = %v, want %v", names, want)
+	}
+	if _, ok := cfg.Channels["pager"]; !ok || len(cfg.Channels) != 2 {
+		t.Errorf("channels = %v, want slack and pager", cfg.Channels)
+	}
+	want := []string{
+		filepath.Join(dir, "config.yml"),
+		filepath.Join(dir, "services", "db.yml"),
+		filepath.Join(dir, "conf.d", "web.yml"),
+		filepath.Join(dir, "conf.d", "empty.yml"),
+	}
+	if !slices.Equal(cfg.Files, want) {
+		t.Errorf("files = %v, want %v", cfg.Files, want)
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name: "duplicate alert",
+			files: map[string]string{
+				"config.yml":   alertYAML("disk"),
+				"conf.d/a.yml": alertYAML("disk"),
+			},
+			want: []string{`a.yml: alert "disk" is already defined in `, "config.yml"},
+		},
+		{
+			name: "duplicate channel",
+			files: map[string]string{
+				"config.yml":   "channels:\n  slack:\n    url: slack://a@b\n",
+				"conf.d/a.yml": "channels:\n  slack:\n    url: slack://c@d\n",
+			},
+			want: []string{`a.yml: channel "slack" is already defined in `},
+		},
+		{
+			name: "duplicate within one file",
+			files: map[string]string{
+				"config.yml": alertYAML("disk", "disk"),
+			},
+			want: []string{`config.yml: alert "disk" is defined twice`},
+		},
+		{
+			name: "invalid include",
+			files: map[string]string{
+				"config.yml":   "",
+				"conf.d/a.yml": alertYAML("disk") + "    bogus: true\n",
+			},
+			want: []string{"a.yml: ", "unknown field"},
+		},
+		{
+			name: "options in include",
+			files: map[string]string{
+				"config.yml":   "",
+				"conf.d/a.yml": "options:\n  logs_dir: /tmp\n",
+			},
+			want: []string{"a.yml: ", "unknown field \"options\""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.files["config.yml"] == "" {
+				tt.files["config.yml"] = "alerts: []\n"
+			}
+			writeFiles(t, dir, tt.files)
+			_, err := Load(filepath.Join(dir, "config.yml"))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not contain %q", err, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Alert inheritance: every alert starts from the defaults block, then the
+// templates it extends, in order, then its own fields. Each layer is merged
+// onto the previous one:
+//
+//   - scalars, and blocks such as group or thresholds, replace what they
+//     inherit when set;
+//   - lists replace what they inherit, even when empty, except notify;
+//   - args, events and retry merge key by key, and events.override merges
+//     per event type;
+//   - notify lists merge by channel: inherited targets stay, a target for
+//     an inherited channel merges its params and replaces its group, and
+//     an explicitly empty list drops what was inherited.
+
+// resolveAlerts applies the defaults and the alert templates to every alert
+// of cfg, keeping the alerts as written in cfg.Declared, and checks the
+// fields an alert needs, the values decoding can't check and the
+// dependencies between alerts. origins holds the file of each alert.
+func resolveAlerts(cfg *Config, path string, origins []string) error {
+	var errs []error
+	if d := cfg.Defaults; d != nil && (d.Name != "" || len(d.Extends) > 0) {
+		errs = append(errs, fmt.Errorf("config %s: defaults: name and extends are not allowed", path))
+	}
+
+	templates := make(map[string]Alert, len(cfg.AlertTemplates))
+	for _, name := range slices.Sorted(maps.Keys(cfg.AlertTemplates)) {
+		if cfg.AlertTemplates[name].Name != "" {
+			errs = append(errs, fmt.Errorf("config %s: alert_templates.%s: name is not allowed", path, name))
+			continue
+		}
+		if _, err := resolveTemplate(cfg.AlertTemplates, templates, name, nil); err != nil {
+			errs = append(errs, fmt.Errorf("config %s: alert_templates.%s: %w", path, name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	cfg.Declared = slices.Clone(cfg.Alerts)
+	for i, a := range cfg.Alerts {
+		var resolved Alert
+		if cfg.Defaults != nil {
+			resolved = mergeAlert(resolved, *cfg.Defaults)
+		}
+		for _, name := range a.Extends {
+			t, ok := templates[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("config %s: alert %q: extends unknown template %q", origins[i], a.Name, name))
+				continue
+			}
+			resolved = mergeAlert(resolved, t)
+		}
+		resolved = mergeAlert(resolved, a)
+		resolved.Extends = a.Extends
+
+		if resolved.Healthcheck == "" {
+			errs = append(errs, fmt.Errorf("config %s: alert %q: healthcheck is required", origins[i], a.Name))
+		}
+		if resolved.Template == "" {
+			errs = append(errs, fmt.Errorf("config %s: alert %q: template is required", origins[i], a.Name))
+		}
+		if err := checkRetry(cfg.Options.Retry, resolved.Retry); err != nil {
+			errs = append(errs, fmt.Errorf("config %s: alert %q: retry: %w", origins[i], a.Name, err))
+		}
+		if resolved.Events != nil {
+			if err := checkFlap(resolved.Events.Flap); err != nil {
+				errs = append(errs, fmt.Errorf("config %s: alert %q: events.flap: %w", origins[i], a.Name, err))
+			}
+		}
+		if err := checkThresholds(resolved.Thresholds); err != nil {
+			errs = append(errs, fmt.Errorf("config %s: alert %q: thresholds: %w", origins[i], a.Name, err))
+		}
+		if err := checkGroups(&resolved); err != nil {
+			errs = append(errs, fmt.Errorf("config %s: alert %q: %w", origins[i], a.Name, err))
+		}
+		if err := checkEscalationRefs(&resolved, cfg.Escalations); err != nil {
+			errs = append(errs, fmt.Errorf("config %s: alert %q: %w", origins[i], a.Name, err))
+		}
+		cfg.Alerts[i] = resolved
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return checkDependencies(cfg.Alerts, origins)
+}
+
+// resolveTemplate returns the template name merged onto the templates it
+// extends, memoized in resolved. stack holds the templates being resolved,
+// to detect cycles.
+func resolveTemplate(raw, resolved map[string]Alert, name string, stack []string) (Alert, error) {
+	if t, ok := resolved[name]; ok {
+		return t, nil
+	}
+	if slices.Contains(stack, name) {
+		return Alert{}, fmt.Errorf("extends cycle %s", strings.Join(append(stack, name), " -> "))
+	}
+	t, ok := raw[name]
+	if !ok {
+		return Alert{}, fmt.Errorf("extends unknown template %q", name)
+	}
+	stack = append(stack, name)
+	var out Alert
+	for _, parent := range t.Extends {
+		p, err := resolveTemplate(raw, resolved, parent, stack)
+		if err != nil {
+			return Alert{}, err
+		}
+		out = mergeAlert(out, p)
+	}
+	out = mergeAlert(out, t)
+	out.Extends = nil
+	resolved[name] = out
+	return out, nil
+}
+
+// mergeAlert returns base with over merged onto it. Neither is modified.
+func mergeAlert(base, over Alert) Alert {
+	out := base
+	out.Name = pick(base.Name, over.Name)
+	out.Extends = over.Extends
+	out.Healthcheck = pick(base.Healthcheck, over.Healthcheck)
+	if over.SHA256 != (SHA256{}) {
+		out.SHA256 = over.SHA256
+	}
+	out.Triggers = pickList(base.Triggers, over.Triggers)
+	out.Timeout = pick(base.Timeout, over.Timeout)
+	out.Output = pick(base.Output, over.Output)
+	out.Args = mergeArgs(base.Args, over.Args)
+	out.SideEffects = pickList(base.SideEffects, over.SideEffects)
+	out.Template = pick(base.Template, over.Template)
+	out.Cooldown = pick(base.Cooldown, over.Cooldown)
+	out.Notify = mergeNotify(base.Notify, over.Notify)
+	out.Events = mergeEvents(base.Events, over.Events)
+	out.Retry = mergeRetry(base.Retry, over.Retry)
+	out.DependsOn = pickList(base.DependsOn, over.DependsOn)
+	out.Group = pickPtr(base.Group, over.Group)
+	out.Escalation = pick(base.Escalation, over.Escalation)
+	out.DropIf = pickPtr(base.DropIf, over.DropIf)
+	out.KeepIf = pickPtr(base.KeepIf, over.KeepIf)
+	out.Thresholds = pickPtr(base.Thresholds, over.Thresholds)
+	return out
+}
+
+func mergeEvents(base, over *Events) *Events {
+	if base == nil || over == nil {
+		return pickPtr(base, over)
+	}
+	out := *base
+	out.Healthy = pickList(base.Healthy, over.Healthy)
+	out.OnUnmatched = pick(base.OnUnmatched, over.OnUnmatched)
+	out.UnhealthyThreshold = pick(base.UnhealthyThreshold, over.UnhealthyThreshold)
+	out.HealthyThreshold = pick(base.HealthyThreshold, over.HealthyThreshold)
+	out.Flap = pickPtr(base.Flap, over.Flap)
+	out.Key = pickList(base.Key, over.Key)
+	if base.Override != nil && over.Override != nil {
+		out.Override = maps.Clone(base.Override)
+		for typ, ov := range over.Override {
+			if prev, ok := out.Override[typ]; ok {
+				ov = mergeOverride(prev, ov)
+			}
+			out.Override[typ] = ov
+		}
+	} else if over.Override != nil {
+		out.Override = over.Override
+	}
+	return &out
+}
+
+func mergeOverride(base, over EventOverride) EventOverride {
+	out := base
+	out.When = pickPtr(base.When, over.When)
+	out.Template = pick(base.Template, over.Template)
+	out.Cooldown = pick(base.Cooldown, over.Cooldown)
+	out.Notify = mergeNotify(base.Notify, over.Notify)
+	out.Escalation = pick(base.Escalation, over.Escalation)
+	out.UnhealthyThreshold = pick(base.UnhealthyThreshold, over.UnhealthyThreshold)
+	out.HealthyThreshold = pick(base.HealthyThreshold, over.HealthyThreshold)
+	return out
+}
+
+func mergeRetry(base, over *Retry) *Retry {
+	if base == nil || over == nil {
+		return pickPtr(base, over)
+	}
+	out := *base
+	out.Attempts = pickPtr(base.Attempts, over.Attempts)
+	out.Backoff = pick(base.Backoff, over.Backoff)
+	out.MaxBackoff = pick(base.MaxBackoff, over.MaxBackoff)
+	return &out
+}
+
+// mergeNotify merges notify targets by channel, keeping base's order.
+func mergeNotify(base, over []NotifyTarget) []NotifyTarget {
+	if over == nil {
+		return base
+	}
+	if len(over) == 0 || len(base) == 0 {
+		return over
+	}
+	out := slices.Clone(base)
+	for _, t := range over {
+		i := slices.IndexFunc(out, func(b NotifyTarget) bool { return b.Channel == t.Channel })
+		if i < 0 {
+			out = append(out, t)
+			continue
+		}
+		merged := out[i]
+		if t.Params != nil {
+			merged.Params = maps.Clone(merged.Params)
+			if merged.Params == nil {
+				merged.Params = make(map[string]string, len(t.Params))
+			}
+			maps.Copy(merged.Params, t.Params)
+		}
+		merged.Group = pickPtr(merged.Group, t.Group)
+		out[i] = merged
+	}
+	return out
+}
+
+// mergeArgs merges args recursively: nested maps merge key by key, any
+// other value replaces what it inherits.
+func mergeArgs(base, over map[string]any) map[string]any {
+	if base == nil || over == nil {
+		return pickMap(base, over)
+	}
+	out := maps.Clone(base)
+	for k, v := range over {
+		bm, bok := out[k].(map[string]any)
+		om, ook := v.(map[string]any)
+		if bok && ook {
+			v = mergeArgs(bm, om)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// pick returns over if it is set, else base.
+func pick[T comparable](base, over T) T {
+	var zero T
+	if over != zero {
+		return over
+	}
+	return base
+}
+
+func pickPtr[T any](base, over *T) *T {
+	if over != nil {
+		return over
+	}
+	return base
+}
+
+// pickList returns over if it is set, even to an empty list, else base.
+func pickList[S ~[]E, E any](base, over S) S {
+	if over != nil {
+		return over
+	}
+	return base
+}
+
+func pickMap[M ~map[K]V, K comparable, V any](base, over M) M {
+	if over != nil {
+		return over
+	}
+	return base
+}
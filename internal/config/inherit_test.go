@@ -0,0 +1,155 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInheritance(t *testing.T) {
+	cfg := loadFromString(t, `
+defaults:
+  cooldown: 10m
+  notify:
+    - slack:
+        params:
+          color: grey
+  retry:
+    attempts: 5
+alert_templates:
+  base:
+    triggers:
+      - interval: 30s
+    args:
+      warn: 80
+      limits:
+        crit: 95
+        max: 100
+    events:
+      healthy: [ok]
+      override:
+        critical:
+          cooldown: 1m
+          notify: [pager]
+  usage:
+    extends: base
+    template: "{{event.type}}"
+alerts:
+  - name: disk
+    extends: usage
+    healthcheck: builtin://disk_usage
+    args:
+      mount: /
+      limits:
+        crit: 90
+    notify:
+      - slack:
+          params:
+            channel: ops
+      - email
+    retry:
+      backoff: 1s
+    events:
+      override:
+        critical:
+          template: disk full
+        warning:
+          cooldown: 5m
+  - name: quiet
+    healthcheck: builtin://lifecycle
+    template: lifecycle
+    triggers:
+      - lifecycle: true
+    notify: []
+    cooldown: 1h
+`)
+	disk := cfg.Alerts[0]
+	if disk.Template != "{{event.type}}" || disk.Cooldown != "10m" || len(disk.Triggers) != 1 || disk.Triggers[0].Interval != "30s" {
+		t.Errorf("scalars not inherited: template %q cooldown %q triggers %v", disk.Template, disk.Cooldown, disk.Triggers)
+	}
+	wantArgs := map[string]any{"warn": uint64(80), "mount": "/", "limits": map[string]any{"crit": uint64(90), "max": uint64(100)}}
+	if !reflect.DeepEqual(disk.Args, wantArgs) {
+		t.Errorf("args = %#v, want %#v", disk.Args, wantArgs)
+	}
+	if len(disk.Notify) != 2 || disk.Notify[0].Channel != "slack" || disk.Notify[1].Channel != "email" {
+		t.Fatalf("notify = %+v, want slack then email", disk.Notify)
+	}
+	if p := disk.Notify[0].Params; p["color"] != "grey" || p["channel"] != "ops" {
+		t.Errorf("slack params = %v, want merged", p)
+	}
+	if disk.Retry == nil || *disk.Retry.Attempts != 5 || disk.Retry.Backoff != "1s" {
+		t.Errorf("retry = %+v, want merged", disk.Retry)
+	}
+	crit := disk.Events.Override["critical"]
+	if crit.Template != "disk full" || crit.Cooldown != "1m" || len(crit.Notify) != 1 || crit.Notify[0].Channel != "pager" {
+		t.Errorf("override critical = %+v, want merged", crit)
+	}
+	if disk.Events.Override["warning"].Cooldown != "5m" || len(disk.Events.Healthy) != 1 {
+		t.Errorf("events = %+v", disk.Events)
+	}
+
+	quiet := cfg.Alerts[1]
+	if len(quiet.Notify) != 0 || quiet.Cooldown != "1h" || quiet.Retry == nil {
+		t.Errorf("quiet = %+v, want notify cleared, own cooldown, default retry", quiet)
+	}
+
+	// The alerts as written, and the templates, are left alone.
+	if d := cfg.Declared[0]; d.Template != "" || len(d.Notify) != 2 || d.Notify[0].Params["color"] != "" {
+		t.Errorf("declared alert modified: %+v", d)
+	}
+	if _, ok := cfg.AlertTemplates["base"].Args["mount"]; ok {
+		t.Error("template args modified")
+	}
+}
+
+func TestInheritanceErrors(t *testing.T) {
+	tests := []struct {
+		name, yml, want string
+	}{
+		{"unknown template", `
+alerts:
+  - name: disk
+    extends: missing
+    healthcheck: x
+    template: y
+`, `alert "disk": extends unknown template "missing"`},
+		{"cycle", `
+alert_templates:
+  a:
+    extends: b
+  b:
+    extends: [a]
+alerts: []
+`, "extends cycle a -> b -> a"},
+		{"missing after inheritance", `
+defaults:
+  template: t
+alerts:
+  - name: disk
+`, `alert "disk": healthcheck is required`},
+		{"name in defaults", `
+defaults:
+  name: all
+alerts: []
+`, "defaults: name and extends are not allowed"},
+		{"name in template", `
+alert_templates:
+  t:
+    name: x
+alerts: []
+`, "alert_templates.t: name is not allowed"},
+		{"missing name", `
+alerts:
+  - healthcheck: x
+    template: y
+`, "alerts[0]: name is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadErr(t, tt.yml)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
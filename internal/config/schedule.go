@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// CronParser parses the cron expressions of triggers, groups, digests and
+// silences: 5 fields, optional seconds, or descriptors such as @daily.
+var CronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// SilenceWindow is the parsed time range of a Silence: recurring, starting
+// at each Schedule activation and lasting Duration, or one-off from Start
+// to End. A zero Start means already started.
+type SilenceWindow struct {
+	Schedule cron.Schedule
+	Duration time.Duration
+	Start    time.Time
+	End      time.Time
+}
+
+// Window parses the time range of a maintenance window: a cron schedule
+// with a duration, or a start with an end or a duration, or just an end.
+func (s Silence) Window() (SilenceWindow, error) {
+	var w SilenceWindow
+	if s.Duration != "" {
+		d, err := time.ParseDuration(s.Duration)
+		if err != nil || d <= 0 {
+			return SilenceWindow{}, fmt.Errorf("invalid duration %q", s.Duration)
+		}
+		w.Duration = d
+	}
+
+	if s.Cron != "" {
+		if s.Start != "" || s.End != "" {
+			return SilenceWindow{}, errors.New("cron cannot be combined with start or end")
+		}
+		if w.Duration == 0 {
+			return SilenceWindow{}, errors.New("cron requires a duration")
+		}
+		sched, err := CronParser.Parse(s.Cron)
+		if err != nil {
+			return SilenceWindow{}, fmt.Errorf("invalid cron %q: %w", s.Cron, err)
+		}
+		w.Schedule = sched
+		return w, nil
+	}
+
+	var err error
+	if s.Start != "" {
+		if w.Start, err = time.Parse(time.RFC3339, s.Start); err != nil {
+			return SilenceWindow{}, fmt.Errorf("invalid start %q: want RFC 3339", s.Start)
+		}
+	}
+	switch {
+	case s.End != "" && w.Duration != 0:
+		return SilenceWindow{}, errors.New("end and duration are mutually exclusive")
+	case s.End != "":
+		if w.End, err = time.Parse(time.RFC3339, s.End); err != nil {
+			return SilenceWindow{}, fmt.Errorf("invalid end %q: want RFC 3339", s.End)
+		}
+	case w.Duration != 0 && !w.Start.IsZero():
+		w.End = w.Start.Add(w.Duration)
+	case w.Duration != 0:
+		return SilenceWindow{}, errors.New("duration requires cron or start")
+	default:
+		return SilenceWindow{}, errors.New("one of cron, end or duration is required")
+	}
+	if !w.End.After(w.Start) {
+		return SilenceWindow{}, errors.New("end must be after start")
+	}
+	return w, nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"1048576", 1 << 20, false},
+		{"10MB", 10 << 20, false},
+		{"512kb", 512 << 10, false},
+		{"1 GB", 1 << 30, false},
+		{"100B", 100, false},
+		{"0", 0, false},
+		{"", 0, true},
+		{"-1MB", 0, true},
+		{"ten", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d, err=%v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
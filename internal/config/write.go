@@ -42,6 +42,20 @@ func Write(cfg *Config, path string) error {
 	return nil
 }
 
+// NotifyByDefault adds channels to the notify list of the defaults block,
+// so every alert notifies them.
+func NotifyByDefault(cfg *Config, channels ...string) {
+	if len(channels) == 0 {
+		return
+	}
+	if cfg.Defaults == nil {
+		cfg.Defaults = &Alert{}
+	}
+	for _, name := range channels {
+		cfg.Defaults.Notify = append(cfg.Defaults.Notify, NotifyTarget{Channel: name})
+	}
+}
+
 // DefaultWritePath returns the default config write location:
 // /etc/sznuper/config.yml for root, ~/.config/sznuper/config.yml otherwise.
 func DefaultWritePath() string {
@@ -63,6 +77,7 @@ func DefaultOptions() Options {
 			HealthchecksDir: "/etc/sznuper/healthchecks",
 			CacheDir:        "/var/cache/sznuper",
 			LogsDir:         "/var/log/sznuper",
+			StateDir:        "/var/lib/sznuper",
 		}
 	}
 	home, _ := os.UserHomeDir()
@@ -70,6 +85,7 @@ func DefaultOptions() Options {
 		HealthchecksDir: filepath.Join(home, ".config", "sznuper", "healthchecks"),
 		CacheDir:        filepath.Join(home, ".cache", "sznuper"),
 		LogsDir:         filepath.Join(home, ".local", "state", "sznuper", "logs"),
+		StateDir:        filepath.Join(home, ".local", "state", "sznuper"),
 	}
 }
 
@@ -0,0 +1,152 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/sznuper/sznuper/internal/silence"
+)
+
+// Client talks to a running daemon over its control socket.
+type Client struct {
+	path string
+	http *http.Client
+}
+
+// NewClient creates a client for the socket at path.
+func NewClient(path string) *Client {
+	return &Client{
+		path: path,
+		http: &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}},
+	}
+}
+
+// Status returns the daemon's status.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var st Status
+	if err := c.do(ctx, http.MethodGet, "/v1/status", nil, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// Trigger runs alert once in the daemon and returns its events.
+func (c *Client) Trigger(ctx context.Context, alert string) ([]RunResult, error) {
+	var results []RunResult
+	if err := c.do(ctx, http.MethodPost, "/v1/alerts/"+url.PathEscape(alert)+"/trigger", nil, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Acknowledge acknowledges the unhealthy incident of alert and returns the
+// acknowledgements made.
+func (c *Client) Acknowledge(ctx context.Context, alert string, req AckRequest) ([]Ack, error) {
+	var acks []Ack
+	if err := c.do(ctx, http.MethodPost, "/v1/alerts/"+url.PathEscape(alert)+"/ack", req, &acks); err != nil {
+		return nil, err
+	}
+	return acks, nil
+}
+
+// Silences lists the daemon's active and pending silences.
+func (c *Client) Silences(ctx context.Context) ([]silence.Silence, error) {
+	var list []silence.Silence
+	if err := c.do(ctx, http.MethodGet, "/v1/silences", nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// AddSilence creates an ad-hoc silence and returns it with its ID.
+func (c *Client) AddSilence(ctx context.Context, s silence.Silence) (silence.Silence, error) {
+	var created silence.Silence
+	if err := c.do(ctx, http.MethodPost, "/v1/silences", s, &created); err != nil {
+		return silence.Silence{}, err
+	}
+	return created, nil
+}
+
+// ExpireSilence ends an ad-hoc silence.
+func (c *Client) ExpireSilence(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/v1/silences/"+url.PathEscape(id), nil, nil)
+}
+
+// Reload asks the daemon to reload its config and returns any load error.
+func (c *Client) Reload(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/v1/reload", nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "http://sznuper"+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return fmt.Errorf("cannot reach daemon at %s (is sznuper start running?): %w", c.path, opErr.Err)
+		}
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		var body errorBody
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+			return fmt.Errorf("daemon returned %s", resp.Status)
+		}
+		return &remoteError{msg: body.Error, code: resp.StatusCode}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding daemon response: %w", err)
+	}
+	return nil
+}
+
+// remoteError carries an error message returned by the daemon. Not-found
+// errors match ErrUnknownAlert and silence.ErrNotFound, conflicts match
+// ErrNotUnhealthy.
+type remoteError struct {
+	msg  string
+	code int
+}
+
+func (e *remoteError) Error() string { return e.msg }
+
+func (e *remoteError) Is(target error) bool {
+	switch e.code {
+	case http.StatusNotFound:
+		return target == ErrUnknownAlert || target == silence.ErrNotFound
+	case http.StatusConflict:
+		return target == ErrNotUnhealthy
+	}
+	return false
+}
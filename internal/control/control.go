@@ -0,0 +1,136 @@
+// Package control implements the daemon's local control API: JSON over HTTP
+// on a Unix domain socket. `sznuper start` serves it; `sznuper status`,
+// `sznuper trigger`, `sznuper reload`, `sznuper silence` and `sznuper ack`
+// are its clients.
+package control
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/silence"
+)
+
+// SocketName is the socket file name inside the default directory.
+const SocketName = "control.sock"
+
+var (
+	// ErrUnknownAlert is returned by Daemon.Trigger and Daemon.Acknowledge
+	// for alerts not in the running config.
+	ErrUnknownAlert = errors.New("unknown alert")
+	// ErrNotUnhealthy is returned by Daemon.Acknowledge when there is no
+	// unhealthy incident to acknowledge.
+	ErrNotUnhealthy = errors.New("no unhealthy incident")
+)
+
+// Daemon is the running daemon as seen by the control API.
+type Daemon interface {
+	Status() Status
+	// Trigger runs an alert once through the daemon's cooldown and state.
+	Trigger(ctx context.Context, alert string) ([]RunResult, error)
+	// Reload reloads the config file. A load error leaves the running
+	// config in place and is returned to the caller.
+	Reload(ctx context.Context) error
+	// Silences lists active maintenance windows and pending or active
+	// ad-hoc silences.
+	Silences() []silence.Silence
+	AddSilence(ctx context.Context, s silence.Silence) (silence.Silence, error)
+	ExpireSilence(ctx context.Context, id string) error
+	// Acknowledge acknowledges the unhealthy incident of an alert, or of
+	// one entity of a keyed alert. An empty key on a keyed alert
+	// acknowledges every unhealthy entity.
+	Acknowledge(ctx context.Context, alert string, req AckRequest) ([]Ack, error)
+}
+
+// AckRequest is the body of an acknowledge request.
+type AckRequest struct {
+	Key     string `json:"key,omitempty"`
+	By      string `json:"by,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// Ack is an acknowledgement of an unhealthy incident.
+type Ack struct {
+	Key       string    `json:"key,omitempty"`
+	By        string    `json:"by,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	At        time.Time `json:"at"`
+	EventType string    `json:"event_type,omitempty"`
+}
+
+// Status describes the running daemon.
+type Status struct {
+	StartedAt  time.Time     `json:"started_at"`
+	ReloadedAt time.Time     `json:"reloaded_at,omitzero"`
+	ConfigPath string        `json:"config_path"`
+	DryRun     bool          `json:"dry_run,omitempty"`
+	Alerts     []AlertStatus `json:"alerts"`
+}
+
+// AlertStatus describes one configured alert.
+type AlertStatus struct {
+	Name      string         `json:"name"`
+	Triggers  []string       `json:"triggers,omitempty"`
+	LastRun   time.Time      `json:"last_run,omitzero"`
+	LastEvent string         `json:"last_event,omitempty"`
+	LastError string         `json:"last_error,omitempty"`
+	Healthy   *bool          `json:"healthy,omitempty"` // nil without events.healthy
+	Flapping  bool           `json:"flapping,omitempty"`
+	Ack       *Ack           `json:"ack,omitempty"`
+	Cooldowns []Cooldown     `json:"cooldowns,omitempty"`
+	Entities  []EntityStatus `json:"entities,omitempty"` // non-idle entities of keyed alerts
+}
+
+// EntityStatus describes one events.key entity of a keyed alert.
+type EntityStatus struct {
+	Key       string     `json:"key"`
+	Healthy   *bool      `json:"healthy,omitempty"`
+	Flapping  bool       `json:"flapping,omitempty"`
+	Ack       *Ack       `json:"ack,omitempty"`
+	Cooldowns []Cooldown `json:"cooldowns,omitempty"`
+}
+
+// Cooldown is an active cooldown timer.
+type Cooldown struct {
+	EventType string    `json:"event_type"`
+	Until     time.Time `json:"until,omitzero"` // zero = until recovery
+}
+
+// RunResult summarizes one event of a triggered run.
+type RunResult struct {
+	EventType      string   `json:"event_type,omitempty"`
+	Key            string   `json:"key,omitempty"`
+	Notified       []string `json:"notified,omitempty"`
+	Failed         []string `json:"failed,omitempty"`
+	Grouped        []string `json:"grouped,omitempty"`
+	Suppressed     bool     `json:"suppressed,omitempty"`
+	Pending        bool     `json:"pending,omitempty"`
+	Flapping       bool     `json:"flapping,omitempty"`
+	Dropped        bool     `json:"dropped,omitempty"`
+	DropReason     string   `json:"drop_reason,omitempty"`
+	DryRun         bool     `json:"dry_run,omitempty"`
+	SilencedBy     string   `json:"silenced_by,omitempty"`
+	InhibitedBy    string   `json:"inhibited_by,omitempty"`
+	Acknowledged   bool     `json:"acknowledged,omitempty"`
+	AcknowledgedBy string   `json:"acknowledged_by,omitempty"`
+	Healthy        *bool    `json:"healthy,omitempty"`
+	Error          string   `json:"error,omitempty"`
+	ErrStage       string   `json:"err_stage,omitempty"`
+}
+
+// DefaultSocketPath returns the socket path used when options.control_socket
+// is unset: /run/sznuper for root, $XDG_RUNTIME_DIR/sznuper or
+// ~/.local/state/sznuper otherwise.
+func DefaultSocketPath() string {
+	if os.Getuid() == 0 {
+		return filepath.Join("/run", "sznuper", SocketName)
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "sznuper", SocketName)
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "state", "sznuper", SocketName)
+}
@@ -0,0 +1,241 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/secret"
+	"github.com/sznuper/sznuper/internal/silence"
+)
+
+type fakeDaemon struct {
+	status    Status
+	reloadErr error
+	triggered []string
+	silences  *silence.Store
+}
+
+func (f *fakeDaemon) Status() Status { return f.status }
+
+func (f *fakeDaemon) Trigger(_ context.Context, alert string) ([]RunResult, error) {
+	if alert != "disk" {
+		return nil, fmt.Errorf("%w %q", ErrUnknownAlert, alert)
+	}
+	f.triggered = append(f.triggered, alert)
+	return []RunResult{{EventType: "high_usage", Notified: []string{"telegram"}}}, nil
+}
+
+func (f *fakeDaemon) Reload(context.Context) error { return f.reloadErr }
+
+func (f *fakeDaemon) Silences() []silence.Silence { return f.silences.List(time.Now()) }
+
+func (f *fakeDaemon) AddSilence(_ context.Context, s silence.Silence) (silence.Silence, error) {
+	return f.silences.Add(s, time.Now())
+}
+
+func (f *fakeDaemon) ExpireSilence(_ context.Context, id string) error {
+	return f.silences.Expire(id, time.Now())
+}
+
+func (f *fakeDaemon) Acknowledge(_ context.Context, alert string, req AckRequest) ([]Ack, error) {
+	switch {
+	case alert != "disk":
+		return nil, fmt.Errorf("%w %q", ErrUnknownAlert, alert)
+	case req.Key != "/":
+		return nil, fmt.Errorf("%w for alert %q key %q", ErrNotUnhealthy, alert, req.Key)
+	}
+	return []Ack{{Key: req.Key, By: req.By, Comment: req.Comment, EventType: "high_usage"}}, nil
+}
+
+func serve(t *testing.T, d Daemon) *Client {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), SocketName)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := Serve(ctx, path, d, slog.New(slog.DiscardHandler)); err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(path)
+}
+
+func TestClient_Status(t *testing.T) {
+	healthy := false
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	d := &fakeDaemon{status: Status{
+		StartedAt:  started,
+		ConfigPath: "/etc/sznuper/config.yml",
+		Alerts: []AlertStatus{{
+			Name:      "disk",
+			LastEvent: "high_usage",
+			Healthy:   &healthy,
+			Cooldowns: []Cooldown{{EventType: "high_usage", Until: started.Add(time.Hour)}},
+		}},
+	}}
+
+	st, err := serve(t, d).Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.StartedAt.Equal(started) || st.ConfigPath != "/etc/sznuper/config.yml" {
+		t.Errorf("status = %+v", st)
+	}
+	if len(st.Alerts) != 1 || st.Alerts[0].Healthy == nil || *st.Alerts[0].Healthy {
+		t.Fatalf("alerts = %+v", st.Alerts)
+	}
+	if cd := st.Alerts[0].Cooldowns; len(cd) != 1 || !cd[0].Until.Equal(started.Add(time.Hour)) {
+		t.Errorf("cooldowns = %+v", cd)
+	}
+}
+
+func TestClient_Trigger(t *testing.T) {
+	d := &fakeDaemon{}
+	c := serve(t, d)
+
+	results, err := c.Trigger(context.Background(), "disk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].EventType != "high_usage" || len(d.triggered) != 1 {
+		t.Errorf("results = %+v, triggered = %v", results, d.triggered)
+	}
+
+	_, err = c.Trigger(context.Background(), "no/such alert")
+	if !errors.Is(err, ErrUnknownAlert) {
+		t.Fatalf("err = %v, want ErrUnknownAlert", err)
+	}
+	if want := `unknown alert "no/such alert"`; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
+
+func TestClient_Acknowledge(t *testing.T) {
+	c := serve(t, &fakeDaemon{})
+	ctx := context.Background()
+
+	acks, err := c.Acknowledge(ctx, "disk", AckRequest{Key: "/", By: "alice", Comment: "cleaning up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acks) != 1 || acks[0].By != "alice" || acks[0].Comment != "cleaning up" || acks[0].EventType != "high_usage" {
+		t.Errorf("acks = %+v", acks)
+	}
+
+	if _, err := c.Acknowledge(ctx, "disk", AckRequest{Key: "/home"}); !errors.Is(err, ErrNotUnhealthy) {
+		t.Errorf("err = %v, want ErrNotUnhealthy", err)
+	}
+	if _, err := c.Acknowledge(ctx, "cpu", AckRequest{}); !errors.Is(err, ErrUnknownAlert) || errors.Is(err, ErrNotUnhealthy) {
+		t.Errorf("err = %v, want ErrUnknownAlert only", err)
+	}
+}
+
+func TestClient_Reload(t *testing.T) {
+	d := &fakeDaemon{}
+	c := serve(t, d)
+	if err := c.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	d.reloadErr = errors.New("config: alerts[0]: healthcheck is required")
+	err := c.Reload(context.Background())
+	if err == nil || err.Error() != d.reloadErr.Error() {
+		t.Errorf("err = %v, want %v", err, d.reloadErr)
+	}
+
+	secret.Register("ctl-t0ken")
+	d.reloadErr = errors.New(`config: channel "slack": invalid url "slack://ctl-t0ken@C0123"`)
+	err = c.Reload(context.Background())
+	if err == nil || strings.Contains(err.Error(), "ctl-t0ken") || !strings.Contains(err.Error(), secret.Redacted) {
+		t.Errorf("err = %v, want the token redacted", err)
+	}
+}
+
+func TestClient_Silences(t *testing.T) {
+	d := &fakeDaemon{silences: silence.NewStore("")}
+	c := serve(t, d)
+	ctx := context.Background()
+
+	if _, err := c.AddSilence(ctx, silence.Silence{End: time.Now().Add(time.Hour)}); err == nil {
+		t.Fatal("expected error for a silence without matchers")
+	}
+
+	created, err := c.AddSilence(ctx, silence.Silence{
+		Match:   silence.Matcher{Alert: "disk_*", Fields: map[string]string{"mount": "/backup"}},
+		End:     time.Now().Add(time.Hour),
+		Comment: "backup migration",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || created.Start.IsZero() {
+		t.Errorf("created = %+v, want ID and start", created)
+	}
+
+	list, err := c.Silences(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != created.ID || list[0].Match.Fields["mount"] != "/backup" {
+		t.Fatalf("list = %+v", list)
+	}
+
+	if err := c.ExpireSilence(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ExpireSilence(ctx, created.ID); !errors.Is(err, silence.ErrNotFound) {
+		t.Errorf("second expire err = %v, want ErrNotFound", err)
+	}
+	if list, _ := c.Silences(ctx); len(list) != 0 {
+		t.Errorf("list after expire = %+v", list)
+	}
+}
+
+func TestClient_NoDaemon(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SocketName)
+	_, err := NewClient(path).Status(context.Background())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.HasPrefix(err.Error(), "cannot reach daemon at "+path) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestListen_StaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", SocketName)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// A socket file nobody listens on, as left behind by a crash.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatalf("stale socket not replaced: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket mode = %v, want 0600", perm)
+	}
+
+	if _, err := Listen(path); err == nil {
+		t.Error("expected error for a socket in use")
+	}
+}
@@ -0,0 +1,163 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/secret"
+	"github.com/sznuper/sznuper/internal/silence"
+)
+
+// Listen binds the control socket at path. A leftover socket from a daemon
+// that did not shut down cleanly is replaced; one that still accepts
+// connections is an error. The socket is only accessible to its owner.
+func Listen(path string) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if c, err := net.Dial("unix", path); err == nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("control socket %s is in use by another daemon", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale control socket: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating control socket directory: %w", err)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// Serve listens on the socket at path and serves d until ctx is done. The
+// socket is bound before Serve returns so errors surface at startup.
+func Serve(ctx context.Context, path string, d Daemon, logger *slog.Logger) error {
+	ln, err := Listen(path)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           Handler(d),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("control server failed", "error", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	logger.Info("control socket listening", "path", path)
+	return nil
+}
+
+// Handler returns the HTTP handler for the control API.
+func Handler(d Daemon) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, d.Status())
+	})
+	mux.HandleFunc("POST /v1/alerts/{name}/trigger", func(w http.ResponseWriter, r *http.Request) {
+		results, err := d.Trigger(r.Context(), r.PathValue("name"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, results)
+	})
+	mux.HandleFunc("POST /v1/alerts/{name}/ack", func(w http.ResponseWriter, r *http.Request) {
+		var req AckRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, errorBody{Error: "invalid request: " + err.Error()})
+				return
+			}
+		}
+		acks, err := d.Acknowledge(r.Context(), r.PathValue("name"), req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, acks)
+	})
+	mux.HandleFunc("GET /v1/silences", func(w http.ResponseWriter, r *http.Request) {
+		list := d.Silences()
+		if list == nil {
+			list = []silence.Silence{}
+		}
+		writeJSON(w, http.StatusOK, list)
+	})
+	mux.HandleFunc("POST /v1/silences", func(w http.ResponseWriter, r *http.Request) {
+		var s silence.Silence
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			writeError(w, fmt.Errorf("%w: %v", silence.ErrInvalid, err))
+			return
+		}
+		created, err := d.AddSilence(r.Context(), s)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+	})
+	mux.HandleFunc("DELETE /v1/silences/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := d.ExpireSilence(r.Context(), r.PathValue("id")); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /v1/reload", func(w http.ResponseWriter, r *http.Request) {
+		if err := d.Reload(r.Context()); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// writeError replies with err, secrets redacted, and a status code for its
+// kind.
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrUnknownAlert), errors.Is(err, silence.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, silence.ErrInvalid):
+		code = http.StatusBadRequest
+	case errors.Is(err, ErrNotUnhealthy):
+		code = http.StatusConflict
+	}
+	writeJSON(w, code, errorBody{Error: secret.Redact(err.Error())})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -1,12 +1,17 @@
 package cooldown
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 // Infinite is a sentinel duration meaning "suppress until cooldowns are reset".
 const Infinite time.Duration = -1
 
 // State tracks per-event-type cooldown for one alert.
+// It is safe for concurrent use by multiple triggers of the same alert.
 type State struct {
+	mu     sync.Mutex
 	timers map[string]*timer
 	now    func() time.Time
 }
@@ -32,6 +37,9 @@ func (s *State) Check(eventType string, duration time.Duration) bool {
 		return true
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	now := s.now()
 	t, ok := s.timers[eventType]
 	if !ok {
@@ -49,11 +57,52 @@ func (s *State) Check(eventType string, duration time.Duration) bool {
 // ResetAll clears all cooldown timers.
 // Called on recovery (unhealthy→healthy transition).
 func (s *State) ResetAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	for _, t := range s.timers {
 		t.reset()
 	}
 }
 
+// Timer is a serializable snapshot of a single event type's cooldown timer.
+type Timer struct {
+	Duration time.Duration `json:"duration"`
+	Expiry   time.Time     `json:"expiry,omitzero"` // zero for Infinite
+}
+
+// Snapshot returns the currently running timers keyed by event type.
+// Expired timers are omitted since they no longer suppress anything.
+func (s *State) Snapshot() map[string]Timer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	out := make(map[string]Timer)
+	for typ, t := range s.timers {
+		if !t.active(now) {
+			continue
+		}
+		out[typ] = Timer{Duration: t.duration, Expiry: t.expiry}
+	}
+	return out
+}
+
+// Restore replaces all timers with the given snapshot.
+// Timers that have expired in the meantime are discarded.
+func (s *State) Restore(timers map[string]Timer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.timers = make(map[string]*timer, len(timers))
+	for typ, snap := range timers {
+		t := &timer{started: true, duration: snap.Duration, expiry: snap.Expiry}
+		if t.active(now) {
+			s.timers[typ] = t
+		}
+	}
+}
+
 type timer struct {
 	started  bool
 	duration time.Duration
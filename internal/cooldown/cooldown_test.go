@@ -119,3 +119,50 @@ func TestCheck_SameDurationForAllTypes(t *testing.T) {
 		t.Error("want login notify after cooldown")
 	}
 }
+
+func TestSnapshotRestore_RoundTrip(t *testing.T) {
+	s, now := newState()
+	s.Check("failure", 5*time.Minute)
+	s.Check("login", Infinite)
+
+	snap := s.Snapshot()
+	if len(snap) != 2 {
+		t.Fatalf("snapshot = %v, want 2 timers", snap)
+	}
+
+	restored := New(func() time.Time { return *now })
+	restored.Restore(snap)
+
+	*now = t0.Add(2 * time.Minute)
+	if restored.Check("failure", 5*time.Minute) {
+		t.Error("want failure suppressed after restore")
+	}
+	if restored.Check("login", Infinite) {
+		t.Error("want login suppressed after restore (infinite)")
+	}
+
+	*now = t0.Add(6 * time.Minute)
+	if !restored.Check("failure", 5*time.Minute) {
+		t.Error("want failure notify once restored timer expires")
+	}
+}
+
+func TestSnapshot_OmitsExpiredTimers(t *testing.T) {
+	s, now := newState()
+	s.Check("failure", 5*time.Minute)
+	*now = t0.Add(10 * time.Minute)
+	if snap := s.Snapshot(); len(snap) != 0 {
+		t.Errorf("snapshot = %v, want empty", snap)
+	}
+}
+
+func TestRestore_DiscardsExpiredTimers(t *testing.T) {
+	s, now := newState()
+	*now = t0.Add(time.Hour)
+	s.Restore(map[string]Timer{
+		"failure": {Duration: 5 * time.Minute, Expiry: t0.Add(5 * time.Minute)},
+	})
+	if !s.Check("failure", 5*time.Minute) {
+		t.Error("want notify=true (restored timer already expired)")
+	}
+}
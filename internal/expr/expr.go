@@ -0,0 +1,747 @@
+// Package expr implements rule expressions: small boolean expressions over
+// an event's fields, the alert's args and the config globals, used by
+// drop_if, keep_if and override conditions.
+//
+//	event.type == "login" and event.ip in ["10.0.0.5", "10.0.0.6"]
+//	event.usage >= 90 or not exists(event.mount)
+//	event.user matches "^svc-" and in_cidr(event.ip, "10.0.0.0/8")
+//
+// Names are event.<field>, args.<name> and globals.<name> (dotted for nested
+// globals); a missing name is null. Comparisons are numeric when both sides
+// are numbers or numeric strings, so event.usage > 90 works on the string
+// fields of "--- event" output. Ordering comparisons on non-numeric values
+// fail at evaluation time.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Env holds the values an expression is evaluated against.
+type Env struct {
+	Event   map[string]any
+	Args    map[string]any
+	Globals map[string]any
+}
+
+// Expr is a compiled expression. It is safe for concurrent use.
+type Expr struct {
+	src  string
+	root node
+	refs []string
+}
+
+// Compile parses and checks an expression. Names must start with event.,
+// args. or globals., regular expressions must compile, and the expression
+// must be boolean.
+func Compile(src string) (*Expr, error) {
+	p := &parser{lex: lexer{src: src}}
+	p.next()
+	n, err := p.parseOr()
+	if err == nil {
+		err = p.err
+	}
+	if err == nil && p.tok.kind != tokEOF {
+		err = p.errorf("unexpected %s", p.tok)
+	}
+	if err == nil && !n.boolean() {
+		err = errors.New("expression must be a condition, e.g. a comparison")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+	return &Expr{src: src, root: n, refs: p.refs}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string { return e.src }
+
+// Refs returns the names the expression refers to, e.g. "event.ip", in
+// order of appearance.
+func (e *Expr) Refs() []string { return slices.Clone(e.refs) }
+
+// Eval evaluates the expression against env.
+func (e *Expr) Eval(env Env) (bool, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return false, fmt.Errorf("evaluating %q: %w", e.src, err)
+	}
+	return v.(bool), nil
+}
+
+// ---- lexer ----
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp // == != < <= > >= ( ) [ ] ,
+)
+
+type token struct {
+	kind tokKind
+	text string // identifier, operator, or the unquoted string
+	num  float64
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return "'" + t.text + "'"
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && strings.ContainsRune(" \t\r\n", rune(l.src[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case isIdentStart(c):
+		for l.pos < len(l.src) && (isIdentStart(l.src[l.pos]) || isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}, nil
+	case isDigit(c) || (c == '-' || c == '.') && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1]):
+		l.pos++
+		for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		text := l.src[start:l.pos]
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid number %q at offset %d", text, start)
+		}
+		return token{kind: tokNumber, text: text, num: n, pos: start}, nil
+	case c == '"' || c == '\'':
+		var b strings.Builder
+		l.pos++
+		for l.pos < len(l.src) {
+			ch := l.src[l.pos]
+			switch {
+			case ch == c:
+				l.pos++
+				return token{kind: tokString, text: b.String(), pos: start}, nil
+			case ch == '\\' && l.pos+1 < len(l.src):
+				// Only the quote and the backslash are escaped, so regular
+				// expressions keep their own escapes.
+				if esc := l.src[l.pos+1]; esc == c || esc == '\\' {
+					b.WriteByte(esc)
+					l.pos += 2
+					continue
+				}
+			}
+			b.WriteByte(ch)
+			l.pos++
+		}
+		return token{}, fmt.Errorf("unterminated string at offset %d", start)
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "(", ")", "[", "]", ","} {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, pos: start}, nil
+		}
+	}
+	return token{}, fmt.Errorf("unexpected character %q at offset %d", c, start)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// ---- parser ----
+
+type parser struct {
+	lex  lexer
+	tok  token
+	err  error
+	refs []string
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lex.next()
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf(format+" at offset %d", append(args, p.tok.pos)...)
+}
+
+func (p *parser) isOp(op string) bool { return p.err == nil && p.tok.kind == tokOp && p.tok.text == op }
+
+func (p *parser) isKeyword(kw string) bool {
+	return p.err == nil && p.tok.kind == tokIdent && p.tok.text == kw
+}
+
+func (p *parser) expectOp(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expected '%s', got %s", op, p.tok)
+	}
+	p.next()
+	return p.err
+}
+
+// or := and ("or" and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.isKeyword("or") {
+		p.next()
+		var right node
+		if right, err = p.parseAnd(); err == nil {
+			left, err = newLogic("or", left, right)
+		}
+	}
+	return left, err
+}
+
+// and := not ("and" not)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	for err == nil && p.isKeyword("and") {
+		p.next()
+		var right node
+		if right, err = p.parseNot(); err == nil {
+			left, err = newLogic("and", left, right)
+		}
+	}
+	return left, err
+}
+
+// not := "not" not | cmp
+func (p *parser) parseNot() (node, error) {
+	if !p.isKeyword("not") {
+		return p.parseCmp()
+	}
+	p.next()
+	n, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if !n.boolean() {
+		return nil, errors.New("'not' needs a condition")
+	}
+	return notNode{n}, nil
+}
+
+// cmp := operand (cmpop operand | "matches" string | ["not"] "in" operand)?
+func (p *parser) parseCmp() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case p.tok.kind == tokOp && slices.Contains([]string{"==", "!=", "<", "<=", ">", ">="}, p.tok.text):
+		op := p.tok.text
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if op != "==" && op != "!=" {
+			for _, n := range []node{left, right} {
+				if !n.numeric() {
+					return nil, fmt.Errorf("'%s' needs numbers, got %s", op, n)
+				}
+			}
+		}
+		return cmpNode{op: op, left: left, right: right}, nil
+	case p.isKeyword("matches"):
+		p.next()
+		if p.tok.kind != tokString {
+			return nil, p.errorf("'matches' needs a string pattern, got %s", p.tok)
+		}
+		re, err := regexp.Compile(p.tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p.tok.text, err)
+		}
+		p.next()
+		return matchNode{left: left, re: re}, p.err
+	case p.isKeyword("in"), p.isKeyword("not"):
+		negate := p.isKeyword("not")
+		if negate {
+			p.next()
+			if !p.isKeyword("in") {
+				return nil, p.errorf("expected 'in' after 'not', got %s", p.tok)
+			}
+		}
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if l, ok := right.(literal); ok {
+			if _, isList := l.v.([]any); !isList {
+				return nil, fmt.Errorf("'in' needs a list, got %s", right)
+			}
+		}
+		return inNode{left: left, right: right, negate: negate}, nil
+	}
+	return left, nil
+}
+
+// operand := literal | name | call | list | "(" or ")"
+func (p *parser) parseOperand() (node, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		p.next()
+		return literal{tok.num}, p.err
+	case tokString:
+		p.next()
+		return literal{tok.text}, p.err
+	case tokOp:
+		switch tok.text {
+		case "(":
+			p.next()
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return parenNode{n}, p.expectOp(")")
+		case "[":
+			return p.parseList()
+		}
+	case tokIdent:
+		p.next()
+		switch tok.text {
+		case "true":
+			return literal{true}, p.err
+		case "false":
+			return literal{false}, p.err
+		case "null":
+			return literal{nil}, p.err
+		case "and", "or", "not", "in", "matches":
+			return nil, fmt.Errorf("unexpected '%s' at offset %d", tok.text, tok.pos)
+		}
+		if p.isOp("(") {
+			return p.parseCall(tok)
+		}
+		path := strings.Split(tok.text, ".")
+		switch {
+		case !slices.Contains([]string{"event", "args", "globals"}, path[0]):
+			return nil, fmt.Errorf("unknown name %q at offset %d: names start with event., args. or globals.", tok.text, tok.pos)
+		case len(path) < 2 || slices.Contains(path[1:], ""):
+			return nil, fmt.Errorf("invalid name %q at offset %d", tok.text, tok.pos)
+		}
+		p.refs = append(p.refs, tok.text)
+		return nameNode{path: path}, nil
+	}
+	return nil, p.errorf("unexpected %s", tok)
+}
+
+func (p *parser) parseList() (node, error) {
+	p.next() // [
+	var items []any
+	for !p.isOp("]") {
+		if len(items) > 0 {
+			if err := p.expectOp(","); err != nil {
+				return nil, err
+			}
+		}
+		n, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		l, ok := n.(literal)
+		if !ok {
+			return nil, fmt.Errorf("list items must be literals, got %s", n)
+		}
+		items = append(items, l.v)
+	}
+	if items == nil {
+		items = []any{}
+	}
+	return literal{items}, p.expectOp("]")
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := funcs[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at offset %d", name.text, name.pos)
+	}
+	p.next() // (
+	var args []node
+	for !p.isOp(")") {
+		if len(args) > 0 {
+			if err := p.expectOp(","); err != nil {
+				return nil, err
+			}
+		}
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, n)
+	}
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+	if len(args) != fn.arity {
+		return nil, fmt.Errorf("%s() takes %d arguments, got %d", name.text, fn.arity, len(args))
+	}
+	if fn.check != nil {
+		if err := fn.check(args); err != nil {
+			return nil, fmt.Errorf("%s(): %w", name.text, err)
+		}
+	}
+	return callNode{name: name.text, fn: fn, args: args}, nil
+}
+
+// ---- nodes ----
+
+type node interface {
+	eval(env Env) (any, error)
+	boolean() bool // always evaluates to a bool
+	numeric() bool // may evaluate to a number
+	String() string
+}
+
+type literal struct{ v any }
+
+func (l literal) eval(Env) (any, error) { return l.v, nil }
+func (l literal) boolean() bool         { _, ok := l.v.(bool); return ok }
+func (l literal) numeric() bool         { _, ok := toNumber(l.v); return ok }
+func (l literal) String() string {
+	switch v := l.v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case []any:
+		return "a list"
+	}
+	return fmt.Sprint(l.v)
+}
+
+type nameNode struct{ path []string }
+
+func (n nameNode) eval(env Env) (any, error) {
+	var m map[string]any
+	switch n.path[0] {
+	case "event":
+		m = env.Event
+	case "args":
+		m = env.Args
+	case "globals":
+		m = env.Globals
+	}
+	var v any = m
+	for _, key := range n.path[1:] {
+		mm, ok := v.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		v = mm[key]
+	}
+	return v, nil
+}
+
+func (n nameNode) boolean() bool  { return false }
+func (n nameNode) numeric() bool  { return true }
+func (n nameNode) String() string { return strings.Join(n.path, ".") }
+
+type parenNode struct{ n node }
+
+func (p parenNode) eval(env Env) (any, error) { return p.n.eval(env) }
+func (p parenNode) boolean() bool             { return p.n.boolean() }
+func (p parenNode) numeric() bool             { return p.n.numeric() }
+func (p parenNode) String() string            { return "(" + p.n.String() + ")" }
+
+type notNode struct{ n node }
+
+func (n notNode) eval(env Env) (any, error) {
+	v, err := n.n.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !v.(bool), nil
+}
+
+func (n notNode) boolean() bool  { return true }
+func (n notNode) numeric() bool  { return false }
+func (n notNode) String() string { return "not " + n.n.String() }
+
+type logicNode struct {
+	op          string
+	left, right node
+}
+
+func newLogic(op string, left, right node) (node, error) {
+	if !left.boolean() || !right.boolean() {
+		return nil, fmt.Errorf("'%s' needs conditions on both sides", op)
+	}
+	return logicNode{op: op, left: left, right: right}, nil
+}
+
+func (n logicNode) eval(env Env) (any, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if l.(bool) == (n.op == "or") {
+		return l, nil
+	}
+	return n.right.eval(env)
+}
+
+func (n logicNode) boolean() bool  { return true }
+func (n logicNode) numeric() bool  { return false }
+func (n logicNode) String() string { return n.left.String() + " " + n.op + " " + n.right.String() }
+
+type cmpNode struct {
+	op          string
+	left, right node
+}
+
+func (n cmpNode) eval(env Env) (any, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	}
+	a, ok := toNumber(l)
+	if !ok {
+		return nil, fmt.Errorf("%s is %s, not a number", n.left, describe(l))
+	}
+	b, ok := toNumber(r)
+	if !ok {
+		return nil, fmt.Errorf("%s is %s, not a number", n.right, describe(r))
+	}
+	switch n.op {
+	case "<":
+		return a < b, nil
+	case "<=":
+		return a <= b, nil
+	case ">":
+		return a > b, nil
+	default:
+		return a >= b, nil
+	}
+}
+
+func (n cmpNode) boolean() bool  { return true }
+func (n cmpNode) numeric() bool  { return false }
+func (n cmpNode) String() string { return n.left.String() + " " + n.op + " " + n.right.String() }
+
+type matchNode struct {
+	left node
+	re   *regexp.Regexp
+}
+
+func (n matchNode) eval(env Env) (any, error) {
+	v, err := n.left.eval(env)
+	if err != nil || v == nil {
+		return false, err
+	}
+	return n.re.MatchString(toString(v)), nil
+}
+
+func (n matchNode) boolean() bool { return true }
+func (n matchNode) numeric() bool { return false }
+func (n matchNode) String() string {
+	return n.left.String() + " matches " + strconv.Quote(n.re.String())
+}
+
+type inNode struct {
+	left, right node
+	negate      bool
+}
+
+func (n inNode) eval(env Env) (any, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	switch list := r.(type) {
+	case []any:
+		found = slices.ContainsFunc(list, func(item any) bool { return equal(l, item) })
+	case []string:
+		found = slices.ContainsFunc(list, func(item string) bool { return equal(l, item) })
+	case nil:
+	default:
+		return nil, fmt.Errorf("%s is %s, not a list", n.right, describe(r))
+	}
+	return found != n.negate, nil
+}
+
+func (n inNode) boolean() bool { return true }
+func (n inNode) numeric() bool { return false }
+func (n inNode) String() string {
+	op := " in "
+	if n.negate {
+		op = " not in "
+	}
+	return n.left.String() + op + n.right.String()
+}
+
+type callNode struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n callNode) eval(env Env) (any, error) {
+	args := make([]any, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return n.fn.call(args)
+}
+
+func (n callNode) boolean() bool  { return true }
+func (n callNode) numeric() bool  { return false }
+func (n callNode) String() string { return n.name + "()" }
+
+// ---- functions ----
+
+type function struct {
+	arity int
+	check func(args []node) error // compile-time checks of literal arguments
+	call  func(args []any) (any, error)
+}
+
+var funcs = map[string]function{
+	"exists": {arity: 1, call: func(a []any) (any, error) { return a[0] != nil, nil }},
+	"contains": {arity: 2, call: func(a []any) (any, error) {
+		return a[0] != nil && strings.Contains(toString(a[0]), toString(a[1])), nil
+	}},
+	"starts_with": {arity: 2, call: func(a []any) (any, error) {
+		return a[0] != nil && strings.HasPrefix(toString(a[0]), toString(a[1])), nil
+	}},
+	"ends_with": {arity: 2, call: func(a []any) (any, error) {
+		return a[0] != nil && strings.HasSuffix(toString(a[0]), toString(a[1])), nil
+	}},
+	"in_cidr": {
+		arity: 2,
+		check: func(args []node) error {
+			if l, ok := args[1].(literal); ok {
+				if _, err := netip.ParsePrefix(toString(l.v)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		call: func(a []any) (any, error) {
+			prefix, err := netip.ParsePrefix(toString(a[1]))
+			if err != nil {
+				return nil, err
+			}
+			addr, err := netip.ParseAddr(toString(a[0]))
+			if err != nil {
+				return false, nil // not an IP address
+			}
+			return prefix.Contains(addr.Unmap()), nil
+		},
+	},
+}
+
+// ---- values ----
+
+// equal compares numerically when both values are numbers or numeric
+// strings, else as strings. null only equals null.
+func equal(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			return x == y
+		}
+	}
+	return toString(a) == toString(b)
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+func toString(v any) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func describe(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "missing"
+	case string:
+		return strconv.Quote(v)
+	}
+	return fmt.Sprintf("%v", v)
+}
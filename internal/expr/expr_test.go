@@ -0,0 +1,123 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	env := Env{
+		Event: map[string]any{
+			"type":  "login",
+			"ip":    "10.0.0.5",
+			"user":  "svc-backup",
+			"usage": "84.5",
+			"units": []any{"nginx", "redis"},
+		},
+		Args:    map[string]any{"threshold": uint64(80), "bastions": []any{"10.0.0.5", "10.0.0.6"}},
+		Globals: map[string]any{"site": map[string]any{"name": "fra1"}},
+	}
+	cases := []struct {
+		src  string
+		want bool
+	}{
+		{`event.type == "login"`, true},
+		{`event.type != 'login'`, false},
+		{`event.usage > 80`, true},
+		{`event.usage >= args.threshold and event.usage < 85`, true},
+		{`event.usage == 84.50`, true},
+		{`event.ip in ["10.0.0.5", "10.0.0.6"]`, true},
+		{`event.ip in args.bastions`, true},
+		{`event.ip not in args.bastions`, false},
+		{`"redis" in event.units`, true},
+		{`event.user matches "^svc-"`, true},
+		{`event.user matches "^root$" or event.type == "login"`, true},
+		{`not (event.type == "login" and in_cidr(event.ip, "10.0.0.0/8"))`, false},
+		{`in_cidr(event.user, "10.0.0.0/8")`, false},
+		{`exists(event.mount)`, false},
+		{`event.mount == null`, true},
+		{`event.mount == ""`, false},
+		{`starts_with(event.user, "svc") and ends_with(event.user, "backup") and contains(event.user, "-")`, true},
+		{`globals.site.name == "fra1"`, true},
+		{`globals.site.name.x == null`, true},
+		{`true`, true},
+	}
+	for _, c := range cases {
+		e, err := Compile(c.src)
+		if err != nil {
+			t.Errorf("Compile(%s): %v", c.src, err)
+			continue
+		}
+		got, err := e.Eval(env)
+		if err != nil {
+			t.Errorf("Eval(%s): %v", c.src, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("Eval(%s) = %v, want %v", c.src, got, c.want)
+		}
+	}
+}
+
+func TestEval_Errors(t *testing.T) {
+	env := Env{Event: map[string]any{"type": "login", "user": "root"}}
+	for _, src := range []string{
+		`event.user > 5`,
+		`event.missing < 5`,
+		`event.type in event.user`,
+	} {
+		e, err := Compile(src)
+		if err != nil {
+			t.Fatalf("Compile(%s): %v", src, err)
+		}
+		if _, err := e.Eval(env); err == nil {
+			t.Errorf("Eval(%s) = nil error", src)
+		}
+	}
+	// Short-circuiting skips the failing side.
+	e, _ := Compile(`event.type == "login" or event.user > 5`)
+	if ok, err := e.Eval(env); err != nil || !ok {
+		t.Errorf("short-circuit or = %v, %v", ok, err)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	cases := map[string]string{
+		``:                              "unexpected end of expression",
+		`event.type`:                    "must be a condition",
+		`type == "login"`:               "unknown name",
+		`event == "x"`:                  "invalid name",
+		`event.type == "login" and 5`:   "needs conditions",
+		`event.usage > "high"`:          "needs numbers",
+		`event.user matches "("`:        "invalid pattern",
+		`event.user matches event.x`:    "needs a string pattern",
+		`event.ip in "10.0.0.5"`:        "needs a list",
+		`in_cidr(event.ip, "10.0.0.0")`: "in_cidr()",
+		`exists(event.a, event.b)`:      "takes 1 arguments",
+		`lower(event.user) == "x"`:      "unknown function",
+		`event.type == "login`:          "unterminated string",
+		`event.type = "login"`:          "unexpected character",
+		`event.type == "a" "b"`:         "unexpected",
+		`event.ip in [event.x]`:         "list items must be literals",
+	}
+	for src, want := range cases {
+		_, err := Compile(src)
+		if err == nil {
+			t.Errorf("Compile(%s) = nil error, want %q", src, want)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Compile(%s) = %v, want %q", src, err, want)
+		}
+	}
+}
+
+func TestRefs(t *testing.T) {
+	e, err := Compile(`event.ip in args.bastions or globals.env == "dev"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(e.Refs(), ","); got != "event.ip,args.bastions,globals.env" {
+		t.Errorf("Refs() = %s", got)
+	}
+}
@@ -0,0 +1,173 @@
+// Package group batches notifications: events are collected per group key
+// and flushed together after a wait, at an interval, or on a cron schedule.
+package group
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+// Defaults for groups that set neither wait/interval nor cron.
+const (
+	DefaultWait     = 30 * time.Second
+	DefaultInterval = 5 * time.Minute
+)
+
+// Policy decides when a group is flushed.
+type Policy struct {
+	// Wait delays the first flush of a new group, Interval the following
+	// ones. Both are ignored when Schedule is set.
+	Wait     time.Duration
+	Interval time.Duration
+	// Schedule flushes the group at each activation (digest mode).
+	Schedule cron.Schedule
+}
+
+// NewPolicy compiles a group config into a Policy. Config loading has
+// checked the durations and the cron expression.
+func NewPolicy(cfg config.Group) Policy {
+	if cfg.Cron != "" {
+		sched, _ := config.CronParser.Parse(cfg.Cron)
+		return Policy{Schedule: sched}
+	}
+	p := Policy{Wait: DefaultWait, Interval: DefaultInterval}
+	if cfg.Wait != "" {
+		p.Wait, _ = time.ParseDuration(cfg.Wait)
+	}
+	if cfg.Interval != "" {
+		p.Interval, _ = time.ParseDuration(cfg.Interval)
+	}
+	return p
+}
+
+// first returns when a new group created at now is flushed.
+func (p Policy) first(now time.Time) time.Time {
+	if p.Schedule != nil {
+		return p.Schedule.Next(now)
+	}
+	return now.Add(p.Wait)
+}
+
+// next returns when a group flushed at now is flushed again.
+func (p Policy) next(now time.Time) time.Time {
+	if p.Schedule != nil {
+		return p.Schedule.Next(now)
+	}
+	return now.Add(p.Interval)
+}
+
+// Item is one event collected into a group.
+type Item struct {
+	Time      time.Time
+	Alert     string
+	EventType string
+	Event     map[string]any // event template values
+	Message   string         // the event's own rendered notification
+}
+
+// FlushFunc sends a group's collected items. It is called without locks
+// held and may block.
+type FlushFunc func(ctx context.Context, items []Item)
+
+// Grouper collects items per group key until their policy flushes them.
+// The zero value is not usable; use New.
+type Grouper struct {
+	mu     sync.Mutex
+	groups map[string]*group
+	closed bool
+	wg     sync.WaitGroup // in-flight flushes
+}
+
+type group struct {
+	items  []Item
+	policy Policy
+	flush  FlushFunc
+	timer  *time.Timer
+}
+
+// New creates an empty Grouper.
+func New() *Grouper {
+	return &Grouper{groups: make(map[string]*group)}
+}
+
+// Add queues item under key. A new group is flushed per policy.first; after
+// a flush the group lives on and is flushed again per policy.next, or
+// removed if nothing arrived in the meantime. The policy and flush of the
+// latest Add are used, so a config reload applies to pending groups.
+// It reports false after Close, leaving the caller to send the item itself.
+func (g *Grouper) Add(key string, policy Policy, flush FlushFunc, item Item) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return false
+	}
+	gr, ok := g.groups[key]
+	if !ok {
+		gr = &group{}
+		g.groups[key] = gr
+		gr.timer = time.AfterFunc(time.Until(policy.first(item.Time)), func() { g.fire(key, gr) })
+	}
+	gr.items = append(gr.items, item)
+	gr.policy, gr.flush = policy, flush
+	return true
+}
+
+// fire flushes a group when its timer expires and schedules the next flush.
+func (g *Grouper) fire(key string, gr *group) {
+	g.mu.Lock()
+	if g.closed || g.groups[key] != gr {
+		g.mu.Unlock()
+		return
+	}
+	items, flush := gr.items, gr.flush
+	gr.items = nil
+	if len(items) == 0 {
+		delete(g.groups, key)
+	} else {
+		gr.timer = time.AfterFunc(time.Until(gr.policy.next(time.Now())), func() { g.fire(key, gr) })
+		g.wg.Add(1)
+	}
+	g.mu.Unlock()
+
+	if len(items) > 0 {
+		defer g.wg.Done()
+		flush(context.Background(), items)
+	}
+}
+
+// Pending returns the number of items waiting to be flushed.
+func (g *Grouper) Pending() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n := 0
+	for _, gr := range g.groups {
+		n += len(gr.items)
+	}
+	return n
+}
+
+// Close stops all timers, flushes every pending group with ctx and waits
+// for in-flight flushes. Later Adds are refused.
+func (g *Grouper) Close(ctx context.Context) {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return
+	}
+	g.closed = true
+	groups := g.groups
+	g.groups = nil
+	g.mu.Unlock()
+
+	for _, gr := range groups {
+		gr.timer.Stop()
+		if len(gr.items) > 0 {
+			gr.flush(ctx, gr.items)
+		}
+	}
+	g.wg.Wait()
+}
@@ -0,0 +1,132 @@
+package group
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+func TestNewPolicy(t *testing.T) {
+	p := NewPolicy(config.Group{})
+	if p.Wait != DefaultWait || p.Interval != DefaultInterval || p.Schedule != nil {
+		t.Errorf("defaults = %+v", p)
+	}
+
+	p = NewPolicy(config.Group{Wait: "0s", Interval: "1m"})
+	if p.Wait != 0 || p.Interval != time.Minute {
+		t.Errorf("wait/interval = %+v", p)
+	}
+
+	p = NewPolicy(config.Group{Cron: "0 8 * * *"})
+	if p.Schedule == nil {
+		t.Fatalf("cron = %+v", p)
+	}
+	from := time.Date(2026, 3, 1, 9, 0, 0, 0, time.Local)
+	if got, want := p.first(from), time.Date(2026, 3, 2, 8, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("first flush = %v, want %v", got, want)
+	}
+
+}
+
+// recorder collects flushed batches.
+type recorder struct {
+	batches chan []Item
+}
+
+func newRecorder() *recorder {
+	return &recorder{batches: make(chan []Item, 16)}
+}
+
+func (r *recorder) flush(_ context.Context, items []Item) {
+	r.batches <- items
+}
+
+func (r *recorder) wait(t *testing.T) []Item {
+	t.Helper()
+	select {
+	case batch := <-r.batches:
+		return batch
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for flush")
+		return nil
+	}
+}
+
+func item(typ string) Item {
+	return Item{Time: time.Now(), EventType: typ}
+}
+
+func TestGrouper_WaitAndInterval(t *testing.T) {
+	g := New()
+	rec := newRecorder()
+	p := Policy{Wait: 50 * time.Millisecond, Interval: 100 * time.Millisecond}
+
+	g.Add("a", p, rec.flush, item("failure"))
+	g.Add("a", p, rec.flush, item("failure"))
+	g.Add("b", p, rec.flush, item("login"))
+
+	got := map[int]int{}
+	for range 2 {
+		got[len(rec.wait(t))]++
+	}
+	if got[2] != 1 || got[1] != 1 {
+		t.Fatalf("first flushes = %v, want one batch of 2 and one of 1", got)
+	}
+
+	// Events after a flush wait for the interval and come as one batch.
+	g.Add("a", p, rec.flush, item("failure"))
+	g.Add("a", p, rec.flush, item("failure"))
+	g.Add("a", p, rec.flush, item("failure"))
+	if batch := rec.wait(t); len(batch) != 3 {
+		t.Errorf("interval batch = %d items, want 3", len(batch))
+	}
+	if n := g.Pending(); n != 0 {
+		t.Errorf("pending = %d, want 0", n)
+	}
+	g.Close(context.Background())
+}
+
+// everyTick fires a fixed duration after each call, standing in for a cron
+// schedule.
+type everyTick time.Duration
+
+func (d everyTick) Next(t time.Time) time.Time { return t.Add(time.Duration(d)) }
+
+func TestGrouper_Schedule(t *testing.T) {
+	g := New()
+	rec := newRecorder()
+	p := Policy{Schedule: everyTick(50 * time.Millisecond)}
+
+	for range 4 {
+		g.Add("digest", p, rec.flush, item("ok"))
+	}
+	if batch := rec.wait(t); len(batch) != 4 {
+		t.Errorf("digest = %d items, want 4", len(batch))
+	}
+	g.Close(context.Background())
+}
+
+func TestGrouper_Close(t *testing.T) {
+	g := New()
+	rec := newRecorder()
+	p := Policy{Wait: time.Hour, Interval: time.Hour}
+
+	g.Add("a", p, rec.flush, item("failure"))
+	g.Add("a", p, rec.flush, item("failure"))
+	if n := g.Pending(); n != 2 {
+		t.Fatalf("pending = %d, want 2", n)
+	}
+
+	g.Close(context.Background())
+	if len(rec.batches) != 1 {
+		t.Fatalf("batches after close = %d, want 1", len(rec.batches))
+	}
+	if batch := <-rec.batches; len(batch) != 2 {
+		t.Errorf("batch = %d items, want 2", len(batch))
+	}
+	if g.Add("a", p, rec.flush, item("failure")) {
+		t.Error("Add after Close = true, want false")
+	}
+}
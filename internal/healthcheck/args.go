@@ -0,0 +1,103 @@
+package healthcheck
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Helpers for reading alert args in builtin healthchecks. Args arrive as
+// decoded YAML, so scalars may be strings, ints, floats, or bools.
+
+// argString returns args[key] formatted as a string, or def if unset.
+func argString(args map[string]any, key, def string) string {
+	v, ok := args[key]
+	if !ok || v == nil {
+		return def
+	}
+	return formatArg(v)
+}
+
+// argBool returns args[key] as a bool, or def if unset.
+func argBool(args map[string]any, key string, def bool) (bool, error) {
+	v, ok := args[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	b, err := strconv.ParseBool(formatArg(v))
+	if err != nil {
+		return false, fmt.Errorf("arg %s: must be a boolean", key)
+	}
+	return b, nil
+}
+
+// argFloat returns args[key] as a float64, or def if unset.
+func argFloat(args map[string]any, key string, def float64) (float64, error) {
+	v, ok := args[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(formatArg(v), 64)
+	if err != nil {
+		return 0, fmt.Errorf("arg %s: must be a number", key)
+	}
+	return f, nil
+}
+
+// argDuration returns args[key] as a duration, or def if unset.
+func argDuration(args map[string]any, key string, def time.Duration) (time.Duration, error) {
+	v, ok := args[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	d, err := time.ParseDuration(formatArg(v))
+	if err != nil {
+		return 0, fmt.Errorf("arg %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// argList returns args[key] as a list of strings. A YAML list is used as-is;
+// a scalar is split on commas. Empty entries are dropped.
+func argList(args map[string]any, key string) []string {
+	v, ok := args[key]
+	if !ok || v == nil {
+		return nil
+	}
+	var raw []string
+	if list, ok := v.([]any); ok {
+		for _, item := range list {
+			raw = append(raw, formatArg(item))
+		}
+	} else {
+		raw = strings.Split(formatArg(v), ",")
+	}
+	var out []string
+	for _, s := range raw {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// argMap returns args[key] as a string map (e.g. HTTP headers).
+func argMap(args map[string]any, key string) (map[string]string, error) {
+	v, ok := args[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("arg %s: must be a map", key)
+	}
+	out := make(map[string]string, len(m))
+	for k, val := range m {
+		out[k] = formatArg(val)
+	}
+	return out, nil
+}
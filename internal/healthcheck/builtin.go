@@ -1,25 +1,61 @@
 package healthcheck
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 )
 
-// ExecBuiltin returns synthetic ExecResult output for built-in healthchecks
-// without spawning a process.
-func ExecBuiltin(name string, params map[string]string) (*ExecResult, error) {
-	switch name {
-	case "lifecycle":
-		return execLifecycle(params)
-	case "ok":
+// BuiltinOpts configures a builtin healthcheck run.
+type BuiltinOpts struct {
+	Params  map[string]string // daemon-provided params, e.g. the lifecycle event
+	Args    map[string]any    // alert args
+	Timeout time.Duration     // alert timeout (0 = builtin default)
+}
+
+type builtinFunc func(ctx context.Context, opts BuiltinOpts) (*ExecResult, error)
+
+// builtins maps builtin:// names to their implementations.
+var builtins = map[string]builtinFunc{
+	"lifecycle": execLifecycle,
+	"ok": func(context.Context, BuiltinOpts) (*ExecResult, error) {
 		return &ExecResult{Stdout: "--- event\ntype=ok\n"}, nil
-	default:
+	},
+	"http":       execHTTP,
+	"tls_expiry": execTLSExpiry,
+	"tcp":        execTCP,
+	"dns":        execDNS,
+
+	"cpu_usage":    execCPUUsage,
+	"memory_usage": execMemoryUsage,
+	"disk_usage":   execDiskUsage,
+	"load_average": execLoadAverage,
+}
+
+// ExecBuiltin runs a built-in healthcheck in-process without spawning a
+// process. The result carries either synthetic Stdout or pre-parsed Events.
+func ExecBuiltin(ctx context.Context, name string, opts BuiltinOpts) (*ExecResult, error) {
+	fn, ok := builtins[name]
+	if !ok {
 		return nil, fmt.Errorf("unknown builtin healthcheck: %s", name)
 	}
+	start := time.Now()
+	result, err := fn(ctx, opts)
+	if result != nil {
+		result.Duration = time.Since(start)
+	}
+	return result, err
+}
+
+// IsBuiltin reports whether name is a known builtin healthcheck.
+func IsBuiltin(name string) bool {
+	_, ok := builtins[name]
+	return ok
 }
 
-func execLifecycle(params map[string]string) (*ExecResult, error) {
-	event := params["event"]
+func execLifecycle(_ context.Context, opts BuiltinOpts) (*ExecResult, error) {
+	event := opts.Params["event"]
 	if event == "" {
 		return nil, fmt.Errorf("builtin lifecycle: missing event param")
 	}
@@ -27,9 +63,19 @@ func execLifecycle(params map[string]string) (*ExecResult, error) {
 	var b strings.Builder
 	b.WriteString("--- event\n")
 	b.WriteString("type=" + event + "\n")
-	if alerts, ok := params["alerts"]; ok {
+	if alerts, ok := opts.Params["alerts"]; ok {
 		b.WriteString("alerts=" + alerts + "\n")
 	}
 
 	return &ExecResult{Stdout: b.String()}, nil
 }
+
+// newEvent builds an Event from a type and alternating key/value pairs.
+func newEvent(typ string, kv ...string) Event {
+	fields := make(map[string]string, len(kv)/2+1)
+	fields["type"] = typ
+	for i := 0; i+1 < len(kv); i += 2 {
+		fields[kv[i]] = kv[i+1]
+	}
+	return Event{Type: typ, Fields: fields}
+}
@@ -0,0 +1,175 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// execDNS resolves a name and compares the answers against expected records.
+//
+// Args:
+//   - name (required): name to resolve
+//   - record_type: A, AAAA, CNAME, MX, NS, or TXT (default A)
+//   - resolver: host[:port] of the DNS server to query (default: system
+//     resolver; port defaults to 53)
+//   - expected: list or comma-separated records the answers are compared to
+//   - match: "exact" (answers equal expected, default) or "contains" (every
+//     expected record is among the answers)
+//
+// Emits one event: ok, mismatch, nxdomain, timeout, or resolve_error, with
+// name, record_type, resolver, answers and latency_ms fields.
+func execDNS(ctx context.Context, opts BuiltinOpts) (*ExecResult, error) {
+	name := argString(opts.Args, "name", "")
+	if name == "" {
+		return nil, fmt.Errorf("builtin dns: missing name arg")
+	}
+	recordType := strings.ToUpper(argString(opts.Args, "record_type", "A"))
+	lookup, ok := dnsLookups[recordType]
+	if !ok {
+		return nil, fmt.Errorf("builtin dns: unsupported record_type %q", recordType)
+	}
+	match := argString(opts.Args, "match", "exact")
+	if match != "exact" && match != "contains" {
+		return nil, fmt.Errorf("builtin dns: arg match: must be exact or contains")
+	}
+	expected := normalizeRecords(recordType, argList(opts.Args, "expected"))
+
+	resolver := net.DefaultResolver
+	server := argString(opts.Args, "resolver", "")
+	if server != "" {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultBuiltinTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	answers, err := lookup(ctx, resolver, name)
+	latency := time.Since(start)
+
+	fields := []string{
+		"name", name,
+		"record_type", recordType,
+		"resolver", server,
+		"latency_ms", formatMillis(latency),
+	}
+	if err != nil {
+		typ := "resolve_error"
+		var dnsErr *net.DNSError
+		switch {
+		case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+			typ = "nxdomain"
+		case errors.Is(ctx.Err(), context.DeadlineExceeded),
+			errors.As(err, &dnsErr) && dnsErr.IsTimeout:
+			typ = "timeout"
+		}
+		ev := newEvent(typ, append(fields, "error", err.Error())...)
+		return &ExecResult{Events: []Event{ev}}, nil
+	}
+
+	answers = normalizeRecords(recordType, answers)
+	typ := "ok"
+	switch {
+	case len(expected) == 0 && len(answers) == 0:
+		typ = "nxdomain"
+	case len(expected) > 0 && match == "exact" && !slices.Equal(answers, expected):
+		typ = "mismatch"
+	case len(expected) > 0 && match == "contains" && !containsAll(answers, expected):
+		typ = "mismatch"
+	}
+
+	fields = append(fields, "answers", strings.Join(answers, ","))
+	if len(expected) > 0 {
+		fields = append(fields, "expected", strings.Join(expected, ","))
+	}
+	return &ExecResult{Events: []Event{newEvent(typ, fields...)}}, nil
+}
+
+type dnsLookupFunc func(ctx context.Context, r *net.Resolver, name string) ([]string, error)
+
+var dnsLookups = map[string]dnsLookupFunc{
+	"A":    lookupIP("ip4"),
+	"AAAA": lookupIP("ip6"),
+	"CNAME": func(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return []string{cname}, nil
+	},
+	"MX": func(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+		mxs, err := r.LookupMX(ctx, name)
+		out := make([]string, 0, len(mxs))
+		for _, mx := range mxs {
+			out = append(out, mx.Host)
+		}
+		return out, err
+	},
+	"NS": func(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+		nss, err := r.LookupNS(ctx, name)
+		out := make([]string, 0, len(nss))
+		for _, ns := range nss {
+			out = append(out, ns.Host)
+		}
+		return out, err
+	},
+	"TXT": func(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+		return r.LookupTXT(ctx, name)
+	},
+}
+
+func lookupIP(network string) dnsLookupFunc {
+	return func(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+		ips, err := r.LookupIP(ctx, network, name)
+		out := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			out = append(out, ip.String())
+		}
+		return out, err
+	}
+}
+
+// normalizeRecords canonicalizes IPs, lowercases names and strips their
+// trailing dots (TXT values are kept verbatim), and returns the records
+// sorted and deduplicated so answer order does not matter.
+func normalizeRecords(recordType string, records []string) []string {
+	out := make([]string, 0, len(records))
+	for _, r := range records {
+		if ip := net.ParseIP(r); ip != nil {
+			out = append(out, ip.String())
+		} else if recordType == "TXT" {
+			out = append(out, r)
+		} else {
+			out = append(out, strings.TrimSuffix(strings.ToLower(r), "."))
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		if !slices.Contains(have, w) {
+			return false
+		}
+	}
+	return true
+}
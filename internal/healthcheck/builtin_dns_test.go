@@ -0,0 +1,152 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serveDNS starts a stub UDP DNS server answering from records, keyed by
+// fully qualified name and type. Unknown names get NXDOMAIN.
+func serveDNS(t *testing.T, records map[string]map[dnsmessage.Type][]dnsmessage.ResourceBody) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = pc.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var req dnsmessage.Message
+			if err := req.Unpack(buf[:n]); err != nil || len(req.Questions) != 1 {
+				continue
+			}
+			q := req.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: req.ID, Response: true, Authoritative: true, RCode: dnsmessage.RCodeSuccess},
+				Questions: req.Questions,
+			}
+			byType, ok := records[q.Name.String()]
+			if !ok {
+				resp.RCode = dnsmessage.RCodeNameError
+			}
+			for _, body := range byType[q.Type] {
+				resp.Answers = append(resp.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   body,
+				})
+			}
+			out, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = pc.WriteTo(out, addr)
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func runDNS(t *testing.T, args map[string]any) Event {
+	t.Helper()
+	result, err := ExecBuiltin(context.Background(), "dns", BuiltinOpts{Args: args, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Events) != 1 {
+		t.Fatalf("events = %d, want 1", len(result.Events))
+	}
+	return result.Events[0]
+}
+
+func TestBuiltinDNS(t *testing.T) {
+	server := serveDNS(t, map[string]map[dnsmessage.Type][]dnsmessage.ResourceBody{
+		"app.test.": {
+			dnsmessage.TypeA: {
+				&dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}},
+				&dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}},
+			},
+		},
+		"txt.test.": {
+			dnsmessage.TypeTXT: {&dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		args     map[string]any
+		wantType string
+		answers  string
+	}{
+		{
+			name:     "any answer",
+			args:     map[string]any{"name": "app.test."},
+			wantType: "ok",
+			answers:  "10.0.0.1,10.0.0.2",
+		},
+		{
+			name:     "exact match ignores order",
+			args:     map[string]any{"name": "app.test.", "expected": []any{"10.0.0.2", "10.0.0.1"}},
+			wantType: "ok",
+			answers:  "10.0.0.1,10.0.0.2",
+		},
+		{
+			name:     "exact mismatch",
+			args:     map[string]any{"name": "app.test.", "expected": "10.0.0.1"},
+			wantType: "mismatch",
+			answers:  "10.0.0.1,10.0.0.2",
+		},
+		{
+			name:     "contains",
+			args:     map[string]any{"name": "app.test.", "expected": "10.0.0.1", "match": "contains"},
+			wantType: "ok",
+			answers:  "10.0.0.1,10.0.0.2",
+		},
+		{
+			name:     "txt",
+			args:     map[string]any{"name": "txt.test.", "record_type": "txt", "expected": []any{"v=spf1 -all"}},
+			wantType: "ok",
+			answers:  "v=spf1 -all",
+		},
+		{
+			name:     "nxdomain",
+			args:     map[string]any{"name": "missing.test."},
+			wantType: "nxdomain",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args["resolver"] = server
+			ev := runDNS(t, tt.args)
+			if ev.Type != tt.wantType {
+				t.Fatalf("type = %q, want %q (fields %v)", ev.Type, tt.wantType, ev.Fields)
+			}
+			if ev.Fields["answers"] != tt.answers {
+				t.Errorf("answers = %q, want %q", ev.Fields["answers"], tt.answers)
+			}
+			if ev.Fields["resolver"] != server || ev.Fields["latency_ms"] == "" {
+				t.Errorf("fields = %v", ev.Fields)
+			}
+		})
+	}
+}
+
+func TestBuiltinDNS_InvalidArgs(t *testing.T) {
+	tests := []map[string]any{
+		{},
+		{"name": "example.com", "record_type": "SRV"},
+		{"name": "example.com", "match": "some"},
+	}
+	for _, args := range tests {
+		if _, err := ExecBuiltin(context.Background(), "dns", BuiltinOpts{Args: args}); err == nil {
+			t.Errorf("args %v: expected error", args)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package healthcheck
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Host metric builtins mirror the official disk_usage, memory_usage and
+// cpu_usage binaries: same args, same event types, same fields, so alerts
+// can switch between https:// and builtin:// without touching templates.
+
+// procRoot is where /proc is read from; tests point it at fixtures.
+var procRoot = "/proc"
+
+// usageThresholds reads threshold_warn_percent / threshold_crit_percent.
+func usageThresholds(args map[string]any) (warn, crit float64, err error) {
+	if warn, err = argFloat(args, "threshold_warn_percent", 80); err != nil {
+		return 0, 0, err
+	}
+	if crit, err = argFloat(args, "threshold_crit_percent", 95); err != nil {
+		return 0, 0, err
+	}
+	return warn, crit, nil
+}
+
+// classify maps a value to ok, high_<kind> or critical_<kind>.
+func classify(value, warn, crit float64, kind string) string {
+	switch {
+	case value >= crit:
+		return "critical_" + kind
+	case value >= warn:
+		return "high_" + kind
+	default:
+		return "ok"
+	}
+}
+
+// execDiskUsage reports filesystem usage for a mount point via statfs.
+//
+// Args: threshold_warn_percent (default 80), threshold_crit_percent
+// (default 95), mount (default /).
+func execDiskUsage(_ context.Context, opts BuiltinOpts) (*ExecResult, error) {
+	warn, crit, err := usageThresholds(opts.Args)
+	if err != nil {
+		return nil, fmt.Errorf("builtin disk_usage: %w", err)
+	}
+	mount := argString(opts.Args, "mount", "/")
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(mount, &st); err != nil {
+		return nil, fmt.Errorf("builtin disk_usage: statfs %s: %w", mount, err)
+	}
+	bsize := uint64(st.Bsize)
+	used := (st.Blocks - st.Bfree) * bsize
+	avail := st.Bavail * bsize
+	// Like df, usage is relative to the space available to unprivileged
+	// users, so reserved blocks count as unavailable.
+	var usage float64
+	if used+avail > 0 {
+		usage = float64(used) / float64(used+avail) * 100
+	}
+
+	ev := newEvent(classify(usage, warn, crit, "usage"),
+		"mount", mount,
+		"usage_percent", formatPercent(usage),
+		"total", formatBytes(st.Blocks*bsize),
+		"used", formatBytes(used),
+		"available", formatBytes(avail),
+	)
+	return &ExecResult{Events: []Event{ev}}, nil
+}
+
+// execMemoryUsage reports memory usage from /proc/meminfo.
+//
+// Args: threshold_warn_percent (default 80), threshold_crit_percent
+// (default 95).
+func execMemoryUsage(_ context.Context, opts BuiltinOpts) (*ExecResult, error) {
+	warn, crit, err := usageThresholds(opts.Args)
+	if err != nil {
+		return nil, fmt.Errorf("builtin memory_usage: %w", err)
+	}
+	info, err := readMeminfo()
+	if err != nil {
+		return nil, fmt.Errorf("builtin memory_usage: %w", err)
+	}
+
+	total := info["MemTotal"]
+	avail, ok := info["MemAvailable"]
+	if !ok {
+		// Kernels before 3.14 lack MemAvailable.
+		avail = info["MemFree"] + info["Buffers"] + info["Cached"]
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("builtin memory_usage: MemTotal missing from meminfo")
+	}
+	used := total - min(avail, total)
+	usage := float64(used) / float64(total) * 100
+
+	ev := newEvent(classify(usage, warn, crit, "usage"),
+		"usage_percent", formatPercent(usage),
+		"total", formatBytes(total),
+		"used", formatBytes(used),
+		"available", formatBytes(avail),
+	)
+	return &ExecResult{Events: []Event{ev}}, nil
+}
+
+// execCPUUsage reports CPU usage over a sampling window from /proc/stat.
+//
+// Args: threshold_warn_percent (default 80), threshold_crit_percent
+// (default 95), sample: window between the two /proc/stat reads (default 1s).
+func execCPUUsage(ctx context.Context, opts BuiltinOpts) (*ExecResult, error) {
+	warn, crit, err := usageThresholds(opts.Args)
+	if err != nil {
+		return nil, fmt.Errorf("builtin cpu_usage: %w", err)
+	}
+	sample, err := argDuration(opts.Args, "sample", time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("builtin cpu_usage: %w", err)
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	idle1, total1, err := readCPUTimes()
+	if err != nil {
+		return nil, fmt.Errorf("builtin cpu_usage: %w", err)
+	}
+	select {
+	case <-time.After(sample):
+	case <-ctx.Done():
+		return nil, fmt.Errorf("builtin cpu_usage: %w", ctx.Err())
+	}
+	idle2, total2, err := readCPUTimes()
+	if err != nil {
+		return nil, fmt.Errorf("builtin cpu_usage: %w", err)
+	}
+
+	var usage float64
+	if dt := total2 - total1; dt > 0 {
+		usage = float64(dt-(idle2-idle1)) / float64(dt) * 100
+	}
+	ev := newEvent(classify(usage, warn, crit, "usage"),
+		"usage_percent", formatPercent(usage),
+		"cpus", strconv.Itoa(runtime.NumCPU()),
+	)
+	return &ExecResult{Events: []Event{ev}}, nil
+}
+
+// execLoadAverage reports the system load average from /proc/loadavg.
+//
+// Args: threshold_warn (default: number of CPUs), threshold_crit (default:
+// twice the number of CPUs), period: 1, 5 or 15 minute average compared
+// against the thresholds (default 1).
+func execLoadAverage(_ context.Context, opts BuiltinOpts) (*ExecResult, error) {
+	cpus := runtime.NumCPU()
+	warn, err := argFloat(opts.Args, "threshold_warn", float64(cpus))
+	if err != nil {
+		return nil, fmt.Errorf("builtin load_average: %w", err)
+	}
+	crit, err := argFloat(opts.Args, "threshold_crit", float64(2*cpus))
+	if err != nil {
+		return nil, fmt.Errorf("builtin load_average: %w", err)
+	}
+	period := argString(opts.Args, "period", "1")
+	var idx int
+	switch period {
+	case "1":
+		idx = 0
+	case "5":
+		idx = 1
+	case "15":
+		idx = 2
+	default:
+		return nil, fmt.Errorf("builtin load_average: arg period: must be 1, 5 or 15")
+	}
+
+	data, err := os.ReadFile(filepath.Join(procRoot, "loadavg"))
+	if err != nil {
+		return nil, fmt.Errorf("builtin load_average: %w", err)
+	}
+	parts := strings.Fields(string(data))
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("builtin load_average: malformed loadavg %q", strings.TrimSpace(string(data)))
+	}
+	var loads [3]float64
+	for i := range loads {
+		if loads[i], err = strconv.ParseFloat(parts[i], 64); err != nil {
+			return nil, fmt.Errorf("builtin load_average: malformed loadavg: %w", err)
+		}
+	}
+
+	ev := newEvent(classify(loads[idx], warn, crit, "load"),
+		"load1", parts[0],
+		"load5", parts[1],
+		"load15", parts[2],
+		"period", period,
+		"cpus", strconv.Itoa(cpus),
+		"load_per_cpu", strconv.FormatFloat(loads[idx]/float64(cpus), 'f', 2, 64),
+	)
+	return &ExecResult{Events: []Event{ev}}, nil
+}
+
+// readMeminfo parses /proc/meminfo into byte counts keyed by field name.
+func readMeminfo() (map[string]uint64, error) {
+	f, err := os.Open(filepath.Join(procRoot, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info := make(map[string]uint64)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, rest, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 1 && fields[1] == "kB" {
+			v *= 1024
+		}
+		info[key] = v
+	}
+	return info, sc.Err()
+}
+
+// readCPUTimes returns idle and total jiffies from the aggregate cpu line
+// of /proc/stat. Guest time is already counted in user/nice and is skipped.
+func readCPUTimes() (idle, total uint64, err error) {
+	f, err := os.Open(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		// user nice system idle iowait irq softirq steal
+		for i, s := range fields[1:min(len(fields), 9)] {
+			v, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("malformed /proc/stat: %w", err)
+			}
+			total += v
+			if i == 3 || i == 4 {
+				idle += v
+			}
+		}
+		return idle, total, nil
+	}
+	if err := sc.Err(); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, fmt.Errorf("no cpu line in /proc/stat")
+}
+
+// formatPercent formats a percentage with one decimal, e.g. 84.3.
+func formatPercent(p float64) string {
+	return strconv.FormatFloat(p, 'f', 1, 64)
+}
+
+// formatBytes formats a byte count in binary units the way df -h does,
+// e.g. 512B, 8G, 1.5T.
+func formatBytes(n uint64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return strconv.FormatUint(n, 10) + "B"
+	}
+	v := float64(n)
+	i := -1
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	s := strconv.FormatFloat(v, 'f', 1, 64)
+	s = strings.TrimSuffix(s, ".0")
+	return s + string(units[i])
+}
@@ -0,0 +1,177 @@
+package healthcheck
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeProc points procRoot at a temp dir holding the given files.
+func fakeProc(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := procRoot
+	procRoot = dir
+	t.Cleanup(func() { procRoot = old })
+}
+
+func runHost(t *testing.T, name string, args map[string]any) Event {
+	t.Helper()
+	result, err := ExecBuiltin(context.Background(), name, BuiltinOpts{Args: args})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Events) != 1 {
+		t.Fatalf("events = %d, want 1", len(result.Events))
+	}
+	return result.Events[0]
+}
+
+const testMeminfo = `MemTotal:        8000000 kB
+MemFree:          500000 kB
+MemAvailable:    1200000 kB
+Buffers:          100000 kB
+Cached:          2000000 kB
+`
+
+func TestBuiltinMemoryUsage(t *testing.T) {
+	fakeProc(t, map[string]string{"meminfo": testMeminfo})
+
+	tests := []struct {
+		args     map[string]any
+		wantType string
+	}{
+		{nil, "high_usage"},
+		{map[string]any{"threshold_warn_percent": 80, "threshold_crit_percent": 85}, "critical_usage"},
+		{map[string]any{"threshold_warn_percent": 90, "threshold_crit_percent": 95}, "ok"},
+	}
+	for _, tt := range tests {
+		ev := runHost(t, "memory_usage", tt.args)
+		if ev.Type != tt.wantType {
+			t.Errorf("args %v: type = %q, want %q", tt.args, ev.Type, tt.wantType)
+		}
+		if ev.Fields["usage_percent"] != "85.0" || ev.Fields["available"] != "1.1G" || ev.Fields["total"] != "7.6G" {
+			t.Errorf("fields = %v", ev.Fields)
+		}
+	}
+}
+
+func TestBuiltinMemoryUsage_NoMemAvailable(t *testing.T) {
+	fakeProc(t, map[string]string{"meminfo": "MemTotal: 1000 kB\nMemFree: 200 kB\nBuffers: 100 kB\nCached: 200 kB\n"})
+
+	ev := runHost(t, "memory_usage", nil)
+	if ev.Fields["usage_percent"] != "50.0" {
+		t.Errorf("usage_percent = %q, want 50.0", ev.Fields["usage_percent"])
+	}
+}
+
+func TestBuiltinCPUUsage(t *testing.T) {
+	fakeProc(t, map[string]string{
+		"stat": "cpu  100 0 100 800 0 0 0 0 0 0\ncpu0 100 0 100 800 0 0 0 0 0 0\n",
+	})
+
+	// A static /proc/stat means no ticks elapsed between samples.
+	ev := runHost(t, "cpu_usage", map[string]any{"sample": "1ms"})
+	if ev.Type != "ok" || ev.Fields["usage_percent"] != "0.0" {
+		t.Errorf("event = %+v, want ok at 0.0", ev)
+	}
+	if ev.Fields["cpus"] != strconv.Itoa(runtime.NumCPU()) {
+		t.Errorf("cpus = %q", ev.Fields["cpus"])
+	}
+}
+
+func TestBuiltinCPUUsage_Timeout(t *testing.T) {
+	fakeProc(t, map[string]string{"stat": "cpu  1 0 1 8 0 0 0 0\n"})
+
+	_, err := ExecBuiltin(context.Background(), "cpu_usage", BuiltinOpts{
+		Args:    map[string]any{"sample": "1s"},
+		Timeout: 10 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("expected error when sample exceeds timeout")
+	}
+}
+
+func TestReadCPUTimes(t *testing.T) {
+	// user nice system idle iowait irq softirq steal guest guest_nice
+	fakeProc(t, map[string]string{"stat": "cpu  10 20 30 400 50 6 7 8 100 100\n"})
+
+	idle, total, err := readCPUTimes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idle != 450 || total != 531 {
+		t.Errorf("idle, total = %d, %d; want 450, 531", idle, total)
+	}
+}
+
+func TestBuiltinLoadAverage(t *testing.T) {
+	fakeProc(t, map[string]string{"loadavg": "3.50 1.20 0.80 2/345 6789\n"})
+
+	ev := runHost(t, "load_average", map[string]any{"threshold_warn": 2, "threshold_crit": 4})
+	if ev.Type != "high_load" {
+		t.Errorf("type = %q, want high_load", ev.Type)
+	}
+	if ev.Fields["load1"] != "3.50" || ev.Fields["load5"] != "1.20" || ev.Fields["load15"] != "0.80" {
+		t.Errorf("fields = %v", ev.Fields)
+	}
+
+	ev = runHost(t, "load_average", map[string]any{"threshold_warn": 2, "threshold_crit": 4, "period": 5})
+	if ev.Type != "ok" || ev.Fields["period"] != "5" {
+		t.Errorf("event = %+v, want ok for period 5", ev)
+	}
+
+	if _, err := ExecBuiltin(context.Background(), "load_average", BuiltinOpts{Args: map[string]any{"period": 10}}); err == nil {
+		t.Error("expected error for invalid period")
+	}
+}
+
+func TestBuiltinDiskUsage(t *testing.T) {
+	dir := t.TempDir()
+
+	ev := runHost(t, "disk_usage", map[string]any{
+		"mount":                  dir,
+		"threshold_warn_percent": 101,
+		"threshold_crit_percent": 101,
+	})
+	if ev.Type != "ok" {
+		t.Errorf("type = %q, want ok", ev.Type)
+	}
+	for _, k := range []string{"mount", "usage_percent", "total", "used", "available"} {
+		if ev.Fields[k] == "" {
+			t.Errorf("missing field %q in %v", k, ev.Fields)
+		}
+	}
+
+	if _, err := ExecBuiltin(context.Background(), "disk_usage", BuiltinOpts{Args: map[string]any{"mount": filepath.Join(dir, "nope")}}); err == nil {
+		t.Error("expected error for missing mount")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		in   uint64
+		want string
+	}{
+		{0, "0B"},
+		{512, "512B"},
+		{1024, "1K"},
+		{1536, "1.5K"},
+		{8 << 30, "8G"},
+		{3 << 40, "3T"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.in); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultBuiltinTimeout applies to network builtins when the alert sets no timeout.
+const defaultBuiltinTimeout = 10 * time.Second
+
+// maxHTTPBody caps how much of a response body builtin://http reads.
+const maxHTTPBody = 10 << 20
+
+// execHTTP probes an HTTP endpoint.
+//
+// Args:
+//   - url (required)
+//   - method: request method (default GET)
+//   - expected_status: list or comma-separated codes; "2xx"-style classes are
+//     allowed (default 2xx)
+//   - body_regex: regular expression the response body must match
+//   - headers: map of request headers
+//   - tls_verify: verify the server certificate (default true)
+//
+// Emits one event: ok, unexpected_status, body_mismatch, timeout, or
+// connection_error, with url, method, status_code, latency_ms and
+// response_size fields.
+func execHTTP(ctx context.Context, opts BuiltinOpts) (*ExecResult, error) {
+	url := argString(opts.Args, "url", "")
+	if url == "" {
+		return nil, fmt.Errorf("builtin http: missing url arg")
+	}
+	method := strings.ToUpper(argString(opts.Args, "method", http.MethodGet))
+
+	expected, err := parseStatusPatterns(argList(opts.Args, "expected_status"))
+	if err != nil {
+		return nil, fmt.Errorf("builtin http: %w", err)
+	}
+	var bodyRe *regexp.Regexp
+	if pattern := argString(opts.Args, "body_regex", ""); pattern != "" {
+		if bodyRe, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("builtin http: arg body_regex: %w", err)
+		}
+	}
+	headers, err := argMap(opts.Args, "headers")
+	if err != nil {
+		return nil, fmt.Errorf("builtin http: %w", err)
+	}
+	verify, err := argBool(opts.Args, "tls_verify", true)
+	if err != nil {
+		return nil, fmt.Errorf("builtin http: %w", err)
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultBuiltinTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("builtin http: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: !verify},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		typ := "connection_error"
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			typ = "timeout"
+		}
+		ev := newEvent(typ,
+			"url", url,
+			"method", method,
+			"latency_ms", formatMillis(time.Since(start)),
+			"error", err.Error(),
+		)
+		return &ExecResult{Events: []Event{ev}}, nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBody))
+	latency := time.Since(start)
+
+	typ := "ok"
+	switch {
+	case readErr != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		typ = "timeout"
+	case readErr != nil:
+		typ = "connection_error"
+	case !matchesStatus(expected, resp.StatusCode):
+		typ = "unexpected_status"
+	case bodyRe != nil && !bodyRe.Match(body):
+		typ = "body_mismatch"
+	}
+
+	ev := newEvent(typ,
+		"url", url,
+		"method", method,
+		"status_code", strconv.Itoa(resp.StatusCode),
+		"latency_ms", formatMillis(latency),
+		"response_size", strconv.Itoa(len(body)),
+	)
+	if readErr != nil {
+		ev.Fields["error"] = readErr.Error()
+	}
+	return &ExecResult{Events: []Event{ev}}, nil
+}
+
+// statusPattern matches an exact status code, or a class like 2xx when
+// class is set.
+type statusPattern struct {
+	code  int
+	class bool
+}
+
+func parseStatusPatterns(raw []string) ([]statusPattern, error) {
+	if len(raw) == 0 {
+		return []statusPattern{{code: 2, class: true}}, nil
+	}
+	out := make([]statusPattern, 0, len(raw))
+	for _, s := range raw {
+		s = strings.ToLower(s)
+		if len(s) == 3 && strings.HasSuffix(s, "xx") && s[0] >= '1' && s[0] <= '5' {
+			out = append(out, statusPattern{code: int(s[0] - '0'), class: true})
+			continue
+		}
+		code, err := strconv.Atoi(s)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("arg expected_status: invalid status %q", s)
+		}
+		out = append(out, statusPattern{code: code})
+	}
+	return out, nil
+}
+
+func matchesStatus(patterns []statusPattern, code int) bool {
+	for _, p := range patterns {
+		if p.class && code/100 == p.code || !p.class && code == p.code {
+			return true
+		}
+	}
+	return false
+}
+
+// formatMillis formats a duration as fractional milliseconds.
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', 3, 64)
+}
@@ -0,0 +1,132 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func runHTTP(t *testing.T, args map[string]any, timeout time.Duration) Event {
+	t.Helper()
+	result, err := ExecBuiltin(context.Background(), "http", BuiltinOpts{Args: args, Timeout: timeout})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Events) != 1 {
+		t.Fatalf("events = %d, want 1", len(result.Events))
+	}
+	return result.Events[0]
+}
+
+func TestBuiltinHTTP_Ok(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"healthy"}`))
+	}))
+	defer srv.Close()
+
+	ev := runHTTP(t, map[string]any{
+		"url":        srv.URL,
+		"headers":    map[string]any{"X-Token": "secret"},
+		"body_regex": `"status":"healthy"`,
+	}, 0)
+	if ev.Type != "ok" {
+		t.Fatalf("type = %q, want ok (fields %v)", ev.Type, ev.Fields)
+	}
+	if ev.Fields["status_code"] != "200" || ev.Fields["response_size"] != "20" {
+		t.Errorf("fields = %v", ev.Fields)
+	}
+	if ev.Fields["latency_ms"] == "" || ev.Fields["method"] != "GET" {
+		t.Errorf("fields = %v", ev.Fields)
+	}
+}
+
+func TestBuiltinHTTP_UnexpectedStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ev := runHTTP(t, map[string]any{"url": srv.URL}, 0)
+	if ev.Type != "unexpected_status" || ev.Fields["status_code"] != "503" {
+		t.Errorf("event = %+v, want unexpected_status 503", ev)
+	}
+
+	ev = runHTTP(t, map[string]any{"url": srv.URL, "expected_status": []any{200, "5xx"}}, 0)
+	if ev.Type != "ok" {
+		t.Errorf("type = %q, want ok with 5xx expected", ev.Type)
+	}
+}
+
+func TestBuiltinHTTP_Method(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer srv.Close()
+
+	if ev := runHTTP(t, map[string]any{"url": srv.URL, "method": "head"}, 0); ev.Type != "ok" {
+		t.Errorf("type = %q, want ok", ev.Type)
+	}
+}
+
+func TestBuiltinHTTP_BodyMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("maintenance"))
+	}))
+	defer srv.Close()
+
+	ev := runHTTP(t, map[string]any{"url": srv.URL, "body_regex": "^ok$"}, 0)
+	if ev.Type != "body_mismatch" {
+		t.Errorf("type = %q, want body_mismatch", ev.Type)
+	}
+}
+
+func TestBuiltinHTTP_Timeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	ev := runHTTP(t, map[string]any{"url": srv.URL}, 50*time.Millisecond)
+	if ev.Type != "timeout" {
+		t.Errorf("type = %q, want timeout", ev.Type)
+	}
+}
+
+func TestBuiltinHTTP_TLSVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	if ev := runHTTP(t, map[string]any{"url": srv.URL}, 0); ev.Type != "connection_error" {
+		t.Errorf("type = %q, want connection_error for self-signed cert", ev.Type)
+	}
+	if ev := runHTTP(t, map[string]any{"url": srv.URL, "tls_verify": false}, 0); ev.Type != "ok" {
+		t.Errorf("type = %q, want ok with tls_verify: false", ev.Type)
+	}
+}
+
+func TestBuiltinHTTP_InvalidArgs(t *testing.T) {
+	tests := []map[string]any{
+		{},
+		{"url": "http://localhost", "expected_status": "abc"},
+		{"url": "http://localhost", "body_regex": "("},
+		{"url": "http://localhost", "headers": "X-Token: a"},
+	}
+	for _, args := range tests {
+		if _, err := ExecBuiltin(context.Background(), "http", BuiltinOpts{Args: args}); err == nil {
+			t.Errorf("args %v: expected error", args)
+		}
+	}
+}
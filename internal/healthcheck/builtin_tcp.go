@@ -0,0 +1,125 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxBanner caps how much builtin://tcp reads while matching a banner.
+const maxBanner = 4096
+
+// execTCP checks that a TCP endpoint accepts connections.
+//
+// Args:
+//   - address (required): host:port to connect to
+//   - send: payload written after connecting
+//   - expect: regular expression the response must match; when set, reads
+//     until it matches, the peer closes, or the timeout expires
+//
+// Emits one event: ok, banner_mismatch, timeout, or connection_error, with
+// address, connect_ms and latency_ms fields, plus banner when one was read.
+// banner_mismatch means the read ended with a response that did not match;
+// a read that times out or fails before a match reports timeout or
+// connection_error with the error.
+func execTCP(ctx context.Context, opts BuiltinOpts) (*ExecResult, error) {
+	address := argString(opts.Args, "address", "")
+	if address == "" {
+		return nil, fmt.Errorf("builtin tcp: missing address arg")
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("builtin tcp: arg address: %w", err)
+	}
+	send := argString(opts.Args, "send", "")
+	var expect *regexp.Regexp
+	if pattern := argString(opts.Args, "expect", ""); pattern != "" {
+		var err error
+		if expect, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("builtin tcp: arg expect: %w", err)
+		}
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultBuiltinTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	errType := func(err error) string {
+		if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "timeout"
+		}
+		return "connection_error"
+	}
+	fail := func(err error) (*ExecResult, error) {
+		ev := newEvent(errType(err),
+			"address", address,
+			"latency_ms", formatMillis(time.Since(start)),
+			"error", err.Error(),
+		)
+		return &ExecResult{Events: []Event{ev}}, nil
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fail(err)
+	}
+	defer func() { _ = conn.Close() }()
+	connectLatency := time.Since(start)
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if send != "" {
+		if _, err := conn.Write([]byte(send)); err != nil {
+			return fail(err)
+		}
+	}
+
+	typ := "ok"
+	var banner []byte
+	var readErr error
+	if expect != nil {
+		buf := make([]byte, 512)
+		for !expect.Match(banner) && len(banner) < maxBanner {
+			n, err := conn.Read(buf)
+			banner = append(banner, buf[:n]...)
+			if err != nil {
+				readErr = err
+				break
+			}
+		}
+		if len(banner) > maxBanner {
+			banner = banner[:maxBanner]
+		}
+		switch {
+		case expect.Match(banner):
+		case readErr != nil && !errors.Is(readErr, io.EOF):
+			typ = errType(readErr)
+		default:
+			typ = "banner_mismatch"
+		}
+	}
+
+	ev := newEvent(typ,
+		"address", address,
+		"connect_ms", formatMillis(connectLatency),
+		"latency_ms", formatMillis(time.Since(start)),
+	)
+	if len(banner) > 0 {
+		ev.Fields["banner"] = strings.TrimSpace(string(banner))
+	}
+	if typ != "ok" && typ != "banner_mismatch" {
+		ev.Fields["error"] = readErr.Error()
+	}
+	return &ExecResult{Events: []Event{ev}}, nil
+}
@@ -0,0 +1,121 @@
+package healthcheck
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveTCP starts a listener that runs handle for each connection.
+func serveTCP(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				handle(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func runTCP(t *testing.T, args map[string]any, timeout time.Duration) Event {
+	t.Helper()
+	result, err := ExecBuiltin(context.Background(), "tcp", BuiltinOpts{Args: args, Timeout: timeout})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Events) != 1 {
+		t.Fatalf("events = %d, want 1", len(result.Events))
+	}
+	return result.Events[0]
+}
+
+func TestBuiltinTCP_Connect(t *testing.T) {
+	addr := serveTCP(t, func(net.Conn) {})
+
+	ev := runTCP(t, map[string]any{"address": addr}, 0)
+	if ev.Type != "ok" {
+		t.Fatalf("type = %q, want ok (fields %v)", ev.Type, ev.Fields)
+	}
+	if ev.Fields["address"] != addr || ev.Fields["connect_ms"] == "" || ev.Fields["latency_ms"] == "" {
+		t.Errorf("fields = %v", ev.Fields)
+	}
+}
+
+func TestBuiltinTCP_SendExpect(t *testing.T) {
+	addr := serveTCP(t, func(conn net.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if line == "PING\n" {
+			_, _ = conn.Write([]byte("+PONG\r\n"))
+		} else {
+			_, _ = conn.Write([]byte("-ERR\r\n"))
+		}
+	})
+
+	ev := runTCP(t, map[string]any{"address": addr, "send": "PING\n", "expect": `^\+PONG`}, time.Second)
+	if ev.Type != "ok" || ev.Fields["banner"] != "+PONG" {
+		t.Errorf("event = %+v, want ok with banner +PONG", ev)
+	}
+
+	ev = runTCP(t, map[string]any{"address": addr, "send": "QUIT\n", "expect": `^\+PONG`}, time.Second)
+	if ev.Type != "banner_mismatch" || ev.Fields["banner"] != "-ERR" {
+		t.Errorf("event = %+v, want banner_mismatch with banner -ERR", ev)
+	}
+}
+
+func TestBuiltinTCP_ExpectTimeout(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	addr := serveTCP(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("SSH-2.0-"))
+		<-done
+	})
+
+	ev := runTCP(t, map[string]any{"address": addr, "expect": `OpenSSH`}, 100*time.Millisecond)
+	if ev.Type != "timeout" || ev.Fields["banner"] != "SSH-2.0-" || ev.Fields["error"] == "" {
+		t.Errorf("event = %+v, want timeout with partial banner and error", ev)
+	}
+	if ev.Fields["connect_ms"] == "" {
+		t.Errorf("fields = %v, want connect_ms after a successful connect", ev.Fields)
+	}
+}
+
+func TestBuiltinTCP_ConnectionError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	ev := runTCP(t, map[string]any{"address": addr}, time.Second)
+	if ev.Type != "connection_error" || ev.Fields["error"] == "" {
+		t.Errorf("event = %+v, want connection_error", ev)
+	}
+}
+
+func TestBuiltinTCP_InvalidArgs(t *testing.T) {
+	tests := []map[string]any{
+		{},
+		{"address": "localhost"},
+		{"address": "localhost:22", "expect": "("},
+	}
+	for _, args := range tests {
+		if _, err := ExecBuiltin(context.Background(), "tcp", BuiltinOpts{Args: args}); err == nil {
+			t.Errorf("args %v: expected error", args)
+		}
+	}
+}
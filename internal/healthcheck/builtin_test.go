@@ -1,9 +1,12 @@
 package healthcheck
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestExecBuiltin_Ok(t *testing.T) {
-	result, err := ExecBuiltin("ok", nil)
+	result, err := ExecBuiltin(context.Background(), "ok", BuiltinOpts{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -14,7 +17,7 @@ func TestExecBuiltin_Ok(t *testing.T) {
 }
 
 func TestExecBuiltin_Unknown(t *testing.T) {
-	_, err := ExecBuiltin("nonexistent", nil)
+	_, err := ExecBuiltin(context.Background(), "nonexistent", BuiltinOpts{})
 	if err == nil {
 		t.Fatal("expected error for unknown builtin")
 	}
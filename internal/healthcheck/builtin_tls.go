@@ -0,0 +1,229 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// execTLSExpiry checks certificate expiry for TLS endpoints and PEM files.
+//
+// Args:
+//   - targets: list or comma-separated host[:port] endpoints (port defaults to 443)
+//   - files: list or comma-separated PEM file paths
+//   - sni: server name sent in the handshake and used for hostname
+//     verification (default: the target host)
+//   - ca_file: PEM bundle used as the root pool for chain validation
+//     (default: system roots)
+//   - warn_days: days left at or below which a cert is "warning" (default 30)
+//   - crit_days: days left at or below which a cert is "critical" (default 7)
+//
+// Emits one event per certificate: the leaf served by each target, and every
+// certificate found in each file. Types are ok, warning, critical, expired,
+// plus connection_error / read_error when a target or file cannot be read.
+func execTLSExpiry(ctx context.Context, opts BuiltinOpts) (*ExecResult, error) {
+	targets := argList(opts.Args, "targets")
+	files := argList(opts.Args, "files")
+	if len(targets) == 0 && len(files) == 0 {
+		return nil, fmt.Errorf("builtin tls_expiry: at least one of targets or files is required")
+	}
+	warnDays, err := argFloat(opts.Args, "warn_days", 30)
+	if err != nil {
+		return nil, fmt.Errorf("builtin tls_expiry: %w", err)
+	}
+	critDays, err := argFloat(opts.Args, "crit_days", 7)
+	if err != nil {
+		return nil, fmt.Errorf("builtin tls_expiry: %w", err)
+	}
+	sni := argString(opts.Args, "sni", "")
+
+	var roots *x509.CertPool
+	if caFile := argString(opts.Args, "ca_file", ""); caFile != "" {
+		certs, err := readPEMCerts(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("builtin tls_expiry: arg ca_file: %w", err)
+		}
+		roots = x509.NewCertPool()
+		for _, c := range certs {
+			roots.AddCert(c)
+		}
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultBuiltinTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	check := certCheck{roots: roots, warnDays: warnDays, critDays: critDays, now: time.Now()}
+	var events []Event
+	for _, target := range targets {
+		events = append(events, check.target(ctx, target, sni))
+	}
+	for _, path := range files {
+		events = append(events, check.file(path, sni)...)
+	}
+	return &ExecResult{Events: events}, nil
+}
+
+type certCheck struct {
+	roots    *x509.CertPool
+	warnDays float64
+	critDays float64
+	now      time.Time
+}
+
+// target handshakes with a host:port endpoint and reports on its leaf cert.
+func (c certCheck) target(ctx context.Context, target, sni string) Event {
+	addr := target
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "443")
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	serverName := sni
+	if serverName == "" {
+		serverName = host
+	}
+
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName: serverName,
+		// Chain validation is reported as a field rather than failing the
+		// handshake, so expiry is still visible for untrusted certs.
+		InsecureSkipVerify: true,
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return newEvent("connection_error",
+			"target", addr,
+			"server_name", serverName,
+			"error", err.Error(),
+		)
+	}
+	defer func() { _ = conn.Close() }()
+
+	peers := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		return newEvent("connection_error",
+			"target", addr,
+			"server_name", serverName,
+			"error", "server presented no certificates",
+		)
+	}
+	ev := c.event(peers[0], peers[1:], serverName)
+	ev.Fields["target"] = addr
+	ev.Fields["server_name"] = serverName
+	return ev
+}
+
+// file reports on every certificate in a PEM file. Certificates after the
+// first are treated as intermediates when validating each chain.
+func (c certCheck) file(path, sni string) []Event {
+	certs, err := readPEMCerts(path)
+	if err != nil {
+		return []Event{newEvent("read_error", "target", path, "error", err.Error())}
+	}
+	events := make([]Event, 0, len(certs))
+	for i, cert := range certs {
+		var intermediates []*x509.Certificate
+		intermediates = append(intermediates, certs[:i]...)
+		intermediates = append(intermediates, certs[i+1:]...)
+		ev := c.event(cert, intermediates, sni)
+		ev.Fields["target"] = path
+		ev.Fields["index"] = strconv.Itoa(i)
+		events = append(events, ev)
+	}
+	return events
+}
+
+func (c certCheck) event(cert *x509.Certificate, intermediates []*x509.Certificate, dnsName string) Event {
+	left := cert.NotAfter.Sub(c.now)
+	days := int(left.Hours() / 24)
+
+	typ := "ok"
+	switch {
+	case left <= 0:
+		typ = "expired"
+	case float64(days) <= c.critDays:
+		typ = "critical"
+	case float64(days) <= c.warnDays:
+		typ = "warning"
+	}
+
+	ev := newEvent(typ,
+		"days_left", strconv.Itoa(days),
+		"not_before", cert.NotBefore.UTC().Format(time.RFC3339),
+		"not_after", cert.NotAfter.UTC().Format(time.RFC3339),
+		"subject", cert.Subject.String(),
+		"issuer", cert.Issuer.String(),
+		"sans", strings.Join(certSANs(cert), ","),
+		"serial", hex.EncodeToString(cert.SerialNumber.Bytes()),
+		"chain_valid", "true",
+	)
+	if err := c.verify(cert, intermediates, dnsName); err != nil {
+		ev.Fields["chain_valid"] = "false"
+		ev.Fields["chain_error"] = err.Error()
+	}
+	return ev
+}
+
+func (c certCheck) verify(cert *x509.Certificate, intermediates []*x509.Certificate, dnsName string) error {
+	pool := x509.NewCertPool()
+	for _, ic := range intermediates {
+		pool.AddCert(ic)
+	}
+	_, err := cert.Verify(x509.VerifyOptions{
+		DNSName:       dnsName,
+		Roots:         c.roots,
+		Intermediates: pool,
+		CurrentTime:   c.now,
+	})
+	return err
+}
+
+func certSANs(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
+
+func readPEMCerts(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return certs, nil
+}
@@ -0,0 +1,211 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	der  []byte
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert issues a certificate expiring in validFor. A nil parent makes
+// it a self-signed CA.
+func newTestCert(t *testing.T, cn string, validFor time.Duration, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validFor),
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.DNSNames = []string{cn}
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, der: der, key: key}
+}
+
+func writePEM(t *testing.T, certs ...*testCert) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "certs.pem")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	for _, c := range certs {
+		if err := pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: c.der}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// serveTLS starts a TLS listener presenting leaf and returns its address.
+func serveTLS(t *testing.T, leaf *testCert) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{leaf.der}, PrivateKey: leaf.key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func runTLSExpiry(t *testing.T, args map[string]any) []Event {
+	t.Helper()
+	result, err := ExecBuiltin(context.Background(), "tls_expiry", BuiltinOpts{Args: args, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result.Events
+}
+
+func TestBuiltinTLSExpiry_Target(t *testing.T) {
+	ca := newTestCert(t, "Test CA", 365*24*time.Hour, nil)
+	leaf := newTestCert(t, "localhost", 20*24*time.Hour+time.Hour, ca)
+	addr := serveTLS(t, leaf)
+
+	events := runTLSExpiry(t, map[string]any{
+		"targets": []any{addr},
+		"sni":     "localhost",
+		"ca_file": writePEM(t, ca),
+	})
+	if len(events) != 1 {
+		t.Fatalf("events = %d, want 1", len(events))
+	}
+	ev := events[0]
+	if ev.Type != "warning" {
+		t.Errorf("type = %q, want warning", ev.Type)
+	}
+	want := map[string]string{
+		"target":      addr,
+		"server_name": "localhost",
+		"days_left":   "20",
+		"subject":     "CN=localhost",
+		"issuer":      "CN=Test CA",
+		"sans":        "localhost,127.0.0.1",
+		"chain_valid": "true",
+	}
+	for k, v := range want {
+		if ev.Fields[k] != v {
+			t.Errorf("%s = %q, want %q", k, ev.Fields[k], v)
+		}
+	}
+}
+
+func TestBuiltinTLSExpiry_UntrustedChain(t *testing.T) {
+	ca := newTestCert(t, "Test CA", 365*24*time.Hour, nil)
+	leaf := newTestCert(t, "localhost", 90*24*time.Hour, ca)
+	addr := serveTLS(t, leaf)
+
+	ev := runTLSExpiry(t, map[string]any{"targets": addr})[0]
+	if ev.Type != "ok" {
+		t.Errorf("type = %q, want ok", ev.Type)
+	}
+	if ev.Fields["chain_valid"] != "false" || ev.Fields["chain_error"] == "" {
+		t.Errorf("chain_valid = %q, chain_error = %q; want invalid chain", ev.Fields["chain_valid"], ev.Fields["chain_error"])
+	}
+}
+
+func TestBuiltinTLSExpiry_ConnectionError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	ev := runTLSExpiry(t, map[string]any{"targets": addr})[0]
+	if ev.Type != "connection_error" || ev.Fields["error"] == "" {
+		t.Errorf("event = %+v, want connection_error", ev)
+	}
+}
+
+func TestBuiltinTLSExpiry_Files(t *testing.T) {
+	ca := newTestCert(t, "Test CA", 365*24*time.Hour, nil)
+	expired := newTestCert(t, "old.example", -24*time.Hour-time.Minute, ca)
+	soon := newTestCert(t, "soon.example", 3*24*time.Hour+time.Hour, ca)
+	path := writePEM(t, expired, soon)
+
+	events := runTLSExpiry(t, map[string]any{
+		"files":     path + ",/nonexistent.pem",
+		"warn_days": 14,
+		"crit_days": 5,
+	})
+	if len(events) != 3 {
+		t.Fatalf("events = %d, want 3", len(events))
+	}
+	wantTypes := []string{"expired", "critical", "read_error"}
+	for i, want := range wantTypes {
+		if events[i].Type != want {
+			t.Errorf("events[%d].type = %q, want %q", i, events[i].Type, want)
+		}
+	}
+	if events[0].Fields["days_left"] != "-1" {
+		t.Errorf("expired days_left = %q, want -1", events[0].Fields["days_left"])
+	}
+	if events[1].Fields["index"] != strconv.Itoa(1) || events[1].Fields["target"] != path {
+		t.Errorf("fields = %v", events[1].Fields)
+	}
+}
+
+func TestBuiltinTLSExpiry_InvalidArgs(t *testing.T) {
+	tests := []map[string]any{
+		{},
+		{"targets": "example.com", "warn_days": "soon"},
+		{"targets": "example.com", "ca_file": "/nonexistent.pem"},
+	}
+	for _, args := range tests {
+		if _, err := ExecBuiltin(context.Background(), "tls_expiry", BuiltinOpts{Args: args}); err == nil {
+			t.Errorf("args %v: expected error", args)
+		}
+	}
+}
@@ -19,6 +19,7 @@ type ExecResult struct {
 	Duration time.Duration
 	ExitCode int
 	Env      []string
+	Events   []Event // pre-parsed events from builtins; when set, Stdout is not parsed
 }
 
 // ExecOpts configures healthcheck execution.
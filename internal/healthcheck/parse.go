@@ -11,7 +11,21 @@ import (
 type Event struct {
 	Type   string
 	Fields map[string]string
-	Raw    string // original block text (without "--- event" delimiter)
+	Values map[string]any // typed values from JSON output; nil for "--- event" blocks
+	Raw    string         // original block text (without "--- event" delimiter)
+}
+
+// TemplateValues returns the event as exposed to templates: typed values for
+// JSON output, string fields otherwise.
+func (e Event) TemplateValues() map[string]any {
+	if e.Values != nil {
+		return e.Values
+	}
+	out := make(map[string]any, len(e.Fields))
+	for k, v := range e.Fields {
+		out[k] = v
+	}
+	return out
 }
 
 // ParseEvents parses healthcheck stdout into a list of events.
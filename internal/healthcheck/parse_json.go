@@ -0,0 +1,151 @@
+package healthcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Output formats accepted by ParseOutput.
+const (
+	FormatEvents = "events" // "--- event" blocks of KEY=VALUE pairs
+	FormatJSON   = "json"   // JSON Lines or a JSON array of event objects
+)
+
+// jsonHeader on the first non-empty line of stdout selects JSON output
+// regardless of the alert's configured format.
+const jsonHeader = "--- json"
+
+// ParseOutput parses healthcheck stdout in the given format. An empty
+// format means "--- event" blocks unless stdout starts with a "--- json"
+// header line.
+func ParseOutput(stdout, format string) ([]Event, error) {
+	if body, ok := stripJSONHeader(stdout); ok {
+		return ParseJSONEvents(body)
+	}
+	if format == FormatJSON {
+		return ParseJSONEvents(stdout)
+	}
+	return ParseEvents(stdout)
+}
+
+func stripJSONHeader(stdout string) (string, bool) {
+	trimmed := strings.TrimLeft(stdout, " \t\r\n")
+	line, rest, _ := strings.Cut(trimmed, "\n")
+	if strings.TrimSpace(line) != jsonHeader {
+		return "", false
+	}
+	return rest, true
+}
+
+// ParseJSONEvents parses JSON healthcheck output: either a single JSON array
+// of event objects, or a stream of objects (typically one per line). Each
+// object must carry a string "type". Values keep their JSON types; Fields
+// holds their string forms, with nested objects and arrays as compact JSON.
+// Top-level keys are lowercased to match "--- event" output.
+func ParseJSONEvents(stdout string) ([]Event, error) {
+	dec := json.NewDecoder(strings.NewReader(stdout))
+	dec.UseNumber()
+
+	var objects []map[string]any
+	if strings.HasPrefix(strings.TrimSpace(stdout), "[") {
+		if err := dec.Decode(&objects); err != nil {
+			return nil, fmt.Errorf("json output: %w", err)
+		}
+		if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("json output: unexpected data after array")
+		}
+	} else {
+		for {
+			var obj map[string]any
+			err := dec.Decode(&obj)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("json output: event %d: %w", len(objects), err)
+			}
+			objects = append(objects, obj)
+		}
+	}
+
+	events := make([]Event, 0, len(objects))
+	for i, obj := range objects {
+		if obj == nil {
+			return nil, fmt.Errorf("json output: event %d: not an object", i)
+		}
+		values := make(map[string]any, len(obj))
+		fields := make(map[string]string, len(obj))
+		for k, v := range obj {
+			k = strings.ToLower(k)
+			v = convertNumbers(v)
+			values[k] = v
+			fields[k] = flattenValue(v)
+		}
+
+		typ, ok := values["type"].(string)
+		if !ok || typ == "" {
+			return nil, fmt.Errorf("event %d: missing required 'type' field", i)
+		}
+
+		raw, _ := json.Marshal(obj)
+		events = append(events, Event{
+			Type:   typ,
+			Fields: fields,
+			Values: values,
+			Raw:    string(raw),
+		})
+	}
+	return events, nil
+}
+
+// convertNumbers replaces json.Number with int64 where exact, else float64,
+// so templates see plain numbers and large integers print without exponents.
+func convertNumbers(v any) any {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		f, _ := x.Float64()
+		return f
+	case map[string]any:
+		for k, e := range x {
+			x[k] = convertNumbers(e)
+		}
+		return x
+	case []any:
+		for i, e := range x {
+			x[i] = convertNumbers(e)
+		}
+		return x
+	default:
+		return v
+	}
+}
+
+// flattenValue returns the string form of a JSON value for Fields.
+func flattenValue(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case bool:
+		return strconv.FormatBool(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(x)
+		return strings.TrimSuffix(buf.String(), "\n")
+	}
+}
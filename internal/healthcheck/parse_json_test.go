@@ -0,0 +1,127 @@
+package healthcheck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONEvents_Lines(t *testing.T) {
+	stdout := `{"type":"unit_failed","units":["nginx","redis"],"count":2,"load":0.5,"details":{"since":"10m"}}
+{"Type":"ok","healthy":true,"note":null}
+`
+	events, err := ParseJSONEvents(stdout)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %d, want 2", len(events))
+	}
+
+	ev := events[0]
+	if ev.Type != "unit_failed" {
+		t.Errorf("type = %q", ev.Type)
+	}
+	if got := ev.Values["units"]; !reflect.DeepEqual(got, []any{"nginx", "redis"}) {
+		t.Errorf("units = %#v", got)
+	}
+	if got := ev.Values["count"]; got != int64(2) {
+		t.Errorf("count = %#v, want int64(2)", got)
+	}
+	if got := ev.Values["load"]; got != 0.5 {
+		t.Errorf("load = %#v, want 0.5", got)
+	}
+	wantFields := map[string]string{
+		"type":    "unit_failed",
+		"units":   `["nginx","redis"]`,
+		"count":   "2",
+		"load":    "0.5",
+		"details": `{"since":"10m"}`,
+	}
+	if !reflect.DeepEqual(ev.Fields, wantFields) {
+		t.Errorf("fields = %v, want %v", ev.Fields, wantFields)
+	}
+
+	// Top-level keys are lowercased like "--- event" output.
+	ev = events[1]
+	if ev.Type != "ok" || ev.Values["healthy"] != true || ev.Fields["healthy"] != "true" || ev.Fields["note"] != "" {
+		t.Errorf("event = %+v", ev)
+	}
+}
+
+func TestParseJSONEvents_Array(t *testing.T) {
+	events, err := ParseJSONEvents(`[
+  {"type": "a", "bytes": 1500000},
+  {"type": "b"}
+]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 || events[0].Type != "a" || events[1].Type != "b" {
+		t.Fatalf("events = %+v", events)
+	}
+	if events[0].Fields["bytes"] != "1500000" {
+		t.Errorf("bytes = %q, want 1500000", events[0].Fields["bytes"])
+	}
+}
+
+func TestParseJSONEvents_Empty(t *testing.T) {
+	for _, stdout := range []string{"", "\n", "[]"} {
+		events, err := ParseJSONEvents(stdout)
+		if err != nil || len(events) != 0 {
+			t.Errorf("ParseJSONEvents(%q) = %v, %v; want no events", stdout, events, err)
+		}
+	}
+}
+
+func TestParseJSONEvents_Errors(t *testing.T) {
+	tests := []string{
+		`{"usage": 5}`,
+		`{"type": 5}`,
+		`{"type": ""}`,
+		`{"type": "ok"`,
+		`[{"type": "ok"}] {"type": "ok"}`,
+		`["ok"]`,
+		`null`,
+	}
+	for _, stdout := range tests {
+		if _, err := ParseJSONEvents(stdout); err == nil {
+			t.Errorf("ParseJSONEvents(%q): expected error", stdout)
+		}
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		stdout string
+		format string
+		want   string
+	}{
+		{"default events", "--- event\ntype=ok\n", "", "ok"},
+		{"configured json", `{"type":"ok"}`, FormatJSON, "ok"},
+		{"header overrides", "--- json\n{\"type\":\"warn\"}\n", FormatEvents, "warn"},
+		{"header after blank lines", "\n\n--- json\n[{\"type\":\"warn\"}]", "", "warn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := ParseOutput(tt.stdout, tt.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(events) != 1 || events[0].Type != tt.want {
+				t.Errorf("events = %+v, want one %q event", events, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvent_TemplateValues(t *testing.T) {
+	kv := Event{Type: "ok", Fields: map[string]string{"type": "ok", "n": "1"}}
+	if got := kv.TemplateValues(); got["n"] != "1" {
+		t.Errorf("kv values = %v", got)
+	}
+	js := Event{Type: "ok", Fields: map[string]string{"n": "1"}, Values: map[string]any{"n": int64(1)}}
+	if got := js.TemplateValues(); got["n"] != int64(1) {
+		t.Errorf("json values = %v", got)
+	}
+}
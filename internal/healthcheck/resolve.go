@@ -30,10 +30,14 @@ type ResolveOpts struct {
 //   - file://name      → filepath.Join(opts.HealthchecksDir, name)
 //   - file:///abs/path → absolute path as-is
 //   - https://...      → download with sha256 verification and caching
+//   - builtin://name   → in-process healthcheck, see ExecBuiltin
 func Resolve(uri string, opts ResolveOpts) (*ResolvedHealthcheck, error) {
 	switch {
 	case strings.HasPrefix(uri, "builtin://"):
 		name := strings.TrimPrefix(uri, "builtin://")
+		if !IsBuiltin(name) {
+			return nil, fmt.Errorf("unknown builtin healthcheck: %s", name)
+		}
 		return &ResolvedHealthcheck{URI: uri, Path: name, Scheme: "builtin"}, nil
 	case strings.HasPrefix(uri, "file://"):
 		return resolveFile(uri, opts.HealthchecksDir)
@@ -218,3 +218,12 @@ func writeExecutable(t *testing.T, dir, name, content string) {
 		t.Fatal(err)
 	}
 }
+
+func TestResolve_BuiltinUnknown(t *testing.T) {
+	if _, err := Resolve("builtin://nope", ResolveOpts{}); err == nil {
+		t.Fatal("expected error for unknown builtin")
+	}
+	if _, err := Resolve("builtin://http", ResolveOpts{}); err != nil {
+		t.Errorf("builtin://http: unexpected error: %v", err)
+	}
+}
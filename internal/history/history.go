@@ -0,0 +1,314 @@
+// Package history keeps an append-only, size-bounded record of every event
+// result the daemon produces, for `sznuper history`.
+//
+// Records are JSON lines in <state_dir>/history.jsonl. When the file reaches
+// half the size limit it is moved to history.jsonl.1, replacing the previous
+// one, so the two files together stay within the limit and the oldest
+// records are dropped first.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/runner"
+	"github.com/sznuper/sznuper/internal/secret"
+)
+
+// FileName is the history file name inside options.state_dir.
+const FileName = "history.jsonl"
+
+// DefaultMaxSize is the size limit when options.history_max_size is unset.
+const DefaultMaxSize = 10 << 20
+
+// Outcomes of a record.
+const (
+	OutcomeNotified     = "notified"
+	OutcomeFailed       = "failed"
+	OutcomeGrouped      = "grouped"
+	OutcomeSuppressed   = "suppressed"
+	OutcomeSilenced     = "silenced"
+	OutcomeInhibited    = "inhibited"
+	OutcomeAcknowledged = "acknowledged"
+	OutcomeDropped      = "dropped"
+	OutcomePending      = "pending"
+	OutcomeFlapping     = "flapping"
+	OutcomeSkipped      = "skipped" // nothing to notify, e.g. healthy -> healthy
+	OutcomeError        = "error"
+)
+
+// Outcomes lists every outcome, for flag validation and help.
+var Outcomes = []string{
+	OutcomeNotified, OutcomeFailed, OutcomeGrouped, OutcomeSuppressed, OutcomeSilenced,
+	OutcomeInhibited, OutcomeAcknowledged, OutcomeDropped, OutcomePending, OutcomeFlapping,
+	OutcomeSkipped, OutcomeError,
+}
+
+// Record is one event result.
+type Record struct {
+	Time           time.Time         `json:"time"`
+	Alert          string            `json:"alert"`
+	Digest         string            `json:"digest,omitempty"`
+	EventType      string            `json:"event_type,omitempty"`
+	Key            string            `json:"key,omitempty"`
+	Outcome        string            `json:"outcome"`
+	Fields         map[string]string `json:"fields,omitempty"`
+	Rendered       map[string]string `json:"rendered,omitempty"` // channel -> message
+	Notified       []string          `json:"notified,omitempty"`
+	Failed         []string          `json:"failed,omitempty"`
+	Grouped        []string          `json:"grouped,omitempty"`
+	Withheld       []string          `json:"withheld,omitempty"` // channels a suppressed, silenced, inhibited or acknowledged notification would have gone to
+	Suppressed     bool              `json:"suppressed,omitempty"`
+	SilencedBy     string            `json:"silenced_by,omitempty"`
+	InhibitedBy    string            `json:"inhibited_by,omitempty"`
+	AcknowledgedBy string            `json:"acknowledged_by,omitempty"`
+	Dropped        string            `json:"dropped,omitempty"` // drop reason
+	Pending        bool              `json:"pending,omitempty"`
+	Flapping       bool              `json:"flapping,omitempty"`
+	FlapChange     string            `json:"flap_change,omitempty"`
+	Recovery       bool              `json:"recovery,omitempty"`
+	Healthy        *bool             `json:"healthy,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	ErrStage       string            `json:"err_stage,omitempty"`
+	Duration       time.Duration     `json:"duration,omitempty"`
+}
+
+// FromResult converts a runner result finished at t. Secrets are redacted
+// from the key, fields, rendered messages and error.
+func FromResult(res runner.Result, t time.Time) Record {
+	rec := Record{
+		Time:           t.UTC(),
+		Alert:          res.AlertName,
+		Digest:         res.Digest,
+		EventType:      res.EventType,
+		Key:            secret.Redact(res.EntityKey),
+		Fields:         redactValues(res.Fields),
+		Rendered:       redactValues(res.Rendered),
+		Notified:       res.Notified,
+		Failed:         res.Failed,
+		Grouped:        res.Grouped,
+		Withheld:       res.SuppressedChannels,
+		Suppressed:     res.Suppressed,
+		SilencedBy:     res.SilencedBy,
+		InhibitedBy:    res.InhibitedBy,
+		AcknowledgedBy: res.AcknowledgedBy,
+		Pending:        res.Pending,
+		Flapping:       res.Flapping,
+		FlapChange:     res.FlapChange,
+		Recovery:       res.IsRecovery,
+		Healthy:        res.Healthy,
+		ErrStage:       res.ErrStage,
+		Duration:       res.Duration,
+	}
+	if res.Dropped {
+		rec.Dropped = res.DropReason
+	}
+	if res.Err != nil {
+		rec.Error = secret.Redact(res.Err.Error())
+	}
+	rec.Outcome = outcome(res)
+	return rec
+}
+
+// redactValues returns m with secrets redacted from its values.
+func redactValues(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = secret.Redact(v)
+	}
+	return out
+}
+
+// outcome sums up what happened to a result's notification.
+func outcome(res runner.Result) string {
+	switch {
+	case res.Err != nil && len(res.Failed) == 0:
+		return OutcomeError
+	case res.Dropped:
+		return OutcomeDropped
+	case res.Acknowledged:
+		return OutcomeAcknowledged
+	case res.Inhibited:
+		return OutcomeInhibited
+	case res.Silenced:
+		return OutcomeSilenced
+	case res.Suppressed:
+		return OutcomeSuppressed
+	case res.Pending:
+		return OutcomePending
+	case res.Flapping && res.FlapChange == "" && len(res.Notified) == 0:
+		return OutcomeFlapping
+	case len(res.Failed) > 0:
+		return OutcomeFailed
+	case len(res.Notified) > 0:
+		return OutcomeNotified
+	case len(res.Grouped) > 0:
+		return OutcomeGrouped
+	}
+	return OutcomeSkipped
+}
+
+// Store appends records to the history file in a directory.
+type Store struct {
+	path    string
+	maxSize int64
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// Path returns the history file path in dir.
+func Path(dir string) string { return filepath.Join(dir, FileName) }
+
+// NewStore returns a store writing to dir with the given size limit.
+// The file is opened on the first record.
+func NewStore(dir string, maxSize int64) *Store {
+	return &Store{path: Path(dir), maxSize: maxSize}
+}
+
+// Record appends the result as finished now. It implements
+// runner.ResultRecorder.
+func (s *Store) Record(res runner.Result) error {
+	return s.Append(FromResult(res, time.Now()))
+}
+
+// Append writes rec, rotating the file when it reaches half the size limit.
+func (s *Store) Append(rec Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding history record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if s.size > 0 && s.size+int64(len(line)) > s.maxSize/2 {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.f.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening history: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("opening history: %w", err)
+	}
+	s.f, s.size = f, info.Size()
+	return nil
+}
+
+func (s *Store) rotate() error {
+	_ = s.f.Close()
+	s.f = nil
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return fmt.Errorf("rotating history: %w", err)
+	}
+	return s.open()
+}
+
+// Close closes the history file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// Filter selects records. Zero fields match everything.
+type Filter struct {
+	Alert     string // exact name
+	EventType string
+	Key       string
+	Since     time.Time
+	Until     time.Time
+	Outcomes  []string
+}
+
+// Match reports whether rec passes the filter.
+func (f Filter) Match(rec Record) bool {
+	switch {
+	case f.Alert != "" && rec.Alert != f.Alert,
+		f.EventType != "" && rec.EventType != f.EventType,
+		f.Key != "" && rec.Key != f.Key,
+		!f.Since.IsZero() && rec.Time.Before(f.Since),
+		!f.Until.IsZero() && !rec.Time.Before(f.Until):
+		return false
+	}
+	if len(f.Outcomes) == 0 {
+		return true
+	}
+	for _, o := range f.Outcomes {
+		if rec.Outcome == o {
+			return true
+		}
+	}
+	return false
+}
+
+// Read returns the records in dir that match f, oldest first. A missing
+// history is empty; malformed lines, e.g. one cut short by a crash, are
+// skipped.
+func Read(dir string, f Filter) ([]Record, error) {
+	var out []Record
+	for _, path := range []string{Path(dir) + ".1", Path(dir)} {
+		file, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading history: %w", err)
+		}
+		sc := bufio.NewScanner(file)
+		sc.Buffer(make([]byte, 64<<10), 16<<20)
+		for sc.Scan() {
+			var rec Record
+			if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+				continue
+			}
+			if f.Match(rec) {
+				out = append(out, rec)
+			}
+		}
+		err = sc.Err()
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading history: %w", err)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,218 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/runner"
+	"github.com/sznuper/sznuper/internal/secret"
+)
+
+func TestStore_AppendRead(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir, DefaultMaxSize)
+	defer s.Close()
+
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recs := []Record{
+		{Time: t0, Alert: "disk", EventType: "high_usage", Outcome: OutcomeNotified, Notified: []string{"telegram"}},
+		{Time: t0.Add(time.Minute), Alert: "disk", EventType: "high_usage", Outcome: OutcomeSuppressed},
+		{Time: t0.Add(2 * time.Minute), Alert: "ssh", EventType: "login", Key: "alice", Outcome: OutcomeNotified},
+	}
+	for _, rec := range recs {
+		if err := s.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(Path(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode = %o, want 600", perm)
+	}
+
+	tests := []struct {
+		name string
+		f    Filter
+		want int
+	}{
+		{"all", Filter{}, 3},
+		{"alert", Filter{Alert: "disk"}, 2},
+		{"event type", Filter{EventType: "login"}, 1},
+		{"key", Filter{Key: "alice"}, 1},
+		{"since", Filter{Since: t0.Add(time.Minute)}, 2},
+		{"until", Filter{Until: t0.Add(time.Minute)}, 1},
+		{"outcome", Filter{Outcomes: []string{OutcomeSuppressed, OutcomeError}}, 1},
+		{"combined", Filter{Alert: "disk", Outcomes: []string{OutcomeNotified}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(dir, tt.f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("got %d records, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_Rotate(t *testing.T) {
+	dir := t.TempDir()
+	const maxSize = 1000
+	s := NewStore(dir, maxSize)
+	defer s.Close()
+
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 100 {
+		rec := Record{Time: t0.Add(time.Duration(i) * time.Second), Alert: "disk", Outcome: OutcomeNotified}
+		if err := s.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var total int64
+	for _, p := range []string{Path(dir), Path(dir) + ".1"} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += info.Size()
+	}
+	if total > maxSize {
+		t.Errorf("history size = %d, want at most %d", total, maxSize)
+	}
+
+	got, err := Read(dir, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 || len(got) == 100 {
+		t.Fatalf("got %d records, want the most recent subset", len(got))
+	}
+	if last := got[len(got)-1].Time; !last.Equal(t0.Add(99 * time.Second)) {
+		t.Errorf("last record at %v, want the newest", last)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Time.Before(got[i-1].Time) {
+			t.Fatalf("records out of order at %d", i)
+		}
+	}
+}
+
+func TestRead_Missing(t *testing.T) {
+	got, err := Read(t.TempDir(), Filter{})
+	if err != nil || len(got) != 0 {
+		t.Errorf("Read = %v, %v; want empty", got, err)
+	}
+}
+
+func TestRead_SkipsMalformed(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"time":"2026-03-01T12:00:00Z","alert":"disk","outcome":"notified"}` + "\n" + `{"time":"2026-03-01T12:01`
+	if err := os.WriteFile(Path(dir), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(dir, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Errorf("got %d records, want 1", len(got))
+	}
+}
+
+func TestFromResult(t *testing.T) {
+	healthy := false
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	rec := FromResult(runner.Result{
+		AlertName: "disk",
+		EventType: "high_usage",
+		Fields:    map[string]string{"usage": "91"},
+		Rendered:  map[string]string{"telegram": "disk at 91%"},
+		Notified:  []string{"telegram"},
+		Healthy:   &healthy,
+		Duration:  time.Second,
+	}, now)
+	if rec.Outcome != OutcomeNotified || rec.Alert != "disk" || rec.Fields["usage"] != "91" ||
+		rec.Rendered["telegram"] != "disk at 91%" || rec.Healthy == nil || *rec.Healthy {
+		t.Errorf("record = %+v", rec)
+	}
+	if !rec.Time.Equal(now) {
+		t.Errorf("time = %v, want %v", rec.Time, now)
+	}
+}
+
+func TestStore_RedactsSecrets(t *testing.T) {
+	secret.Register("hist-s3cret")
+	dir := t.TempDir()
+	s := NewStore(dir, DefaultMaxSize)
+	err := s.Record(runner.Result{
+		AlertName: "api",
+		EventType: "error",
+		Fields:    map[string]string{"url": "https://hist-s3cret@api.example.com"},
+		Rendered:  map[string]string{"telegram": "token hist-s3cret rejected"},
+		Notified:  []string{"telegram"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+
+	data, err := os.ReadFile(Path(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hist-s3cret") || !strings.Contains(string(data), secret.Redacted) {
+		t.Errorf("history file not redacted: %s", data)
+	}
+
+	// What `sznuper history --json` prints.
+	recs, err := Read(dir, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.MarshalIndent(recs, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "hist-s3cret") {
+		t.Errorf("JSON output not redacted: %s", out)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		name string
+		res  runner.Result
+		want string
+	}{
+		{"skipped", runner.Result{}, OutcomeSkipped},
+		{"notified", runner.Result{Notified: []string{"a"}}, OutcomeNotified},
+		{"failed", runner.Result{Notified: []string{"a"}, Failed: []string{"b"}, Err: errors.New("boom")}, OutcomeFailed},
+		{"grouped", runner.Result{Grouped: []string{"a"}}, OutcomeGrouped},
+		{"error", runner.Result{Err: errors.New("boom"), ErrStage: "healthcheck"}, OutcomeError},
+		{"dropped", runner.Result{Dropped: true, DropReason: "drop_if"}, OutcomeDropped},
+		{"suppressed", runner.Result{Suppressed: true}, OutcomeSuppressed},
+		{"silenced", runner.Result{Suppressed: true, Silenced: true}, OutcomeSilenced},
+		{"inhibited", runner.Result{Suppressed: true, Inhibited: true}, OutcomeInhibited},
+		{"acknowledged", runner.Result{Suppressed: true, Acknowledged: true}, OutcomeAcknowledged},
+		{"pending", runner.Result{Pending: true}, OutcomePending},
+		{"flapping", runner.Result{Flapping: true}, OutcomeFlapping},
+		{"flapping start", runner.Result{Flapping: true, FlapChange: "start", Notified: []string{"a"}}, OutcomeNotified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outcome(tt.res); got != tt.want {
+				t.Errorf("outcome = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -12,21 +12,27 @@ import (
 //go:embed defaults/*.yml
 var defaultConfigs embed.FS
 
-// overlay pairs a condition with the filename to merge when true.
+// overlay pairs a condition with the filename to merge when true. builtin,
+// if set, is an equivalent overlay using builtin:// healthchecks that is
+// merged instead in offline mode.
 type overlay struct {
-	name  string
-	check func() bool
+	name    string
+	builtin string
+	check   func() bool
 }
 
 var overlays = []overlay{
-	{"disk_usage.yml", func() bool { return true }},
-	{"memory_usage.yml", func() bool { return fileExists("/proc/meminfo") }},
-	{"cpu_usage.yml", func() bool { return fileExists("/proc/stat") }},
-	{"ssh_journal.yml", func() bool { return cmdExists("journalctl") }},
+	{"disk_usage.yml", "disk_usage_builtin.yml", func() bool { return true }},
+	{"memory_usage.yml", "memory_usage_builtin.yml", func() bool { return fileExists("/proc/meminfo") }},
+	{"cpu_usage.yml", "cpu_usage_builtin.yml", func() bool { return fileExists("/proc/stat") }},
+	{"ssh_journal.yml", "", func() bool { return cmdExists("journalctl") }},
 }
 
 // DefaultConfig loads base.yml and merges all applicable overlays on top.
-func DefaultConfig() (*config.Config, error) {
+// When offline is set, alerts use builtin:// healthchecks so the config
+// works without downloading anything; overlays with no builtin equivalent
+// are skipped.
+func DefaultConfig(offline bool) (*config.Config, error) {
 	cfg, err := loadEmbedded("base.yml")
 	if err != nil {
 		return nil, err
@@ -36,7 +42,14 @@ func DefaultConfig() (*config.Config, error) {
 		if !o.check() {
 			continue
 		}
-		over, err := loadEmbedded(o.name)
+		name := o.name
+		if offline {
+			if o.builtin == "" {
+				continue
+			}
+			name = o.builtin
+		}
+		over, err := loadEmbedded(name)
 		if err != nil {
 			return nil, err
 		}
@@ -3,6 +3,8 @@ package initcmd
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/sznuper/sznuper/internal/config"
@@ -34,7 +36,7 @@ func TestEmbeddedDefaultsParse(t *testing.T) {
 }
 
 func TestDefaultConfig(t *testing.T) {
-	cfg, err := DefaultConfig()
+	cfg, err := DefaultConfig(false)
 	if err != nil {
 		t.Fatalf("DefaultConfig() error: %v", err)
 	}
@@ -46,6 +48,48 @@ func TestDefaultConfig(t *testing.T) {
 	}
 }
 
+func TestDefaultConfig_Offline(t *testing.T) {
+	cfg, err := DefaultConfig(true)
+	if err != nil {
+		t.Fatalf("DefaultConfig(true) error: %v", err)
+	}
+
+	if len(cfg.Alerts) < 2 {
+		t.Errorf("expected at least 2 alerts (lifecycle + disk), got %d", len(cfg.Alerts))
+	}
+	for _, a := range cfg.Alerts {
+		if !strings.HasPrefix(a.Healthcheck, "builtin://") {
+			t.Errorf("alert %s: healthcheck %s is not builtin", a.Name, a.Healthcheck)
+		}
+		if a.SHA256 != (config.SHA256{}) {
+			t.Errorf("alert %s: unexpected sha256", a.Name)
+		}
+	}
+}
+
+func TestOverlays_BuiltinVariantsMatch(t *testing.T) {
+	for _, o := range overlays {
+		if o.builtin == "" {
+			continue
+		}
+		remote, err := loadEmbedded(o.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		local, err := loadEmbedded(o.builtin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(remote.Alerts) != 1 || len(local.Alerts) != 1 {
+			t.Fatalf("%s: expected one alert per overlay", o.name)
+		}
+		r, l := remote.Alerts[0], local.Alerts[0]
+		if r.Name != l.Name || r.Template != l.Template || !reflect.DeepEqual(r.Args, l.Args) {
+			t.Errorf("%s and %s differ beyond the healthcheck", o.name, o.builtin)
+		}
+	}
+}
+
 func TestMergeConfig(t *testing.T) {
 	base := &config.Config{
 		Channels: map[string]config.Channel{
@@ -96,3 +140,29 @@ func TestFileExists(t *testing.T) {
 		t.Error("fileExists returned true for non-existent path")
 	}
 }
+
+func TestDefaultConfig_Loads(t *testing.T) {
+	cfg, err := DefaultConfig(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Channels = map[string]config.Channel{"logger": {URL: "logger://"}}
+	config.NotifyByDefault(cfg, "logger")
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := config.Write(cfg, path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("generated config does not load: %v", err)
+	}
+	for _, a := range loaded.Alerts {
+		if len(a.Notify) != 1 || a.Notify[0].Channel != "logger" {
+			t.Errorf("alert %s: notify = %v, want logger from defaults", a.Name, a.Notify)
+		}
+		if a.Name == "disk_usage" && (a.Cooldown != "10m" || len(a.Triggers) != 1 || a.Args["threshold_crit_percent"] == nil) {
+			t.Errorf("disk_usage did not inherit resource_usage: %+v", a)
+		}
+	}
+}
@@ -225,12 +225,7 @@ func (m *Model) buildConfig() {
 		}
 	}
 
-	for i := range m.cfg.Alerts {
-		for _, name := range added {
-			m.cfg.Alerts[i].Notify = append(m.cfg.Alerts[i].Notify,
-				config.NotifyTarget{Channel: name})
-		}
-	}
+	config.NotifyByDefault(m.cfg, added...)
 }
 
 func (m Model) existingNames() map[string]bool {
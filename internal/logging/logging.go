@@ -0,0 +1,201 @@
+// Package logging builds the daemon's loggers from the config's logging
+// section: text or JSON records fanned out to stderr, rotating files under
+// options.logs_dir and syslog, each with its own level, plus optional
+// per-alert streams of healthcheck output.
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+// Sink types.
+const (
+	SinkStderr = "stderr"
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+)
+
+// Defaults for unset paths.
+const (
+	DefaultFile      = "sznuper.log"
+	DefaultOutputDir = "healthchecks"
+)
+
+// Logs is the daemon's logging as configured.
+type Logs struct {
+	Logger *slog.Logger
+	// Output receives healthcheck output; nil without
+	// logging.healthcheck_output.
+	Output  *Streams
+	closers []io.Closer
+}
+
+// New opens the sinks of cfg. A nil cfg logs text at info level to stderr.
+// verbose lowers every sink to debug. Relative file paths are resolved
+// against logsDir.
+func New(cfg *config.Logging, logsDir string, verbose bool) (*Logs, error) {
+	if cfg == nil {
+		cfg = &config.Logging{}
+	}
+	sinks := cfg.Sinks
+	if len(sinks) == 0 {
+		sinks = []config.LogSink{{Type: SinkStderr}}
+	}
+
+	l := &Logs{}
+	handlers := make([]slog.Handler, 0, len(sinks))
+	for i, sc := range sinks {
+		h, c, err := openSink(sc, cfg, logsDir, verbose)
+		if err != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("logging.sinks[%d] (%s): %w", i, sc.Type, err)
+		}
+		handlers = append(handlers, h)
+		if c != nil {
+			l.closers = append(l.closers, c)
+		}
+	}
+	if len(handlers) == 1 {
+		l.Logger = slog.New(handlers[0])
+	} else {
+		l.Logger = slog.New(slog.NewMultiHandler(handlers...))
+	}
+
+	if oc := cfg.HealthcheckOutput; oc != nil {
+		s, err := newStreams(oc, cfg.Format, logsDir, l.Logger)
+		if err != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("logging.healthcheck_output: %w", err)
+		}
+		l.Output = s
+		l.closers = append(l.closers, s)
+	}
+	return l, nil
+}
+
+// Validate checks what the config schema can't: rotation values and that
+// file paths can be resolved.
+func Validate(cfg *config.Logging, logsDir string) error {
+	if cfg == nil {
+		return nil
+	}
+	var errs []error
+	for i, sc := range cfg.Sinks {
+		if sc.Type != SinkFile {
+			continue
+		}
+		if _, err := resolvePath(sc.Path, DefaultFile, logsDir); err != nil {
+			errs = append(errs, fmt.Errorf("logging.sinks[%d]: %w", i, err))
+		}
+		if _, err := compileRotation(sc.Rotate); err != nil {
+			errs = append(errs, fmt.Errorf("logging.sinks[%d]: %w", i, err))
+		}
+	}
+	if oc := cfg.HealthcheckOutput; oc != nil {
+		if _, err := resolvePath(oc.Dir, DefaultOutputDir, logsDir); err != nil {
+			errs = append(errs, fmt.Errorf("logging.healthcheck_output: %w", err))
+		}
+		if _, err := compileRotation(oc.Rotate); err != nil {
+			errs = append(errs, fmt.Errorf("logging.healthcheck_output: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes all files and syslog connections.
+func (l *Logs) Close() error {
+	var errs []error
+	for _, c := range l.closers {
+		errs = append(errs, c.Close())
+	}
+	l.closers = nil
+	return errors.Join(errs...)
+}
+
+func openSink(sc config.LogSink, cfg *config.Logging, logsDir string, verbose bool) (slog.Handler, io.Closer, error) {
+	format := first(sc.Format, cfg.Format, "text")
+	level := parseLevel(first(sc.Level, cfg.Level, "info"))
+	if verbose {
+		level = slog.LevelDebug
+	}
+
+	switch sc.Type {
+	case SinkStderr:
+		return newHandler(os.Stderr, format, level, nil), nil, nil
+	case SinkFile:
+		path, err := resolvePath(sc.Path, DefaultFile, logsDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		rot, err := compileRotation(sc.Rotate)
+		if err != nil {
+			return nil, nil, err
+		}
+		f, err := OpenFile(path, rot)
+		if err != nil {
+			return nil, nil, err
+		}
+		return newHandler(f, format, level, nil), f, nil
+	case SinkSyslog:
+		h, err := newSyslogHandler(sc.Address, first(sc.Tag, "sznuper"), format, level)
+		if err != nil {
+			return nil, nil, err
+		}
+		return h, h, nil
+	}
+	return nil, nil, fmt.Errorf("unknown sink type %q", sc.Type)
+}
+
+// newHandler returns a text or JSON handler writing to w that redacts
+// secrets.
+func newHandler(w io.Writer, format string, level slog.Level, replace func([]string, slog.Attr) slog.Attr) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: replace}
+	if format == "json" {
+		return Redact(slog.NewJSONHandler(w, opts))
+	}
+	return Redact(slog.NewTextHandler(w, opts))
+}
+
+// resolvePath resolves a configured path, or def when unset, against
+// logsDir.
+func resolvePath(path, def, logsDir string) (string, error) {
+	if path == "" {
+		path = def
+	}
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	if logsDir == "" {
+		return "", fmt.Errorf("relative path %q needs options.logs_dir", path)
+	}
+	return filepath.Join(logsDir, path), nil
+}
+
+func parseLevel(s string) slog.Level {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+func first(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,159 @@
+package logging
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+func TestNew_FileSinks(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Logging{
+		Format: "json",
+		Sinks: []config.LogSink{
+			{Type: SinkFile}, // json, info
+			{Type: SinkFile, Path: "errors.log", Level: "error", Format: "text"}, // text, error only
+		},
+	}
+	logs, err := New(cfg, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs.Logger.Debug("hidden")
+	logs.Logger.Info("started", "alerts", 3)
+	logs.Logger.With("alert", "disk").Error("exec failed", "error", "boom")
+	if err := logs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, DefaultFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("main log has %d lines, want 2:\n%s", len(lines), data)
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatalf("not JSON: %v", err)
+	}
+	if rec["msg"] != "exec failed" || rec["alert"] != "disk" || rec["level"] != "ERROR" {
+		t.Errorf("record = %v", rec)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, "errors.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); strings.Contains(got, "started") || !strings.Contains(got, `msg="exec failed" alert=disk`) {
+		t.Errorf("error log = %q", got)
+	}
+}
+
+func TestNew_Verbose(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Logging{Sinks: []config.LogSink{{Type: SinkFile, Level: "warn"}}}
+	logs, err := New(cfg, dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs.Logger.Debug("details")
+	_ = logs.Close()
+	data, _ := os.ReadFile(filepath.Join(dir, DefaultFile))
+	if !strings.Contains(string(data), "details") {
+		t.Errorf("verbose did not enable debug: %q", data)
+	}
+}
+
+func TestNew_Syslog(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram not available: %v", err)
+	}
+	defer conn.Close()
+
+	cfg := &config.Logging{Sinks: []config.LogSink{{Type: SinkSyslog, Address: sock, Tag: "sznuper-test"}}}
+	logs, err := New(cfg, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logs.Close()
+	logs.Logger.With("alert", "disk").Warn("notify failed", "channel", "slack")
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	// daemon facility (3) * 8 + warning (4) = 28
+	if !strings.HasPrefix(msg, "<28>") || !strings.Contains(msg, "sznuper-test") ||
+		!strings.Contains(msg, `msg="notify failed" alert=disk channel=slack`) || strings.Contains(msg, "time=") {
+		t.Errorf("syslog message = %q", msg)
+	}
+}
+
+func TestStreams(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Logging{HealthcheckOutput: &config.LogStream{Format: "json"}}
+	logs, err := New(cfg, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs.Output.For("disk").Info("healthcheck output", "stdout", "--- event\ntype=ok\n")
+	logs.Output.For("../etc/passwd").Info("healthcheck output")
+	if err := logs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, DefaultOutputDir, "disk.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec map[string]any
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["alert"] != "disk" || rec["stdout"] != "--- event\ntype=ok\n" {
+		t.Errorf("record = %v", rec)
+	}
+	if _, err := os.Stat(filepath.Join(dir, DefaultOutputDir, ".._etc_passwd.log")); err != nil {
+		t.Errorf("unsafe alert name not mapped into the output dir: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(nil, ""); err != nil {
+		t.Errorf("nil config: %v", err)
+	}
+	ok := &config.Logging{
+		Sinks:             []config.LogSink{{Type: SinkFile, Rotate: &config.LogRotate{MaxSize: "5MB", Keep: 3}}, {Type: SinkStderr}},
+		HealthcheckOutput: &config.LogStream{Dir: "/var/log/sznuper/hc"},
+	}
+	if err := Validate(ok, "/var/log/sznuper"); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+
+	bad := &config.Logging{
+		Sinks:             []config.LogSink{{Type: SinkFile, Rotate: &config.LogRotate{Every: "daily"}}},
+		HealthcheckOutput: &config.LogStream{},
+	}
+	err := Validate(bad, "")
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"logging.sinks[0]: relative path", "rotate.every", "logging.healthcheck_output: relative path"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+// Streams writes healthcheck output to one rotating log file per alert,
+// <dir>/<alert>.log. Files are opened on first use.
+type Streams struct {
+	dir    string
+	format string
+	rot    Rotation
+	log    *slog.Logger // daemon logger, for open failures
+
+	mu      sync.Mutex
+	loggers map[string]*slog.Logger
+	files   []*File
+}
+
+func newStreams(cfg *config.LogStream, format, logsDir string, log *slog.Logger) (*Streams, error) {
+	dir, err := resolvePath(cfg.Dir, DefaultOutputDir, logsDir)
+	if err != nil {
+		return nil, err
+	}
+	rot, err := compileRotation(cfg.Rotate)
+	if err != nil {
+		return nil, err
+	}
+	return &Streams{
+		dir:     dir,
+		format:  first(cfg.Format, format, "text"),
+		rot:     rot,
+		log:     log,
+		loggers: make(map[string]*slog.Logger),
+	}, nil
+}
+
+// For returns the output logger of an alert. If its file can't be opened
+// the failure is logged once and the alert's output is discarded.
+func (s *Streams) For(alert string) *slog.Logger {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.loggers[alert]; ok {
+		return l
+	}
+	path := filepath.Join(s.dir, fileName(alert)+".log")
+	f, err := OpenFile(path, s.rot)
+	if err != nil {
+		s.log.Warn("opening healthcheck output log failed, discarding output", "alert", alert, "error", err)
+		l := slog.New(slog.DiscardHandler)
+		s.loggers[alert] = l
+		return l
+	}
+	s.files = append(s.files, f)
+	l := slog.New(newHandler(f, s.format, slog.LevelDebug, nil)).With("alert", alert)
+	s.loggers[alert] = l
+	return l
+}
+
+// Close closes every open output file.
+func (s *Streams) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, f := range s.files {
+		errs = append(errs, f.Close())
+	}
+	s.files = nil
+	clear(s.loggers)
+	return errors.Join(errs...)
+}
+
+// fileName maps an alert name to a safe file name.
+func fileName(alert string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, alert)
+	if strings.Trim(name, ".") == "" {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/sznuper/sznuper/internal/secret"
+)
+
+// redactHandler replaces secrets in the message and attribute values of
+// records before passing them on. Attributes added with WithAttrs are
+// redacted when a record is handled, so secrets registered after the logger
+// was built, e.g. by a reload, are hidden too.
+type redactHandler struct {
+	base slog.Handler // the wrapped handler, before ops
+	ops  []handlerOp  // WithAttrs and WithGroup calls, in order
+	h    slog.Handler // base with ops applied unredacted, used without secrets
+}
+
+// handlerOp is a WithGroup call, or a WithAttrs call if attrs is set.
+type handlerOp struct {
+	group string
+	attrs []slog.Attr
+}
+
+// Redact wraps h so that secrets never reach it. String values are
+// redacted directly; other values, such as errors, are formatted and
+// replaced by their redacted text only if they contain a secret.
+func Redact(h slog.Handler) slog.Handler {
+	return redactHandler{base: h, h: h}
+}
+
+func (h redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !secret.Active() {
+		return h.h.Handle(ctx, r)
+	}
+	out := slog.NewRecord(r.Time, r.Level, secret.Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.redacted().Handle(ctx, out)
+}
+
+// redacted applies the recorded ops to the wrapped handler, with the
+// attributes redacted against the secrets registered now.
+func (h redactHandler) redacted() slog.Handler {
+	out := h.base
+	for _, op := range h.ops {
+		if op.attrs == nil {
+			out = out.WithGroup(op.group)
+			continue
+		}
+		red := make([]slog.Attr, len(op.attrs))
+		for i, a := range op.attrs {
+			red[i] = redactAttr(a)
+		}
+		out = out.WithAttrs(red)
+	}
+	return out
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return redactHandler{
+		base: h.base,
+		ops:  append(slices.Clip(h.ops), handlerOp{attrs: slices.Clone(attrs)}),
+		h:    h.h.WithAttrs(attrs),
+	}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return redactHandler{
+		base: h.base,
+		ops:  append(slices.Clip(h.ops), handlerOp{group: name}),
+		h:    h.h.WithGroup(name),
+	}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, secret.Redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		red := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			red[i] = redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(red...)}
+	case slog.KindAny:
+		if s := fmt.Sprint(v.Any()); secret.Contains(s) {
+			return slog.String(a.Key, secret.Redact(s))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/sznuper/sznuper/internal/secret"
+)
+
+func TestRedact(t *testing.T) {
+	secret.Register("tok-9f8e7d")
+	var buf bytes.Buffer
+	l := slog.New(Redact(slog.NewTextHandler(&buf, nil))).With("url", "slack://tok-9f8e7d@x")
+	l.WithGroup("notify").Error("sending to tok-9f8e7d failed",
+		"error", errors.New("401 for tok-9f8e7d"),
+		slog.Group("channel", "name", "slack", "token", "tok-9f8e7d"),
+		"attempts", 3,
+	)
+	got := buf.String()
+	if strings.Contains(got, "tok-9f8e7d") {
+		t.Fatalf("secret leaked: %s", got)
+	}
+	for _, want := range []string{
+		`msg="sending to [REDACTED] failed"`,
+		`url=slack://[REDACTED]@x`,
+		`notify.error="401 for [REDACTED]"`,
+		`notify.channel.name=slack notify.channel.token=[REDACTED]`,
+		`notify.attempts=3`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output %q does not contain %q", got, want)
+		}
+	}
+}
+
+func TestRedact_SecretRegisteredLater(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(Redact(slog.NewJSONHandler(&buf, nil))).With("url", "slack://late-5c4b3a@x").WithGroup("run").With("key", "late-5c4b3a")
+
+	// E.g. a reload that reads a new secret file.
+	secret.Register("late-5c4b3a")
+	l.Info("notifying")
+
+	got := buf.String()
+	if strings.Contains(got, "late-5c4b3a") {
+		t.Fatalf("secret leaked: %s", got)
+	}
+	for _, want := range []string{`"url":"slack://[REDACTED]@x"`, `"run":{"key":"[REDACTED]"}`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output %q does not contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+// Default rotation for file sinks without a rotate section.
+const (
+	DefaultMaxSize = 10 << 20
+	DefaultKeep    = 5
+)
+
+// rotatedLayout is the timestamp suffix of rotated files. It sorts in
+// chronological order.
+const rotatedLayout = "2006-01-02T15-04-05.000"
+
+// Rotation is a compiled config.LogRotate. Zero fields disable that limit.
+type Rotation struct {
+	MaxSize int64         // rotate before the file grows past this size
+	Every   time.Duration // rotate when a write falls in a new period
+	Keep    int           // rotated files to keep
+	MaxAge  time.Duration // remove rotated files older than this
+}
+
+// compileRotation parses rc. A nil rc is the default rotation.
+func compileRotation(rc *config.LogRotate) (Rotation, error) {
+	if rc == nil {
+		return Rotation{MaxSize: DefaultMaxSize, Keep: DefaultKeep}, nil
+	}
+	rot := Rotation{Keep: rc.Keep}
+	var err error
+	if rc.MaxSize != "" {
+		if rot.MaxSize, err = config.ParseSize(rc.MaxSize); err != nil {
+			return rot, fmt.Errorf("rotate.max_size: %w", err)
+		}
+	}
+	if rc.Every != "" {
+		if rot.Every, err = time.ParseDuration(rc.Every); err != nil || rot.Every <= 0 {
+			return rot, fmt.Errorf("rotate.every: invalid duration %q", rc.Every)
+		}
+	}
+	if rc.MaxAge != "" {
+		if rot.MaxAge, err = time.ParseDuration(rc.MaxAge); err != nil || rot.MaxAge <= 0 {
+			return rot, fmt.Errorf("rotate.max_age: invalid duration %q", rc.MaxAge)
+		}
+	}
+	return rot, nil
+}
+
+// File is a log file that rotates by size or time. Rotated files are
+// renamed to <path>.<timestamp> and pruned by count and age.
+type File struct {
+	path string
+	rot  Rotation
+	now  func() time.Time
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	period time.Time // start of the current Every period
+}
+
+// OpenFile opens or creates the log file at path, creating its directory.
+func OpenFile(path string, rot Rotation) (*File, error) {
+	w := &File{path: path, rot: rot, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+	if err := w.open(w.now()); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *File) open(now time.Time) error {
+	f, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	w.f, w.size = f, info.Size()
+	// An existing file belongs to the period of its last write, so a
+	// restart in a later period still rotates it.
+	started := now
+	if w.size > 0 {
+		started = info.ModTime()
+	}
+	w.period = w.truncate(started)
+	return nil
+}
+
+func (w *File) truncate(t time.Time) time.Time {
+	if w.rot.Every <= 0 {
+		return time.Time{}
+	}
+	return t.Truncate(w.rot.Every)
+}
+
+// Write appends p, rotating first if p would pass the size limit or falls
+// in a new period.
+func (w *File) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	now := w.now()
+	if w.size > 0 && (w.rot.MaxSize > 0 && w.size+int64(len(p)) > w.rot.MaxSize ||
+		w.rot.Every > 0 && !w.truncate(now).Equal(w.period)) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *File) rotate(now time.Time) error {
+	_ = w.f.Close()
+	w.f = nil
+	name := w.path + "." + now.UTC().Format(rotatedLayout)
+	for i := 1; exists(name); i++ {
+		name = fmt.Sprintf("%s.%s.%d", w.path, now.UTC().Format(rotatedLayout), i)
+	}
+	if err := os.Rename(w.path, name); err != nil {
+		return fmt.Errorf("rotating log file: %w", err)
+	}
+	if err := w.open(now); err != nil {
+		return err
+	}
+	w.prune(now)
+	return nil
+}
+
+// prune removes rotated files beyond Keep or older than MaxAge. Errors are
+// ignored: a file that can't be removed is retried on the next rotation.
+func (w *File) prune(now time.Time) {
+	rotated := w.rotated()
+	for i, name := range rotated {
+		tooMany := w.rot.Keep > 0 && len(rotated)-i > w.rot.Keep
+		tooOld := false
+		if w.rot.MaxAge > 0 {
+			ts := strings.TrimPrefix(name, w.path+".")
+			if t, err := time.Parse(rotatedLayout, ts[:min(len(ts), len(rotatedLayout))]); err == nil {
+				tooOld = now.Sub(t) > w.rot.MaxAge
+			}
+		}
+		if tooMany || tooOld {
+			_ = os.Remove(name)
+		}
+	}
+}
+
+// rotated returns the rotated files of w, oldest first.
+func (w *File) rotated() []string {
+	entries, _ := os.ReadDir(filepath.Dir(w.path))
+	prefix := filepath.Base(w.path) + "."
+	var out []string
+	for _, e := range entries {
+		ts, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || len(ts) < len(rotatedLayout) {
+			continue
+		}
+		if _, err := time.Parse(rotatedLayout, ts[:len(rotatedLayout)]); err == nil {
+			out = append(out, filepath.Join(filepath.Dir(w.path), e.Name()))
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Close closes the file.
+func (w *File) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+// clock returns a now func that advances by step on every call.
+func clock(start time.Time, step time.Duration) func() time.Time {
+	t := start
+	return func() time.Time {
+		now := t
+		t = t.Add(step)
+		return now
+	}
+}
+
+func openTestFile(t *testing.T, path string, rot Rotation, now func() time.Time) *File {
+	t.Helper()
+	w := &File{path: path, rot: rot, now: now}
+	if err := w.open(now()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+	return w
+}
+
+func TestFile_RotateBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sznuper.log")
+	w := openTestFile(t, path, Rotation{MaxSize: 100, Keep: 2}, clock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), time.Second))
+
+	line := strings.Repeat("x", 39) + "\n"
+	for range 10 {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rotated := w.rotated()
+	if len(rotated) != 2 {
+		t.Fatalf("rotated = %v, want 2 files kept", rotated)
+	}
+	for _, p := range append(rotated, path) {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 100 {
+			t.Errorf("%s is %d bytes, want at most 100", p, info.Size())
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if got := strings.Count(string(data), "\n"); got != 2 {
+		t.Errorf("current file has %d lines, want 2", got)
+	}
+}
+
+func TestFile_RotateByTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sznuper.log")
+	w := openTestFile(t, path, Rotation{Every: time.Hour}, clock(time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC), 20*time.Minute))
+
+	// Opened at 12:30, writes at 12:50, 13:10, 13:30, 13:50, 14:10, 14:30.
+	for range 6 {
+		if _, err := w.Write([]byte("line\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(w.rotated()); got != 2 {
+		t.Errorf("rotated %d files, want 2 (one per hour passed)", got)
+	}
+}
+
+func TestFile_PruneByAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sznuper.log")
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	old := path + "." + now.Add(-72*time.Hour).Format(rotatedLayout)
+	recent := path + "." + now.Add(-time.Hour).Format(rotatedLayout)
+	other := filepath.Join(dir, "sznuper.log.keep-me")
+	for _, p := range []string{old, recent, other} {
+		if err := os.WriteFile(p, []byte("x\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := openTestFile(t, path, Rotation{MaxSize: 4, MaxAge: 48 * time.Hour}, func() time.Time { return now })
+	for range 2 {
+		if _, err := w.Write([]byte("abc\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if exists(old) {
+		t.Error("rotated file older than max_age was kept")
+	}
+	if !exists(recent) || !exists(other) {
+		t.Error("recent rotated file or unrelated file was removed")
+	}
+}
+
+func TestCompileRotation(t *testing.T) {
+	rot, err := compileRotation(nil)
+	if err != nil || rot.MaxSize != DefaultMaxSize || rot.Keep != DefaultKeep {
+		t.Errorf("default = %+v, %v", rot, err)
+	}
+
+	rot, err = compileRotation(&config.LogRotate{MaxSize: "1MB", Every: "24h", Keep: 7, MaxAge: "168h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Rotation{MaxSize: 1 << 20, Every: 24 * time.Hour, Keep: 7, MaxAge: 168 * time.Hour}
+	if rot != want {
+		t.Errorf("rotation = %+v, want %+v", rot, want)
+	}
+
+	for _, rc := range []config.LogRotate{{MaxSize: "big"}, {Every: "daily"}, {MaxAge: "-1h"}} {
+		if _, err := compileRotation(&rc); err == nil {
+			t.Errorf("compileRotation(%+v): expected error", rc)
+		}
+	}
+}
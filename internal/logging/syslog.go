@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"log/syslog"
+	"net/url"
+	"strings"
+)
+
+// syslogHandler sends each record as one syslog message with a severity
+// matching its level. Records are formatted by a text or JSON handler
+// without the time, which syslog adds itself.
+type syslogHandler struct {
+	w      *syslog.Writer
+	format string
+	level  slog.Level
+	ops    []func(slog.Handler) slog.Handler // WithAttrs and WithGroup calls, replayed per record
+}
+
+// newSyslogHandler dials address: empty for the local syslog daemon, a
+// socket path, or a udp:// or tcp:// host:port.
+func newSyslogHandler(address, tag, format string, level slog.Level) (*syslogHandler, error) {
+	network, addr := "", ""
+	switch {
+	case address == "":
+	case strings.HasPrefix(address, "/"):
+		network, addr = "unixgram", address
+	default:
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" {
+			return nil, fmt.Errorf("invalid syslog address %q: want a socket path, udp://host:port or tcp://host:port", address)
+		}
+		network, addr = u.Scheme, u.Host
+	}
+	w, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to syslog: %w", err)
+	}
+	return &syslogHandler{w: w, format: format, level: level}, nil
+}
+
+func (h *syslogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	inner := newHandler(&buf, h.format, h.level, dropTime)
+	for _, op := range h.ops {
+		inner = op(inner)
+	}
+	if err := inner.Handle(ctx, r); err != nil {
+		return err
+	}
+	msg := strings.TrimSuffix(buf.String(), "\n")
+	switch {
+	case r.Level >= slog.LevelError:
+		return h.w.Err(msg)
+	case r.Level >= slog.LevelWarn:
+		return h.w.Warning(msg)
+	case r.Level >= slog.LevelInfo:
+		return h.w.Info(msg)
+	default:
+		return h.w.Debug(msg)
+	}
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithAttrs(attrs) })
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithGroup(name) })
+}
+
+func (h *syslogHandler) with(op func(slog.Handler) slog.Handler) *syslogHandler {
+	h2 := *h
+	h2.ops = append(h.ops[:len(h.ops):len(h.ops)], op)
+	return &h2
+}
+
+func (h *syslogHandler) Close() error {
+	return h.w.Close()
+}
+
+func dropTime(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+	return a
+}
@@ -0,0 +1,164 @@
+// Package metrics exposes daemon metrics in Prometheus text format.
+//
+// All methods are safe on a nil *Metrics, so callers record unconditionally
+// and metrics are simply dropped when no listener is configured.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Notification outcomes for NotificationOutcome.
+const (
+	OutcomeSent         = "sent"
+	OutcomeFailed       = "failed"
+	OutcomeSuppressed   = "suppressed"
+	OutcomeSilenced     = "silenced"
+	OutcomeInhibited    = "inhibited"
+	OutcomeAcknowledged = "acknowledged"
+)
+
+// durationBuckets are healthcheck duration histogram bounds in seconds.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Metrics holds the daemon's metric families.
+type Metrics struct {
+	reg registry
+
+	runs          *family
+	errors        *family
+	duration      *family
+	notifications *family
+	healthy       *family
+	restarts      *family
+}
+
+// New creates an empty metrics set.
+func New() *Metrics {
+	m := &Metrics{}
+	m.runs = m.reg.add("sznuper_runs_total",
+		"Healthcheck runs by alert and trigger type.",
+		kindCounter, []string{"alert", "trigger"}, nil)
+	m.errors = m.reg.add("sznuper_run_errors_total",
+		"Failed alert runs by alert and pipeline stage.",
+		kindCounter, []string{"alert", "stage"}, nil)
+	m.duration = m.reg.add("sznuper_healthcheck_duration_seconds",
+		"Healthcheck execution time.",
+		kindHistogram, []string{"alert"}, durationBuckets)
+	m.notifications = m.reg.add("sznuper_notifications_total",
+		"Notifications by alert, channel and outcome (sent, failed, suppressed, silenced, inhibited).",
+		kindCounter, []string{"alert", "channel", "outcome"}, nil)
+	m.healthy = m.reg.add("sznuper_alert_healthy",
+		"Current alert state: 1 healthy, 0 unhealthy. Keyed alerts report one series per entity.",
+		kindGauge, []string{"alert", "key"}, nil)
+	m.restarts = m.reg.add("sznuper_trigger_restarts_total",
+		"Pipe command restarts and watched file reopens, by alert and trigger type.",
+		kindCounter, []string{"alert", "trigger"}, nil)
+	return m
+}
+
+// RunStarted counts one healthcheck invocation.
+func (m *Metrics) RunStarted(alert, trigger string) {
+	if m == nil {
+		return
+	}
+	if trigger == "" {
+		trigger = "manual"
+	}
+	m.runs.add(1, alert, trigger)
+}
+
+// RunFailed counts a run that failed at stage.
+func (m *Metrics) RunFailed(alert, stage string) {
+	if m == nil {
+		return
+	}
+	m.errors.add(1, alert, stage)
+}
+
+// ObserveDuration records how long a healthcheck took to execute.
+func (m *Metrics) ObserveDuration(alert string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.duration.observe(d.Seconds(), alert)
+}
+
+// NotificationOutcome counts one notification for a channel.
+func (m *Metrics) NotificationOutcome(alert, channel, outcome string) {
+	if m == nil {
+		return
+	}
+	m.notifications.add(1, alert, channel, outcome)
+}
+
+// SetHealthy records the current state of an alert, or of one entity of a
+// keyed alert.
+func (m *Metrics) SetHealthy(alert, key string, healthy bool) {
+	if m == nil {
+		return
+	}
+	v := 0.0
+	if healthy {
+		v = 1
+	}
+	m.healthy.set(v, alert, key)
+}
+
+// TriggerRestarted counts a pipe command restart or watched file reopen.
+func (m *Metrics) TriggerRestarted(alert, trigger string) {
+	if m == nil {
+		return
+	}
+	m.restarts.add(1, alert, trigger)
+}
+
+// Prune drops state gauges for alerts not in names, so alerts removed by a
+// reload stop being reported. Counters are kept.
+func (m *Metrics) Prune(names []string) {
+	if m == nil {
+		return
+	}
+	keep := make(map[string]bool, len(names))
+	for _, n := range names {
+		keep[n] = true
+	}
+	m.healthy.deleteIf(func(labels []string) bool { return !keep[labels[0]] })
+}
+
+// ServeHTTP writes all metrics in Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.reg.write(w)
+}
+
+// Serve listens on addr and serves /metrics until ctx is done. The listener
+// is bound before Serve returns so address errors surface at startup.
+func (m *Metrics) Serve(ctx context.Context, addr string, logger *slog.Logger) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics server failed", "error", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	logger.Info("metrics listening", "addr", ln.Addr().String())
+	return nil
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("content type = %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func TestMetrics_Text(t *testing.T) {
+	m := New()
+	m.RunStarted("disk", "interval")
+	m.RunStarted("disk", "interval")
+	m.RunStarted("disk", "")
+	m.RunFailed("disk", "exec")
+	m.NotificationOutcome("disk", "telegram", OutcomeSent)
+	m.SetHealthy("disk", "", false)
+	m.SetHealthy("disk", "", true)
+	m.TriggerRestarted("journal", "pipe")
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		"# HELP sznuper_runs_total ",
+		"# TYPE sznuper_runs_total counter",
+		`sznuper_runs_total{alert="disk",trigger="interval"} 2`,
+		`sznuper_runs_total{alert="disk",trigger="manual"} 1`,
+		`sznuper_run_errors_total{alert="disk",stage="exec"} 1`,
+		`sznuper_notifications_total{alert="disk",channel="telegram",outcome="sent"} 1`,
+		"# TYPE sznuper_alert_healthy gauge",
+		`sznuper_alert_healthy{alert="disk",key=""} 1`,
+		`sznuper_trigger_restarts_total{alert="journal",trigger="pipe"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestMetrics_Histogram(t *testing.T) {
+	m := New()
+	m.ObserveDuration("disk", 20*time.Millisecond)
+	m.ObserveDuration("disk", 3*time.Second)
+	m.ObserveDuration("disk", 2*time.Minute)
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		"# TYPE sznuper_healthcheck_duration_seconds histogram",
+		`sznuper_healthcheck_duration_seconds_bucket{alert="disk",le="0.01"} 0`,
+		`sznuper_healthcheck_duration_seconds_bucket{alert="disk",le="0.025"} 1`,
+		`sznuper_healthcheck_duration_seconds_bucket{alert="disk",le="5"} 2`,
+		`sznuper_healthcheck_duration_seconds_bucket{alert="disk",le="60"} 2`,
+		`sznuper_healthcheck_duration_seconds_bucket{alert="disk",le="+Inf"} 3`,
+		`sznuper_healthcheck_duration_seconds_sum{alert="disk"} 123.02`,
+		`sznuper_healthcheck_duration_seconds_count{alert="disk"} 3`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestMetrics_LabelEscaping(t *testing.T) {
+	m := New()
+	m.SetHealthy("svc", "a\"b\\c\nd", true)
+	out := scrape(t, m)
+	if want := `sznuper_alert_healthy{alert="svc",key="a\"b\\c\nd"} 1`; !strings.Contains(out, want) {
+		t.Errorf("missing %q in:\n%s", want, out)
+	}
+}
+
+func TestMetrics_Prune(t *testing.T) {
+	m := New()
+	m.SetHealthy("kept", "", true)
+	m.SetHealthy("removed", "sda", false)
+	m.RunStarted("removed", "interval")
+	m.Prune([]string{"kept"})
+
+	out := scrape(t, m)
+	if !strings.Contains(out, `sznuper_alert_healthy{alert="kept",key=""} 1`) {
+		t.Errorf("kept gauge dropped:\n%s", out)
+	}
+	if strings.Contains(out, `sznuper_alert_healthy{alert="removed"`) {
+		t.Errorf("removed gauge still reported:\n%s", out)
+	}
+	if !strings.Contains(out, `sznuper_runs_total{alert="removed",trigger="interval"} 1`) {
+		t.Errorf("counter pruned:\n%s", out)
+	}
+}
+
+func TestMetrics_NilSafe(t *testing.T) {
+	var m *Metrics
+	m.RunStarted("a", "interval")
+	m.RunFailed("a", "exec")
+	m.ObserveDuration("a", time.Second)
+	m.NotificationOutcome("a", "c", OutcomeSent)
+	m.SetHealthy("a", "", true)
+	m.TriggerRestarted("a", "pipe")
+	m.Prune(nil)
+}
+
+func TestMetrics_Serve(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Grab a free port, then hand it to Serve.
+	ln := httptest.NewUnstartedServer(nil).Listener
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	m := New()
+	m.RunStarted("disk", "cron")
+	if err := m.Serve(ctx, addr, slog.New(slog.DiscardHandler)); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `sznuper_runs_total{alert="disk",trigger="cron"} 1`) {
+		t.Errorf("body:\n%s", body)
+	}
+
+	if err := m.Serve(ctx, "256.0.0.1:1", slog.New(slog.DiscardHandler)); err == nil {
+		t.Error("expected error for invalid address")
+	}
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FailedLogName is the failed-delivery log file name inside options.logs_dir.
+const FailedLogName = "failed.log"
+
+// FailedDelivery is one entry in the failed-delivery log.
+type FailedDelivery struct {
+	Time      time.Time `json:"time"`
+	Alert     string    `json:"alert"`
+	Digest    string    `json:"digest,omitempty"`
+	EventType string    `json:"event_type"`
+	Channel   string    `json:"channel"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error"`
+}
+
+var failedLogMu sync.Mutex
+
+// AppendFailedDelivery appends entry to the log at path as a single JSON
+// line, creating the file and its directory if needed.
+func AppendFailedDelivery(path string, entry FailedDelivery) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding failed delivery: %w", err)
+	}
+	line = append(line, '\n')
+
+	failedLogMu.Lock()
+	defer failedLogMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating logs directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening failed-delivery log: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("writing failed-delivery log: %w", err)
+	}
+	return f.Close()
+}
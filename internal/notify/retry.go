@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy controls how a failed delivery is retried.
+type RetryPolicy struct {
+	Attempts   int           // retries after the initial send (0 = no retries)
+	Backoff    time.Duration // delay before the first retry, doubled after each one
+	MaxBackoff time.Duration // upper bound for a single delay (0 = unbounded)
+}
+
+// SendFunc delivers a notification to a single target.
+type SendFunc func(Target) error
+
+// SendWithRetry delivers t via send, retrying failures with exponential
+// backoff according to p. It returns the number of attempts made and the
+// last error, or nil once a delivery succeeds. Waiting between attempts
+// stops early when ctx is cancelled.
+func SendWithRetry(ctx context.Context, t Target, p RetryPolicy, send SendFunc) (int, error) {
+	delay := p.Backoff
+	attempts := 0
+	for {
+		attempts++
+		err := send(t)
+		if err == nil || attempts > p.Attempts {
+			return attempts, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempts, err
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+			delay = p.MaxBackoff
+		}
+	}
+}
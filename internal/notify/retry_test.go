@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendWithRetry_SucceedsFirstTry(t *testing.T) {
+	calls := 0
+	attempts, err := SendWithRetry(context.Background(), Target{}, RetryPolicy{Attempts: 3}, func(Target) error {
+		calls++
+		return nil
+	})
+	if err != nil || attempts != 1 || calls != 1 {
+		t.Errorf("attempts=%d calls=%d err=%v, want 1/1/nil", attempts, calls, err)
+	}
+}
+
+func TestSendWithRetry_RetriesUntilSuccess(t *testing.T) {
+	calls := 0
+	attempts, err := SendWithRetry(context.Background(), Target{}, RetryPolicy{Attempts: 3, Backoff: time.Millisecond}, func(Target) error {
+		calls++
+		if calls < 3 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("attempts=%d err=%v, want 3/nil", attempts, err)
+	}
+}
+
+func TestSendWithRetry_GivesUpAfterAttempts(t *testing.T) {
+	calls := 0
+	attempts, err := SendWithRetry(context.Background(), Target{}, RetryPolicy{Attempts: 2, Backoff: time.Millisecond}, func(Target) error {
+		calls++
+		return errors.New("boom")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if attempts != 3 || calls != 3 {
+		t.Errorf("attempts=%d calls=%d, want 3", attempts, calls)
+	}
+}
+
+func TestSendWithRetry_NoRetries(t *testing.T) {
+	calls := 0
+	_, err := SendWithRetry(context.Background(), Target{}, RetryPolicy{}, func(Target) error {
+		calls++
+		return errors.New("boom")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("calls=%d err=%v, want 1 call and an error", calls, err)
+	}
+}
+
+func TestSendWithRetry_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	_, err := SendWithRetry(ctx, Target{}, RetryPolicy{Attempts: 5, Backoff: time.Hour}, func(Target) error {
+		calls++
+		return errors.New("boom")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("calls=%d err=%v, want 1 call and an error", calls, err)
+	}
+}
+
+func TestAppendFailedDelivery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", FailedLogName)
+	for _, ch := range []string{"telegram", "slack"} {
+		err := AppendFailedDelivery(path, FailedDelivery{
+			Time:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			Alert:     "disk",
+			EventType: "critical_usage",
+			Channel:   ch,
+			Attempts:  4,
+			Error:     "timeout",
+		})
+		if err != nil {
+			t.Fatalf("AppendFailedDelivery: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %d, want 2", len(lines))
+	}
+	var got FailedDelivery
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Channel != "slack" || got.Alert != "disk" || got.EventType != "critical_usage" || got.Error != "timeout" {
+		t.Errorf("entry = %+v", got)
+	}
+}
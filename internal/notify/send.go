@@ -6,6 +6,7 @@ import (
 
 	"github.com/nicholas-fedor/shoutrrr"
 	"github.com/nicholas-fedor/shoutrrr/pkg/router"
+	"github.com/sznuper/sznuper/internal/secret"
 )
 
 // Target holds a fully resolved notification target ready to send.
@@ -69,23 +70,25 @@ func ResolveTargets(
 
 // Validate builds the full URL and creates a Shoutrrr sender to verify
 // the channel configuration is valid, without actually sending anything.
+// Secrets are redacted from the error.
 func Validate(t Target) error {
 	_, err := buildSender(t)
-	return err
+	return secret.RedactError(err)
 }
 
 // Send delivers a notification to a single target via Shoutrrr.
 // Params are merged into the URL as query parameters before creating the sender.
+// Secrets are redacted from the error, which often quotes the URL.
 func Send(t Target) error {
 	sender, err := buildSender(t)
 	if err != nil {
-		return err
+		return secret.RedactError(err)
 	}
 
 	errs := sender.Send(t.Message, nil)
 	for _, e := range errs {
 		if e != nil {
-			return fmt.Errorf("sending to %s: %w", t.ChannelName, e)
+			return secret.RedactError(fmt.Errorf("sending to %s: %w", t.ChannelName, e))
 		}
 	}
 
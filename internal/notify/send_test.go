@@ -1,7 +1,10 @@
 package notify
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/sznuper/sznuper/internal/secret"
 )
 
 func TestResolveTargets_Basic(t *testing.T) {
@@ -148,3 +151,14 @@ func TestApplyParams_Empty(t *testing.T) {
 		t.Errorf("url = %q, want unchanged", got)
 	}
 }
+
+func TestValidate_RedactsSecrets(t *testing.T) {
+	secret.Register("s3cr3t-token")
+	err := Validate(Target{ChannelName: "telegram", URL: "telegram://s3cr3t-token%zz@telegram"})
+	if err == nil {
+		t.Fatal("expected error for invalid URL escape")
+	}
+	if strings.Contains(err.Error(), "s3cr3t-token") || !strings.Contains(err.Error(), secret.Redacted) {
+		t.Errorf("error = %q, want the token redacted", err)
+	}
+}
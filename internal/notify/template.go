@@ -14,6 +14,10 @@ type TemplateData struct {
 	Alert   map[string]string
 	Event   map[string]any
 	Args    map[string]string
+	State   map[string]any // alert state machine, see runner.StateView
+	// Group notifications only: the collected events and the group itself.
+	Events []map[string]any
+	Group  map[string]any
 }
 
 // BuildTemplateData constructs template data from event output and config.
@@ -42,7 +46,8 @@ func BuildTemplateData(globals map[string]any, alertName string, eventFields map
 }
 
 // Render executes a Go text/template string with Sprig functions and the
-// custom accessor functions (event, globals, alert, args).
+// custom accessor functions (event, globals, alert, args, state, events,
+// group).
 func Render(tmplStr string, data TemplateData) (string, error) {
 	funcMap := sprig.TxtFuncMap()
 
@@ -52,6 +57,9 @@ func Render(tmplStr string, data TemplateData) (string, error) {
 	funcMap["globals"] = func() map[string]any { return data.Globals }
 	funcMap["alert"] = func() map[string]string { return data.Alert }
 	funcMap["args"] = func() map[string]string { return data.Args }
+	funcMap["state"] = func() map[string]any { return data.State }
+	funcMap["events"] = func() []map[string]any { return data.Events }
+	funcMap["group"] = func() map[string]any { return data.Group }
 
 	t, err := template.New("notify").Funcs(funcMap).Parse(tmplStr)
 	if err != nil {
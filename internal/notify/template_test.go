@@ -91,3 +91,15 @@ func TestRender_DefaultSprigFunc(t *testing.T) {
 		t.Errorf("result = %q, want %q", result, "/")
 	}
 }
+
+func TestRender_StateAccess(t *testing.T) {
+	data := BuildTemplateData(nil, "cpu", map[string]string{"type": "high_usage"}, nil)
+	data.State = map[string]any{"consecutive_unhealthy": 3}
+	got, err := Render("{{event.type}} x{{state.consecutive_unhealthy}}", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "high_usage x3" {
+		t.Errorf("got %q, want %q", got, "high_usage x3")
+	}
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"strings"
+
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+// StateLookup gives read access to the runtime state of other alerts, for
+// depends_on. Both return values are nil for alerts without state.
+type StateLookup interface {
+	Lookup(alert string) (*AlertState, *EntitySet)
+}
+
+// inhibitor returns the dependency of alert that is currently unhealthy and
+// the parent entity key it matched on, if any.
+func (r *Runner) inhibitor(alert *config.Alert, fields map[string]string, states StateLookup) (parent, key string, ok bool) {
+	if states == nil {
+		return "", "", false
+	}
+	for _, dep := range alert.DependsOn {
+		// Config loading has checked that parents exist.
+		p := r.FindAlert(dep.Alert)
+		if p == nil {
+			continue
+		}
+		state, entities := states.Lookup(p.Name)
+
+		if entities != nil && isKeyed(p) {
+			if len(dep.Match) > 0 {
+				key, ok := parentEntityKey(p, dep.Match, fields)
+				if !ok {
+					continue
+				}
+				if e := entities.Lookup(key); e != nil && unhealthy(e.State) {
+					return p.Name, key, true
+				}
+				continue
+			}
+			// Without match, any unhealthy entity inhibits.
+			var found string
+			var hit bool
+			entities.Each(func(k string, e *Entity) {
+				if !hit && unhealthy(e.State) {
+					found, hit = k, true
+				}
+			})
+			if hit {
+				return p.Name, found, true
+			}
+			continue
+		}
+
+		if unhealthy(state) {
+			return p.Name, "", true
+		}
+	}
+	return "", "", false
+}
+
+// parentEntityKey builds the parent's entity key from this event's match
+// fields, mirroring entityKey. It fails when the event lacks a match field
+// or the field count differs from the parent's events.key.
+func parentEntityKey(parent *config.Alert, match config.FieldList, fields map[string]string) (string, bool) {
+	keys := parent.Events.Key
+	if len(match) != len(keys) {
+		return "", false
+	}
+	values := make([]string, len(match))
+	for i, f := range match {
+		v, ok := fields[strings.ToLower(f)]
+		if !ok {
+			return "", false
+		}
+		values[i] = v
+	}
+	if len(keys) == 1 {
+		return values[0], true
+	}
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + values[i]
+	}
+	return strings.Join(parts, " "), true
+}
+
+func unhealthy(s *AlertState) bool {
+	return s != nil && !s.View().Healthy
+}
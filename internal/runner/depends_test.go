@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/notify"
+)
+
+type fakeStates map[string]struct {
+	state    *AlertState
+	entities *EntitySet
+}
+
+func (f fakeStates) Lookup(alert string) (*AlertState, *EntitySet) {
+	s := f[alert]
+	return s.state, s.entities
+}
+
+func TestRunAlert_DependsOn(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\nprintf -- '--- event\\ntype=down\\nservice=nginx\\n'\n")
+
+	units := NewEntitySet()
+	units.Get("nginx", true).State.Healthy = false
+	units.Get("redis", true).State.Healthy = false
+	healthyUnits := NewEntitySet()
+	healthyUnits.Get("nginx", true)
+
+	tests := []struct {
+		name   string
+		deps   []config.Dependency
+		states fakeStates
+		want   string // inhibiting alert, "" = notified
+	}{
+		{
+			name:   "parent unhealthy",
+			deps:   []config.Dependency{{Alert: "uplink"}},
+			states: fakeStates{"uplink": {state: &AlertState{Healthy: false}}},
+			want:   "uplink",
+		},
+		{
+			name:   "parent healthy",
+			deps:   []config.Dependency{{Alert: "uplink"}},
+			states: fakeStates{"uplink": {state: &AlertState{Healthy: true}}},
+		},
+		{
+			name:   "parent without state",
+			deps:   []config.Dependency{{Alert: "uplink"}},
+			states: fakeStates{},
+		},
+		{
+			name:   "unknown parent",
+			deps:   []config.Dependency{{Alert: "missing"}},
+			states: fakeStates{"missing": {state: &AlertState{Healthy: false}}},
+		},
+		{
+			name:   "second dependency",
+			deps:   []config.Dependency{{Alert: "uplink"}, {Alert: "units"}},
+			states: fakeStates{"uplink": {state: &AlertState{Healthy: true}}, "units": {entities: units}},
+			want:   "units",
+		},
+		{
+			name:   "matching entity unhealthy",
+			deps:   []config.Dependency{{Alert: "units", Match: config.FieldList{"service"}}},
+			states: fakeStates{"units": {entities: units}},
+			want:   "units",
+		},
+		{
+			name:   "matching entity healthy",
+			deps:   []config.Dependency{{Alert: "units", Match: config.FieldList{"service"}}},
+			states: fakeStates{"units": {entities: healthyUnits}},
+		},
+		{
+			name:   "match field missing from event",
+			deps:   []config.Dependency{{Alert: "units", Match: config.FieldList{"unit"}}},
+			states: fakeStates{"units": {entities: units}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Options:  config.Options{HealthchecksDir: dir},
+				Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+				Alerts: []config.Alert{
+					{
+						Name:        "nginx_http",
+						Healthcheck: "file://check.sh",
+						Template:    "msg",
+						Notify:      []config.NotifyTarget{{Channel: "logger"}},
+						DependsOn:   tt.deps,
+					},
+					{Name: "uplink", Healthcheck: "builtin://ok", Template: "msg"},
+					{Name: "units", Healthcheck: "builtin://ok", Template: "msg", Events: &config.Events{Key: config.FieldList{"unit"}}},
+				},
+			}
+			r := New(cfg, slog.New(slog.DiscardHandler))
+			sent := 0
+			r.send = func(notify.Target) error {
+				sent++
+				return nil
+			}
+
+			res := <-r.RunAlertOpts(context.Background(), &cfg.Alerts[0], RunOpts{Dependencies: tt.states})
+			if res.Err != nil {
+				t.Fatalf("error at %s: %v", res.ErrStage, res.Err)
+			}
+			if res.InhibitedBy != tt.want || res.Inhibited != (tt.want != "") {
+				t.Errorf("inhibited = %v by %q, want %q", res.Inhibited, res.InhibitedBy, tt.want)
+			}
+			if wantSent := tt.want == ""; (sent == 1) != wantSent {
+				t.Errorf("sent = %d", sent)
+			}
+		})
+	}
+}
+
+func TestParentEntityKey(t *testing.T) {
+	parent := &config.Alert{Events: &config.Events{Key: config.FieldList{"host", "unit"}}}
+	key, ok := parentEntityKey(parent, config.FieldList{"Server", "service"}, map[string]string{"server": "web1", "service": "nginx"})
+	if !ok || key != "host=web1 unit=nginx" {
+		t.Errorf("key = %q, %v", key, ok)
+	}
+	if _, ok := parentEntityKey(parent, config.FieldList{"service"}, map[string]string{"service": "nginx"}); ok {
+		t.Error("expected mismatch for wrong field count")
+	}
+}
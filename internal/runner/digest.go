@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/group"
+	"github.com/sznuper/sznuper/internal/healthcheck"
+	"github.com/sznuper/sznuper/internal/notify"
+)
+
+// DefaultDigestTemplate renders a digest as its events' own messages, one
+// per line, each prefixed with its alert.
+const DefaultDigestTemplate = `{{join "\n" group.messages}}`
+
+// digestEventType is recorded as the event type of digest notifications.
+const digestEventType = "digest"
+
+// queueDigests collects an event for the digests that take its alert. tmpl
+// and data render the event's own message. Without a grouper there are no
+// digests.
+func (r *Runner) queueDigests(log *slog.Logger, alert *config.Alert, ev healthcheck.Event, tmpl string, data notify.TemplateData, dryRun bool) {
+	if r.grouper == nil || len(r.cfg.Digests) == 0 {
+		return
+	}
+	var item *group.Item
+	for _, name := range slices.Sorted(maps.Keys(r.cfg.Digests)) {
+		d := r.cfg.Digests[name]
+		if len(d.Alerts) > 0 && !slices.Contains(d.Alerts, alert.Name) {
+			continue
+		}
+		if item == nil {
+			msg, err := notify.Render(tmpl, data)
+			if err != nil {
+				log.Warn("rendering event for digest failed", "error", err)
+				return
+			}
+			item = &group.Item{
+				Time:      time.Now(),
+				Alert:     alert.Name,
+				EventType: ev.Type,
+				Event:     ev.TemplateValues(),
+				Message:   msg,
+			}
+		}
+		policy := group.NewPolicy(config.Group{Cron: d.Cron})
+		if r.grouper.Add(digestKey(name), policy, r.flushDigest(name, d, dryRun), *item) {
+			log.Debug("event queued for digest", "digest", name)
+		}
+	}
+}
+
+// flushDigest returns the function that sends digest name to its channels.
+func (r *Runner) flushDigest(name string, d config.Digest, dryRun bool) group.FlushFunc {
+	return func(ctx context.Context, items []group.Item) {
+		log := r.logger.With("digest", name, "events", len(items))
+		result := Result{Digest: name, EventType: digestEventType, DryRun: dryRun}
+		defer func() {
+			r.recordResult(result)
+			if r.onResult != nil {
+				r.onResult(ctx, result)
+			}
+		}()
+
+		tmpl := d.Template
+		if tmpl == "" {
+			tmpl = DefaultDigestTemplate
+		}
+		data := digestTemplateData(r.cfg.Globals, items)
+		targets, err := notify.ResolveTargets(mapNotifyRefs(d.Notify), mapChannelDefs(r.cfg.Channels), tmpl, data)
+		if err != nil {
+			result.Err = err
+			result.ErrStage = "template"
+			log.Error("digest template failed", "error", err)
+			return
+		}
+		r.sendBatch(ctx, log, nil, &result, targets, "digest")
+	}
+}
+
+// digestTemplateData builds the template data of a digest: like a group's,
+// with each event's alert in its "alert" value and the alerts involved in
+// group.alerts.
+func digestTemplateData(globals map[string]any, items []group.Item) notify.TemplateData {
+	last := items[len(items)-1]
+	data := notify.BuildTemplateData(globals, "", nil, nil)
+	data.Event = last.Event
+
+	data.Events = make([]map[string]any, len(items))
+	messages := make([]string, len(items))
+	seen := make(map[string]bool)
+	var alerts []string
+	for i, it := range items {
+		ev := make(map[string]any, len(it.Event)+1)
+		maps.Copy(ev, it.Event)
+		ev["alert"] = it.Alert
+		data.Events[i] = ev
+		messages[i] = it.Alert + ": " + it.Message
+		if !seen[it.Alert] {
+			seen[it.Alert] = true
+			alerts = append(alerts, it.Alert)
+		}
+	}
+	slices.Sort(alerts)
+	data.Group = map[string]any{
+		"count":    len(items),
+		"alerts":   alerts,
+		"messages": messages,
+		"first":    items[0].Time,
+		"last":     last.Time,
+	}
+	return data
+}
+
+// digestKey is the grouper key of a digest, set apart from the alert group
+// keys by its prefix.
+func digestKey(name string) string {
+	return "digest:" + name
+}
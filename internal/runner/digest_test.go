@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/group"
+	"github.com/sznuper/sznuper/internal/notify"
+)
+
+func TestRunAlert_Digest(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\nprintf -- '--- event\\ntype=failure\\nuser=root\\n'\n")
+
+	cfg := &config.Config{
+		Options: config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{
+			"telegram": {URL: "logger://"},
+			"mail":     {URL: "logger://"},
+		},
+		Digests: map[string]config.Digest{
+			"daily": {Cron: "@daily", Notify: []config.NotifyTarget{{Channel: "mail"}}},
+			"ssh_only": {
+				Cron:     "@daily",
+				Alerts:   []string{"ssh"},
+				Notify:   []config.NotifyTarget{{Channel: "mail"}},
+				Template: `{{group.count}} from {{join "," group.alerts}}:{{range events}} {{.alert}}/{{.user}}{{end}}`,
+			},
+		},
+		Alerts: []config.Alert{
+			{
+				Name:        "ssh",
+				Healthcheck: "file://check.sh",
+				Template:    "failure for {{event.user}}",
+				Notify:      []config.NotifyTarget{{Channel: "telegram"}},
+			},
+			{
+				Name:        "sudo",
+				Healthcheck: "file://check.sh",
+				Template:    "sudo by {{event.user}}",
+			},
+		},
+	}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	g := group.New()
+	r.SetGrouper(g)
+	var mu sync.Mutex
+	sent := map[string][]string{}
+	r.send = func(t notify.Target) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent[t.ChannelName] = append(sent[t.ChannelName], t.Message)
+		return nil
+	}
+	var hooked []Result
+	r.SetResultHook(func(_ context.Context, res Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		hooked = append(hooked, res)
+	})
+
+	for i := range cfg.Alerts {
+		for res := range r.RunAlertOpts(context.Background(), &cfg.Alerts[i], RunOpts{}) {
+			if res.Err != nil {
+				t.Fatalf("%s: unexpected error: %v", res.AlertName, res.Err)
+			}
+		}
+	}
+	if len(sent["telegram"]) != 1 || len(sent["mail"]) != 0 {
+		t.Fatalf("sent before flush = %v, want 1 telegram and no mail messages", sent)
+	}
+
+	g.Close(context.Background())
+	want := map[string]bool{
+		"ssh: failure for root\nsudo: sudo by root": true,
+		"1 from ssh: ssh/root":                      true,
+	}
+	if len(sent["mail"]) != 2 {
+		t.Fatalf("mail messages = %q, want 2", sent["mail"])
+	}
+	for _, msg := range sent["mail"] {
+		if !want[msg] {
+			t.Errorf("unexpected digest %q", msg)
+		}
+	}
+	if len(hooked) != 2 {
+		t.Fatalf("hooked results = %d, want 2", len(hooked))
+	}
+	for _, res := range hooked {
+		if res.Digest == "" || res.AlertName != "" || res.EventType != digestEventType || len(res.Notified) != 1 {
+			t.Errorf("digest result = %+v", res)
+		}
+	}
+}
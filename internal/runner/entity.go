@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/cooldown"
+)
+
+// Entity is the state machine and cooldown for one distinct events.key value.
+type Entity struct {
+	State    *AlertState // nil when events.healthy is not configured
+	Cooldown *cooldown.State
+
+	lastUsed time.Time // by Get, see EntitySet.Prune
+}
+
+// idle reports whether the entity is indistinguishable from a fresh one.
+func (e *Entity) idle() bool {
+	return (e.State == nil || e.State.idle()) && len(e.Cooldown.Snapshot()) == 0
+}
+
+// EntitySet holds independent state per entity for alerts with events.key,
+// so one unit recovering does not reset cooldowns of another that is still
+// down. It is safe for concurrent use.
+type EntitySet struct {
+	mu       sync.Mutex
+	entities map[string]*Entity
+}
+
+// NewEntitySet creates an empty EntitySet.
+func NewEntitySet() *EntitySet {
+	return &EntitySet{entities: make(map[string]*Entity)}
+}
+
+// Get returns the entity for key, creating it on first use. tracked reports
+// whether the alert has a state machine (events.healthy); it attaches or
+// detaches the entity's AlertState to match.
+func (s *EntitySet) Get(key string, tracked bool) *Entity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entities[key]
+	if !ok {
+		e = &Entity{Cooldown: cooldown.New(nil)}
+		s.entities[key] = e
+	}
+	e.lastUsed = time.Now()
+	switch {
+	case tracked && e.State == nil:
+		e.State = &AlertState{Healthy: true}
+	case !tracked:
+		e.State = nil
+	}
+	return e
+}
+
+// Lookup returns the entity for key without creating it.
+func (s *EntitySet) Lookup(key string) *Entity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entities[key]
+}
+
+// Put stores e under key, replacing any existing entity. Used to restore
+// persisted state.
+func (s *EntitySet) Put(key string, e *Entity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entities[key] = e
+}
+
+// Each calls fn for every entity that differs from a freshly created one,
+// in key order. Idle entities are skipped so persisted state does not grow
+// with every key ever seen.
+func (s *EntitySet) Each(fn func(key string, e *Entity)) {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.entities))
+	for k := range s.entities {
+		keys = append(keys, k)
+	}
+	entities := make(map[string]*Entity, len(s.entities))
+	for k, e := range s.entities {
+		entities[k] = e
+	}
+	s.mu.Unlock()
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		e := entities[k]
+		if e.idle() {
+			continue
+		}
+		fn(k, e)
+	}
+}
+
+// Prune drops the idle entities that Get last returned before cutoff, so
+// alerts keyed on open-ended values such as users or IP addresses don't
+// grow without bound. The cutoff leaves runs that are still processing an
+// entity time to finish with it. It returns the number of entities dropped.
+func (s *EntitySet) Prune(cutoff time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for k, e := range s.entities {
+		if e.lastUsed.Before(cutoff) && e.idle() {
+			delete(s.entities, k)
+			n++
+		}
+	}
+	return n
+}
+
+// entityKey derives the entity key for an event from the alert's events.key
+// fields. A single field yields its raw value; several fields yield
+// space-separated field=value pairs in config order. Missing fields count as
+// empty values.
+func entityKey(alert *config.Alert, fields map[string]string) string {
+	keys := alert.Events.Key
+	if len(keys) == 1 {
+		return fields[strings.ToLower(keys[0])]
+	}
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + fields[strings.ToLower(k)]
+	}
+	return strings.Join(parts, " ")
+}
+
+// isKeyed reports whether the alert tracks state per entity.
+func isKeyed(alert *config.Alert) bool {
+	return alert.Events != nil && len(alert.Events.Key) > 0
+}
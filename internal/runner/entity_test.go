@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+func TestEntityKey(t *testing.T) {
+	fields := map[string]string{"unit": "nginx", "host": "web-1"}
+	tests := []struct {
+		keys config.FieldList
+		want string
+	}{
+		{config.FieldList{"unit"}, "nginx"},
+		{config.FieldList{"Unit"}, "nginx"},
+		{config.FieldList{"host", "unit"}, "host=web-1 unit=nginx"},
+		{config.FieldList{"mount"}, ""},
+	}
+	for _, tt := range tests {
+		alert := &config.Alert{Events: &config.Events{Key: tt.keys}}
+		if got := entityKey(alert, fields); got != tt.want {
+			t.Errorf("entityKey(%v) = %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestEntitySet_EachSkipsIdle(t *testing.T) {
+	s := NewEntitySet()
+	s.Get("idle", true)
+	s.Get("down", true).State.Healthy = false
+
+	var keys []string
+	s.Each(func(key string, _ *Entity) { keys = append(keys, key) })
+	if len(keys) != 1 || keys[0] != "down" {
+		t.Errorf("keys = %v, want [down]", keys)
+	}
+}
+
+func TestEntitySet_Prune(t *testing.T) {
+	s := NewEntitySet()
+	s.Get("idle", true)
+	s.Get("down", true).State.Healthy = false
+	s.Get("acked", true).State.Ack = &Ack{By: "alice"}
+	s.Get("ranged", true).State.ThresholdType = "warning"
+	s.Get("cooling", false).Cooldown.Check("failure", time.Hour)
+
+	if n := s.Prune(time.Now().Add(-time.Minute)); n != 0 {
+		t.Errorf("Prune of recently used entities dropped %d, want 0", n)
+	}
+	if n := s.Prune(time.Now().Add(time.Minute)); n != 1 {
+		t.Errorf("Prune dropped %d, want 1", n)
+	}
+	if s.Lookup("idle") != nil {
+		t.Error("idle entity kept")
+	}
+	for _, key := range []string{"down", "acked", "ranged", "cooling"} {
+		if s.Lookup(key) == nil {
+			t.Errorf("entity %q dropped", key)
+		}
+	}
+}
+
+func TestRunAlert_PerEntityStateAndCooldown(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "second-run")
+	writeScript(t, dir, fmt.Sprintf(`#!/bin/sh
+if [ -f %[1]s ]; then
+  printf -- '--- event\ntype=ok\nunit=nginx\n--- event\ntype=failed\nunit=postgres\n'
+else
+  touch %[1]s
+  printf -- '--- event\ntype=failed\nunit=nginx\n--- event\ntype=failed\nunit=postgres\n'
+fi
+`, marker))
+
+	cfg := &config.Config{
+		Options:  config.Options{HealthchecksDir: dir},
+		Globals:  map[string]any{"hostname": "host"},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+		Alerts: []config.Alert{
+			{
+				Name:        "units",
+				Healthcheck: "file://check.sh",
+				Template:    "{{state.key}} {{event.type}}",
+				Cooldown:    "inf",
+				Notify:      []config.NotifyTarget{{Channel: "logger"}},
+				Events:      &config.Events{Healthy: []string{"ok"}, Key: config.FieldList{"unit"}},
+			},
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	r := New(cfg, logger)
+	opts := RunOpts{DryRun: true, Entities: NewEntitySet()}
+
+	collect := func() map[string]Result {
+		out := map[string]Result{}
+		for res := range r.RunAlertOpts(context.Background(), &cfg.Alerts[0], opts) {
+			if res.Err != nil {
+				t.Fatalf("unexpected error at stage %q: %v", res.ErrStage, res.Err)
+			}
+			out[res.EntityKey] = res
+		}
+		return out
+	}
+
+	first := collect()
+	for _, key := range []string{"nginx", "postgres"} {
+		if first[key].Suppressed || len(first[key].Notified) != 1 {
+			t.Errorf("first run %s: want notified independently, got %+v", key, first[key])
+		}
+	}
+	if got := first["nginx"].Rendered["logger"]; got != "nginx failed" {
+		t.Errorf("rendered = %q, want %q", got, "nginx failed")
+	}
+
+	second := collect()
+	if !second["nginx"].IsRecovery {
+		t.Error("nginx: want recovery")
+	}
+	if !second["postgres"].Suppressed {
+		t.Error("postgres: want still suppressed; nginx recovery must not reset its cooldown")
+	}
+}
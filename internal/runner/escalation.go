@@ -0,0 +1,317 @@
+package runner
+
+import (
+	"context"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/healthcheck"
+	"github.com/sznuper/sznuper/internal/notify"
+)
+
+// escalationPolicy is a compiled config.Escalation.
+type escalationPolicy struct {
+	name  string
+	steps []escalationStep
+}
+
+type escalationStep struct {
+	after  time.Duration // since the alert became unhealthy
+	repeat time.Duration // 0 = notify once
+	notify []config.NotifyTarget
+}
+
+// compileEscalation compiles an escalation config. Config loading has
+// checked the durations.
+func compileEscalation(name string, cfg config.Escalation) *escalationPolicy {
+	p := &escalationPolicy{name: name}
+	for _, st := range cfg.Steps {
+		step := escalationStep{notify: st.Notify}
+		if st.After != "" {
+			step.after, _ = time.ParseDuration(st.After)
+		}
+		if st.Repeat != "" {
+			step.repeat, _ = time.ParseDuration(st.Repeat)
+		}
+		p.steps = append(p.steps, step)
+	}
+	return p
+}
+
+// compileEscalations compiles every escalation policy of cfg, by name.
+func compileEscalations(cfg *config.Config) map[string]*escalationPolicy {
+	out := make(map[string]*escalationPolicy, len(cfg.Escalations))
+	for name, esc := range cfg.Escalations {
+		out[name] = compileEscalation(name, esc)
+	}
+	return out
+}
+
+// due returns how many steps are reached after being unhealthy for elapsed.
+func (p *escalationPolicy) due(elapsed time.Duration) int {
+	n := 0
+	for n < len(p.steps) && p.steps[n].after <= elapsed {
+		n++
+	}
+	return n
+}
+
+// next returns when the next step is reached or the current one repeats.
+func (p *escalationPolicy) next(level int, since, escalatedAt time.Time) (time.Time, bool) {
+	var at time.Time
+	ok := false
+	if level < len(p.steps) {
+		at, ok = since.Add(p.steps[level].after), true
+	}
+	if level > 0 {
+		if rep := p.steps[level-1].repeat; rep > 0 {
+			if t := escalatedAt.Add(rep); !ok || t.Before(at) {
+				at, ok = t, true
+			}
+		}
+	}
+	return at, ok
+}
+
+// reached returns the notify targets of the first level steps, each channel
+// once.
+func (p *escalationPolicy) reached(level int) []config.NotifyTarget {
+	var out []config.NotifyTarget
+	seen := make(map[string]bool)
+	for _, st := range p.steps[:min(level, len(p.steps))] {
+		for _, n := range st.notify {
+			if !seen[n.Channel] {
+				seen[n.Channel] = true
+				out = append(out, n)
+			}
+		}
+	}
+	return out
+}
+
+// escalationPolicy returns the policy called name, or nil if there is
+// none. Config loading has checked that the names alerts refer to exist.
+func (r *Runner) escalationPolicy(name string) *escalationPolicy {
+	if name == "" {
+		return nil
+	}
+	return r.escalations[name]
+}
+
+// escalationName returns the escalation policy name for an event: the
+// override's, else the alert's.
+func escalationName(alert *config.Alert, override *config.EventOverride) string {
+	if override != nil && override.Escalation != "" {
+		return override.Escalation
+	}
+	return alert.Escalation
+}
+
+// escalator sends the timed notifications of one AlertState's escalation.
+type escalator struct {
+	r      *Runner
+	ctx    context.Context // the scheduler's: a reload or shutdown stops escalating
+	alert  *config.Alert
+	policy *escalationPolicy
+	state  *AlertState
+	key    string
+	opts   RunOpts
+}
+
+// escalate records an unhealthy event under policy and (re)arms the timer
+// for its next step. The steps already due are reached right away: at the
+// unhealthy transition, and when an event switches to another policy. It
+// returns the number of steps reached.
+func (e *escalator) escalate(ev healthcheck.Event, now time.Time) int {
+	s := e.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.UnhealthySince.IsZero() {
+		s.UnhealthySince = now
+	}
+	if s.Escalation != e.policy.name {
+		s.Escalation = e.policy.name
+		s.EscalationLevel = e.policy.due(now.Sub(s.UnhealthySince))
+		s.EscalatedAt = now
+	}
+	s.escEvent = ev
+	s.EscalationFields = ev.Fields
+	if s.Ack == nil {
+		e.armLocked()
+	}
+	return s.EscalationLevel
+}
+
+// ResumeEscalations re-arms the escalation timers of alert's restored
+// states, opts.State or those in opts.Entities, so an incident escalating
+// before a restart or reload reaches its next steps without waiting for
+// another unhealthy event. Like a run's, they stop when ctx is done.
+func (r *Runner) ResumeEscalations(ctx context.Context, alert *config.Alert, opts RunOpts) {
+	resume := func(key string, s *AlertState) {
+		if s == nil {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.Healthy || s.Ack != nil {
+			return
+		}
+		// A policy removed by a reload is replaced at the next event.
+		p := r.escalationPolicy(s.Escalation)
+		if p == nil {
+			return
+		}
+		if s.escEvent.Type == "" {
+			s.escEvent = healthcheck.Event{Type: s.UnhealthyType, Fields: s.EscalationFields}
+		}
+		e := &escalator{r: r, ctx: ctx, alert: alert, policy: p, state: s, key: key, opts: opts}
+		e.armLocked()
+	}
+	if isKeyed(alert) && opts.Entities != nil {
+		opts.Entities.Each(func(key string, e *Entity) { resume(key, e.State) })
+		return
+	}
+	resume("", opts.State)
+}
+
+// armLocked replaces any pending timer with one for the next step or repeat.
+func (e *escalator) armLocked() {
+	s := e.state
+	s.stopEscalationLocked()
+	at, ok := e.policy.next(s.EscalationLevel, s.UnhealthySince, s.EscalatedAt)
+	if !ok {
+		return
+	}
+	gen := s.escGen
+	s.escTimer = time.AfterFunc(time.Until(at), func() { e.fire(gen) })
+}
+
+// fire reaches the next step, or repeats the current one, and notifies its
+// channels.
+func (e *escalator) fire(gen uint64) {
+	s := e.state
+	now := time.Now()
+	s.mu.Lock()
+	// A flapping alert notifies nothing; the event that ends the flapping
+	// re-arms the timer.
+	if gen != s.escGen || s.Healthy || s.Ack != nil || s.Flapping || e.ctx.Err() != nil {
+		s.mu.Unlock()
+		return
+	}
+	repeat := true
+	if s.EscalationLevel < len(e.policy.steps) && !now.Before(s.UnhealthySince.Add(e.policy.steps[s.EscalationLevel].after)) {
+		s.EscalationLevel++
+		repeat = false
+	}
+	if s.EscalationLevel == 0 {
+		// Fired early, e.g. after a wall clock change.
+		e.armLocked()
+		s.mu.Unlock()
+		return
+	}
+	s.EscalatedAt = now
+	step := e.policy.steps[s.EscalationLevel-1]
+	ev := s.escEvent
+	view := s.viewLocked()
+	e.armLocked()
+	s.mu.Unlock()
+
+	e.notify(step, repeat, ev, view)
+}
+
+// notify sends an escalation notification for the latest unhealthy event to
+// one step's channels. Silences and dependencies apply as for events;
+// cooldown does not.
+func (e *escalator) notify(step escalationStep, repeat bool, ev healthcheck.Event, view StateView) {
+	r, alert := e.r, e.alert
+	log := r.logger.With("alert", alert.Name, "escalation", e.policy.name, "level", view.EscalationLevel)
+	if e.key != "" {
+		log = log.With("key", e.key)
+	}
+	result := Result{
+		AlertName:      alert.Name,
+		HealthcheckURI: alert.Healthcheck,
+		EventType:      ev.Type,
+		EntityKey:      e.key,
+		Fields:         ev.Fields,
+		DryRun:         e.opts.DryRun,
+	}
+	defer func() {
+		r.recordResult(result)
+		if r.onResult != nil {
+			r.onResult(e.ctx, result)
+		}
+	}()
+
+	suppress := func() {
+		for _, n := range step.notify {
+			result.SuppressedChannels = append(result.SuppressedChannels, n.Channel)
+		}
+	}
+	if parent, _, ok := r.inhibitor(alert, ev.Fields, e.opts.Dependencies); ok {
+		log.Info("escalation inhibited by dependency", "parent", parent)
+		result.Inhibited, result.InhibitedBy = true, parent
+		suppress()
+		return
+	}
+	if sil, ok := r.silences.Match(alert.Name, ev.Type, ev.Fields, time.Now()); ok {
+		log.Info("escalation silenced", "silence", sil.ID)
+		result.Silenced, result.SilencedBy = true, sil.ID
+		suppress()
+		return
+	}
+
+	tmpl := alert.Template
+	if alert.Events != nil {
+		if o, ok := alert.Events.Override[ev.Type]; ok && o.Template != "" && r.overrideApplies(log, alert, &o, ev) {
+			tmpl = o.Template
+		}
+	}
+	data := notify.BuildTemplateData(r.cfg.Globals, alert.Name, ev.Fields, alert.Args)
+	data.Event = ev.TemplateValues()
+	view.Key = e.key
+	data.State = view.templateData()
+
+	targets, err := notify.ResolveTargets(mapNotifyRefs(step.notify), mapChannelDefs(r.cfg.Channels), tmpl, data)
+	if err != nil {
+		result.Err = err
+		result.ErrStage = "template"
+		log.Error("escalation template failed", "error", err)
+		return
+	}
+	if e.opts.DryRun {
+		for _, t := range targets {
+			if err := notify.Validate(t); err != nil {
+				result.Err = err
+				result.ErrStage = "notify"
+				log.Error("notify validation failed (dry-run)", "channel", t.ChannelName, "error", err)
+				return
+			}
+			result.Notified = append(result.Notified, t.ChannelName)
+		}
+		log.Info("would send escalation notification (dry-run)", "repeat", repeat, "channels", result.Notified)
+		return
+	}
+	r.deliver(e.ctx, log, alert, &result, targets)
+	log.Info("escalation notification sent", "repeat", repeat, "channels", result.Notified)
+}
+
+// stopEscalationLocked cancels the pending escalation timer, if any.
+func (s *AlertState) stopEscalationLocked() {
+	s.escGen++
+	if s.escTimer != nil {
+		s.escTimer.Stop()
+		s.escTimer = nil
+	}
+}
+
+// resetEscalationLocked ends the escalation of a recovered incident.
+func (s *AlertState) resetEscalationLocked() {
+	s.stopEscalationLocked()
+	s.UnhealthySince = time.Time{}
+	s.Escalation = ""
+	s.EscalationLevel = 0
+	s.EscalatedAt = time.Time{}
+	s.EscalationFields = nil
+	s.escEvent = healthcheck.Event{}
+}
@@ -0,0 +1,286 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/cooldown"
+	"github.com/sznuper/sznuper/internal/notify"
+)
+
+func TestCompileEscalation(t *testing.T) {
+	cfg := config.Escalation{Steps: []config.EscalationStep{
+		{Notify: []config.NotifyTarget{{Channel: "slack"}}},
+		{After: "15m", Notify: []config.NotifyTarget{{Channel: "telegram"}, {Channel: "slack"}}, Repeat: "10m"},
+		{After: "1h", Notify: []config.NotifyTarget{{Channel: "email"}}},
+	}}
+	p := compileEscalation("oncall", cfg)
+
+	for elapsed, want := range map[time.Duration]int{0: 1, 14 * time.Minute: 1, 15 * time.Minute: 2, 2 * time.Hour: 3} {
+		if got := p.due(elapsed); got != want {
+			t.Errorf("due(%s) = %d, want %d", elapsed, got, want)
+		}
+	}
+
+	since := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		level int
+		at    time.Time
+		want  time.Time
+	}{
+		{1, since, since.Add(15 * time.Minute)},
+		{2, since.Add(15 * time.Minute), since.Add(25 * time.Minute)}, // repeat before step 3
+		{2, since.Add(55 * time.Minute), since.Add(time.Hour)},        // step 3 before the repeat
+	}
+	for _, c := range cases {
+		if got, ok := p.next(c.level, since, c.at); !ok || !got.Equal(c.want) {
+			t.Errorf("next(%d, %v) = %v, %v; want %v", c.level, c.at, got, ok, c.want)
+		}
+	}
+	if _, ok := p.next(3, since, since.Add(time.Hour)); ok {
+		t.Error("next after the last step without repeat = ok, want none")
+	}
+
+	var channels []string
+	for _, n := range p.reached(2) {
+		channels = append(channels, n.Channel)
+	}
+	if !slices.Equal(channels, []string{"slack", "telegram"}) {
+		t.Errorf("reached(2) = %v, want [slack telegram]", channels)
+	}
+
+}
+
+func TestRunAlert_Escalation(t *testing.T) {
+	dir := t.TempDir()
+	typeFile := filepath.Join(dir, "type")
+	writeScript(t, dir, fmt.Sprintf("#!/bin/sh\nprintf -- '--- event\\ntype=%%s\\n' \"$(cat %s)\"\n", typeFile))
+	setType := func(typ string) {
+		if err := os.WriteFile(typeFile, []byte(typ), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{
+		Options: config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{
+			"slack":    {URL: "logger://"},
+			"telegram": {URL: "logger://"},
+			"email":    {URL: "logger://"},
+		},
+		Escalations: map[string]config.Escalation{
+			"oncall": {Steps: []config.EscalationStep{
+				{Notify: []config.NotifyTarget{{Channel: "slack"}}},
+				{After: "50ms", Notify: []config.NotifyTarget{{Channel: "telegram"}}},
+				{After: "1h", Notify: []config.NotifyTarget{{Channel: "email"}}},
+			}},
+		},
+		Alerts: []config.Alert{
+			{
+				Name:        "uplink",
+				Healthcheck: "file://check.sh",
+				Template:    "{{event.type}} level={{state.escalation_level}}",
+				Cooldown:    "inf",
+				Events:      &config.Events{Healthy: []string{"ok"}},
+				Notify:      []config.NotifyTarget{{Channel: "slack"}},
+				Escalation:  "oncall",
+			},
+		},
+	}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	var mu sync.Mutex
+	var sent []string
+	telegram := make(chan struct{}, 1)
+	r.send = func(t notify.Target) error {
+		mu.Lock()
+		sent = append(sent, t.ChannelName+": "+t.Message)
+		mu.Unlock()
+		if t.ChannelName == "telegram" {
+			telegram <- struct{}{}
+		}
+		return nil
+	}
+	state := &AlertState{Healthy: true}
+	opts := RunOpts{State: state, Cooldown: cooldown.New(nil)}
+	run := func() Result {
+		t.Helper()
+		var res Result
+		for res = range r.RunAlertOpts(context.Background(), &cfg.Alerts[0], opts) {
+		}
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		return res
+	}
+
+	setType("down")
+	if res := run(); !slices.Equal(res.Notified, []string{"slack"}) {
+		t.Fatalf("first notification went to %v, want [slack]", res.Notified)
+	}
+
+	select {
+	case <-telegram:
+	case <-time.After(2 * time.Second):
+		t.Fatal("escalation to telegram never happened")
+	}
+	if v := state.View(); v.EscalationLevel != 2 || v.Escalation != "oncall" {
+		t.Errorf("state = %+v, want oncall level 2", v)
+	}
+
+	setType("ok")
+	res := run()
+	if !res.IsRecovery || !slices.Equal(res.Notified, []string{"slack", "telegram"}) {
+		t.Errorf("recovery = %v, notified %v; want recovery to [slack telegram]", res.IsRecovery, res.Notified)
+	}
+	if v := state.View(); v.EscalationLevel != 0 || v.Escalation != "" {
+		t.Errorf("state after recovery = %+v, want escalation reset", v)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"slack: down level=1", "telegram: down level=2"}
+	if len(sent) < 2 || !slices.Equal(sent[:2], want) {
+		t.Errorf("sent = %q, want %q first", sent, want)
+	}
+	if !slices.Contains(sent, "telegram: ok level=2") {
+		t.Errorf("sent = %q, want the recovery on telegram with the reached level", sent)
+	}
+}
+
+func TestResumeEscalations(t *testing.T) {
+	cfg := &config.Config{
+		Channels: map[string]config.Channel{
+			"slack":    {URL: "logger://"},
+			"telegram": {URL: "logger://"},
+		},
+		Escalations: map[string]config.Escalation{
+			"oncall": {Steps: []config.EscalationStep{
+				{Notify: []config.NotifyTarget{{Channel: "slack"}}},
+				{After: "1m", Notify: []config.NotifyTarget{{Channel: "telegram"}}},
+			}},
+		},
+		Alerts: []config.Alert{
+			{
+				Name:        "uplink",
+				Healthcheck: "file://check.sh",
+				Template:    "{{event.type}} on {{event.iface}} level={{state.escalation_level}}",
+				Events:      &config.Events{Healthy: []string{"ok"}},
+				Escalation:  "oncall",
+			},
+		},
+	}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	sent := make(chan string, 1)
+	r.send = func(t notify.Target) error {
+		sent <- t.ChannelName + ": " + t.Message
+		return nil
+	}
+
+	// State saved by a daemon that stopped while step 2 was pending; it came
+	// due in the meantime.
+	since := time.Now().Add(-2 * time.Minute)
+	saved, err := json.Marshal(map[string]any{
+		"healthy":           false,
+		"unhealthy_since":   since,
+		"escalation":        "oncall",
+		"escalation_level":  1,
+		"escalated_at":      since,
+		"escalation_fields": map[string]string{"type": "down", "iface": "eth0"},
+		"unhealthy_type":    "down",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &AlertState{}
+	if err := json.Unmarshal(saved, state); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.ResumeEscalations(ctx, &cfg.Alerts[0], RunOpts{State: state})
+
+	select {
+	case got := <-sent:
+		if want := "telegram: down on eth0 level=2"; got != want {
+			t.Errorf("sent %q, want %q", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("restored escalation never reached step 2")
+	}
+	if v := state.View(); v.EscalationLevel != 2 {
+		t.Errorf("level = %d, want 2", v.EscalationLevel)
+	}
+}
+
+func TestRunAlert_EscalationWhileFlapping(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\nprintf -- '--- event\\ntype=down\\n'\n")
+
+	cfg := &config.Config{
+		Options: config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{
+			"slack":    {URL: "logger://"},
+			"telegram": {URL: "logger://"},
+		},
+		Escalations: map[string]config.Escalation{
+			"oncall": {Steps: []config.EscalationStep{
+				{Notify: []config.NotifyTarget{{Channel: "slack"}}},
+				{After: "20ms", Notify: []config.NotifyTarget{{Channel: "telegram"}}},
+			}},
+		},
+		Alerts: []config.Alert{
+			{
+				Name:        "uplink",
+				Healthcheck: "file://check.sh",
+				Template:    "{{event.type}}",
+				Events: &config.Events{
+					Healthy: []string{"ok"},
+					Flap:    &config.Flap{Window: "1h", Threshold: 3},
+				},
+				Notify:     []config.NotifyTarget{{Channel: "slack"}},
+				Escalation: "oncall",
+			},
+		},
+	}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	var mu sync.Mutex
+	var sent []string
+	r.send = func(t notify.Target) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, t.ChannelName)
+		return nil
+	}
+
+	now := time.Now()
+	state := &AlertState{Healthy: true, Flapping: true, Transitions: []time.Time{now, now, now}}
+	var res Result
+	for res = range r.RunAlertOpts(context.Background(), &cfg.Alerts[0], RunOpts{State: state, Cooldown: cooldown.New(nil)}) {
+	}
+	if !res.Flapping || len(res.Notified) != 0 {
+		t.Fatalf("result = flapping %v, notified %v; want a suppressed flapping event", res.Flapping, res.Notified)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if v := state.View(); v.Escalation != "" || v.EscalationLevel != 0 {
+		t.Errorf("state = %+v, want no escalation while flapping", v)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 0 {
+		t.Errorf("sent = %v, want nothing while flapping", sent)
+	}
+}
@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"log/slog"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/expr"
+	"github.com/sznuper/sznuper/internal/healthcheck"
+)
+
+// Drop reasons reported on results.
+const (
+	DropOnUnmatched = "on_unmatched"
+	DropIf          = "drop_if"
+	DropKeepIf      = "keep_if"
+)
+
+// filterEvent returns the reason the alert's drop_if or keep_if discards ev,
+// or "" to keep it. An expression that fails to evaluate is logged and
+// keeps the event.
+func (r *Runner) filterEvent(log *slog.Logger, alert *config.Alert, ev healthcheck.Event) string {
+	if alert.DropIf == nil && alert.KeepIf == nil {
+		return ""
+	}
+	env := r.exprEnv(alert, ev)
+	if alert.DropIf != nil {
+		if drop, ok := evalExpr(log, "drop_if", alert.DropIf, env); ok && drop {
+			return DropIf
+		}
+	}
+	if alert.KeepIf != nil {
+		if keep, ok := evalExpr(log, "keep_if", alert.KeepIf, env); ok && !keep {
+			return DropKeepIf
+		}
+	}
+	return ""
+}
+
+// overrideApplies reports whether the override matches ev: it has no when
+// condition, or the condition holds. A failing condition does not match.
+func (r *Runner) overrideApplies(log *slog.Logger, alert *config.Alert, o *config.EventOverride, ev healthcheck.Event) bool {
+	if o.When == nil {
+		return true
+	}
+	match, ok := evalExpr(log, "override "+ev.Type+" when", o.When, r.exprEnv(alert, ev))
+	return ok && match
+}
+
+// exprEnv returns the values rule expressions are evaluated against.
+func (r *Runner) exprEnv(alert *config.Alert, ev healthcheck.Event) expr.Env {
+	return expr.Env{Event: ev.TemplateValues(), Args: alert.Args, Globals: r.cfg.Globals}
+}
+
+func evalExpr(log *slog.Logger, where string, e *config.Expr, env expr.Env) (result, ok bool) {
+	result, err := e.Eval(env)
+	if err != nil {
+		log.Warn("rule expression failed", "rule", where, "error", err)
+		return false, false
+	}
+	return result, true
+}
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/cooldown"
+	"github.com/sznuper/sznuper/internal/notify"
+)
+
+func TestRunAlert_Filters(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\n"+
+		"printf -- '--- event\\ntype=login\\nuser=deploy\\nip=10.0.0.5\\n'\n"+
+		"printf -- '--- event\\ntype=login\\nuser=root\\nip=192.0.2.7\\n'\n"+
+		"printf -- '--- event\\ntype=login\\nuser=alice\\nip=192.0.2.8\\n'\n"+
+		"printf -- '--- event\\ntype=heartbeat\\n'\n"+
+		"printf -- '--- event\\ntype=failed\\nattempts=3\\n'\n"+
+		"printf -- '--- event\\ntype=failed\\nattempts=12\\n'\n")
+
+	var alert config.Alert
+	if err := yaml.Unmarshal([]byte(`
+name: ssh
+healthcheck: file://check.sh
+template: "{{event.type}} {{event.user}}"
+notify: [logger]
+args:
+  bastions: ["10.0.0.5"]
+drop_if: in_cidr(event.ip, "10.0.0.0/8") and event.ip in args.bastions
+keep_if: event.type != "heartbeat"
+events:
+  on_unmatched: drop
+  override:
+    login:
+      when: event.user == "root"
+      template: "root login from {{event.ip}}"
+    failed:
+      when: event.attempts >= 10
+`), &alert); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Options:  config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+		Alerts:   []config.Alert{alert},
+	}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	var sent []string
+	r.send = func(t notify.Target) error {
+		sent = append(sent, t.Message)
+		return nil
+	}
+	var results []Result
+	for res := range r.RunAlertOpts(context.Background(), &cfg.Alerts[0], RunOpts{Cooldown: cooldown.New(nil)}) {
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		results = append(results, res)
+	}
+
+	want := []string{DropIf, "", DropOnUnmatched, DropKeepIf, DropOnUnmatched, ""}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, res := range results {
+		if res.DropReason != want[i] || res.Dropped != (want[i] != "") {
+			t.Errorf("event %d (%s): dropped = %v %q, want %q", i, res.EventType, res.Dropped, res.DropReason, want[i])
+		}
+	}
+	if len(sent) != 2 || sent[0] != "root login from 192.0.2.7" || sent[1] != "failed <no value>" {
+		t.Errorf("sent = %q", sent)
+	}
+}
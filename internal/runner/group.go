@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/group"
+	"github.com/sznuper/sznuper/internal/healthcheck"
+	"github.com/sznuper/sznuper/internal/notify"
+)
+
+// DefaultGroupTemplate renders a group as its events' own messages, one per
+// line.
+const DefaultGroupTemplate = `{{join "\n" group.messages}}`
+
+// groupEventType is recorded as the event type of group notifications,
+// e.g. in the failed-delivery log.
+const groupEventType = "group"
+
+// groupConfig returns the group config for a notify target: its own, else
+// the alert's. Nil means the target is notified per event.
+func groupConfig(alert *config.Alert, n config.NotifyTarget) *config.Group {
+	if n.Group != nil {
+		return n.Group
+	}
+	return alert.Group
+}
+
+// queueGrouped hands the targets with a group config to the grouper and
+// returns the rest, which are notified right away. Without a grouper every
+// target is notified right away.
+func (r *Runner) queueGrouped(log *slog.Logger, alert *config.Alert, result *Result, notifyList []config.NotifyTarget, targets []notify.Target, ev healthcheck.Event) []notify.Target {
+	if r.grouper == nil {
+		return targets
+	}
+	var immediate []notify.Target
+	for i, t := range targets {
+		cfg := groupConfig(alert, notifyList[i])
+		if cfg == nil {
+			immediate = append(immediate, t)
+			continue
+		}
+		policy := group.NewPolicy(*cfg)
+		fields := groupFields(cfg.By, ev.Fields)
+		key := groupKey(alert.Name, t.ChannelName, cfg.By, fields)
+		item := group.Item{
+			Time:      time.Now(),
+			Alert:     alert.Name,
+			EventType: ev.Type,
+			Event:     ev.TemplateValues(),
+			Message:   t.Message,
+		}
+		if !r.grouper.Add(key, policy, r.flushGroup(alert, notifyList[i], *cfg, fields, result.DryRun), item) {
+			immediate = append(immediate, t)
+			continue
+		}
+		result.Grouped = append(result.Grouped, t.ChannelName)
+		log.Debug("notification queued for group", "channel", t.ChannelName, "group", key)
+	}
+	return immediate
+}
+
+// flushGroup returns the function that sends one group notification to
+// target's channel.
+func (r *Runner) flushGroup(alert *config.Alert, target config.NotifyTarget, cfg config.Group, fields map[string]string, dryRun bool) group.FlushFunc {
+	return func(ctx context.Context, items []group.Item) {
+		log := r.logger.With("alert", alert.Name, "channel", target.Channel, "events", len(items))
+		result := Result{
+			AlertName:      alert.Name,
+			HealthcheckURI: alert.Healthcheck,
+			EventType:      groupEventType,
+			Fields:         fields,
+			DryRun:         dryRun,
+		}
+		defer func() {
+			r.recordResult(result)
+			if r.onResult != nil {
+				r.onResult(ctx, result)
+			}
+		}()
+
+		tmpl := cfg.Template
+		if tmpl == "" {
+			tmpl = DefaultGroupTemplate
+		}
+		data := groupTemplateData(r.cfg.Globals, alert, fields, items)
+		targets, err := notify.ResolveTargets(mapNotifyRefs([]config.NotifyTarget{target}), mapChannelDefs(r.cfg.Channels), tmpl, data)
+		if err != nil {
+			result.Err = err
+			result.ErrStage = "template"
+			log.Error("group template failed", "error", err)
+			return
+		}
+		r.sendBatch(ctx, log, alert, &result, targets, "group")
+	}
+}
+
+// sendBatch sends a group or digest notification, or validates its targets
+// in dry-run mode. kind names the notification in the log.
+func (r *Runner) sendBatch(ctx context.Context, log *slog.Logger, alert *config.Alert, result *Result, targets []notify.Target, kind string) {
+	if result.DryRun {
+		for _, t := range targets {
+			if err := notify.Validate(t); err != nil {
+				result.Err = err
+				result.ErrStage = "notify"
+				log.Error("notify validation failed (dry-run)", "error", err)
+				return
+			}
+			log.Info("would send "+kind+" notification (dry-run)", "message", t.Message)
+		}
+		return
+	}
+	r.deliver(ctx, log, alert, result, targets)
+	if result.Err == nil {
+		log.Info(kind + " notification sent")
+	}
+}
+
+// groupTemplateData exposes a group to templates: events lists the
+// collected events, event is the latest one, and group holds count, fields
+// (the group-by values), messages (the events' own rendered messages), and
+// the first and last event times.
+func groupTemplateData(globals map[string]any, alert *config.Alert, fields map[string]string, items []group.Item) notify.TemplateData {
+	last := items[len(items)-1]
+	data := notify.BuildTemplateData(globals, alert.Name, nil, alert.Args)
+	data.Event = last.Event
+
+	data.Events = make([]map[string]any, len(items))
+	messages := make([]string, len(items))
+	for i, it := range items {
+		data.Events[i] = it.Event
+		messages[i] = it.Message
+	}
+	groupFields := make(map[string]any, len(fields))
+	for k, v := range fields {
+		groupFields[k] = v
+	}
+	data.Group = map[string]any{
+		"count":    len(items),
+		"fields":   groupFields,
+		"messages": messages,
+		"first":    items[0].Time,
+		"last":     last.Time,
+	}
+	return data
+}
+
+// groupFields returns the values of the group-by fields; missing fields
+// are empty.
+func groupFields(by config.FieldList, fields map[string]string) map[string]string {
+	out := make(map[string]string, len(by))
+	for _, f := range by {
+		out[f] = fields[strings.ToLower(f)]
+	}
+	return out
+}
+
+// groupKey identifies a group by alert, channel and group-by values.
+func groupKey(alert, channel string, by config.FieldList, fields map[string]string) string {
+	parts := []string{alert, channel}
+	for _, f := range by {
+		parts = append(parts, f+"="+fields[f])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/group"
+	"github.com/sznuper/sznuper/internal/notify"
+)
+
+func TestRunAlert_Grouped(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\nfor u in root admin root; do printf -- '--- event\\ntype=failure\\nuser=%s\\n' $u; done\n")
+
+	cfg := &config.Config{
+		Options: config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{
+			"telegram": {URL: "logger://"},
+			"logfile":  {URL: "logger://"},
+		},
+		Alerts: []config.Alert{
+			{
+				Name:        "ssh",
+				Healthcheck: "file://check.sh",
+				Template:    "failure for {{event.user}}",
+				Notify: []config.NotifyTarget{
+					{Channel: "logfile"},
+					{Channel: "telegram", Group: &config.Group{
+						By:       config.FieldList{"user"},
+						Wait:     "1h",
+						Template: `{{group.count}} failures for {{group.fields.user}}:{{range events}} {{.type}}{{end}}`,
+					}},
+				},
+			},
+		},
+	}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	g := group.New()
+	r.SetGrouper(g)
+	var mu sync.Mutex
+	sent := map[string][]string{}
+	r.send = func(t notify.Target) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent[t.ChannelName] = append(sent[t.ChannelName], t.Message)
+		return nil
+	}
+
+	var results []Result
+	for res := range r.RunAlertOpts(context.Background(), &cfg.Alerts[0], RunOpts{}) {
+		results = append(results, res)
+	}
+	if len(results) != 3 {
+		t.Fatalf("results = %d, want 3", len(results))
+	}
+	for _, res := range results {
+		if res.Err != nil {
+			t.Fatalf("unexpected error: %v", res.Err)
+		}
+		if len(res.Grouped) != 1 || res.Grouped[0] != "telegram" {
+			t.Errorf("grouped = %v, want [telegram]", res.Grouped)
+		}
+		if len(res.Notified) != 1 || res.Notified[0] != "logfile" {
+			t.Errorf("notified = %v, want [logfile]", res.Notified)
+		}
+	}
+	if len(sent["logfile"]) != 3 || len(sent["telegram"]) != 0 {
+		t.Fatalf("sent before flush = %v, want 3 logfile and no telegram messages", sent)
+	}
+
+	g.Close(context.Background())
+	want := map[string]bool{
+		"2 failures for root: failure failure": true,
+		"1 failures for admin: failure":        true,
+	}
+	if len(sent["telegram"]) != 2 {
+		t.Fatalf("telegram messages = %q, want 2", sent["telegram"])
+	}
+	for _, msg := range sent["telegram"] {
+		if !want[msg] {
+			t.Errorf("unexpected group message %q", msg)
+		}
+	}
+}
+
+func TestGroupTemplateDefault(t *testing.T) {
+	items := []group.Item{{Message: "one"}, {Message: "two"}}
+	data := groupTemplateData(nil, &config.Alert{Name: "a"}, nil, items)
+	got, err := notify.Render(DefaultGroupTemplate, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "one\ntwo" {
+		t.Errorf("default group template = %q, want %q", got, "one\ntwo")
+	}
+}
+
+func TestFlushGroup_ResultHook(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\nprintf -- '--- event\\ntype=failure\\nuser=root\\n'\n")
+
+	cfg := &config.Config{
+		Options:  config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{"telegram": {URL: "logger://"}},
+		Alerts: []config.Alert{
+			{
+				Name:        "ssh",
+				Healthcheck: "file://check.sh",
+				Template:    "failure for {{event.user}}",
+				Notify:      []config.NotifyTarget{{Channel: "telegram"}},
+				Group:       &config.Group{By: config.FieldList{"user"}, Wait: "1h"},
+			},
+		},
+	}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	g := group.New()
+	r.SetGrouper(g)
+	r.send = func(notify.Target) error { return nil }
+	var mu sync.Mutex
+	var hooked []Result
+	r.SetResultHook(func(_ context.Context, res Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		hooked = append(hooked, res)
+	})
+
+	for range r.RunAlertOpts(context.Background(), &cfg.Alerts[0], RunOpts{}) {
+	}
+	mu.Lock()
+	if len(hooked) != 0 {
+		t.Errorf("hook got %d results before the flush, want 0", len(hooked))
+	}
+	mu.Unlock()
+
+	g.Close(context.Background())
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hooked) != 1 {
+		t.Fatalf("hook got %d results, want 1", len(hooked))
+	}
+	res := hooked[0]
+	if res.AlertName != "ssh" || res.EventType != groupEventType || res.Fields["user"] != "root" {
+		t.Errorf("hooked result = %+v, want the ssh group flush for root", res)
+	}
+	if len(res.Notified) != 1 || res.Notified[0] != "telegram" {
+		t.Errorf("notified = %v, want [telegram]", res.Notified)
+	}
+}
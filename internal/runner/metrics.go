@@ -0,0 +1,45 @@
+package runner
+
+import "github.com/sznuper/sznuper/internal/metrics"
+
+// recordResult feeds a finished event result into the runner's metrics and
+// recorder. Dry runs count errors and state but not notifications, and are
+// not recorded.
+func (r *Runner) recordResult(res Result) {
+	if r.recorder != nil && !res.DryRun {
+		if err := r.recorder.Record(res); err != nil {
+			r.logger.Warn("recording event history failed", "alert", res.AlertName, "error", err)
+		}
+	}
+	m := r.metrics
+	if m == nil {
+		return
+	}
+	if res.Err != nil {
+		m.RunFailed(res.AlertName, res.ErrStage)
+	}
+	if res.Healthy != nil {
+		m.SetHealthy(res.AlertName, res.EntityKey, *res.Healthy)
+	}
+	if res.DryRun {
+		return
+	}
+	for _, ch := range res.Notified {
+		m.NotificationOutcome(res.AlertName, ch, metrics.OutcomeSent)
+	}
+	for _, ch := range res.Failed {
+		m.NotificationOutcome(res.AlertName, ch, metrics.OutcomeFailed)
+	}
+	outcome := metrics.OutcomeSuppressed
+	switch {
+	case res.Acknowledged:
+		outcome = metrics.OutcomeAcknowledged
+	case res.Inhibited:
+		outcome = metrics.OutcomeInhibited
+	case res.Silenced:
+		outcome = metrics.OutcomeSilenced
+	}
+	for _, ch := range res.SuppressedChannels {
+		m.NotificationOutcome(res.AlertName, ch, outcome)
+	}
+}
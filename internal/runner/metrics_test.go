@@ -0,0 +1,170 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/cooldown"
+	"github.com/sznuper/sznuper/internal/metrics"
+	"github.com/sznuper/sznuper/internal/notify"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestRunAlert_Metrics(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\nprintf -- '--- event\\ntype=critical_usage\\n'\n")
+
+	attempts := 0
+	cfg := &config.Config{
+		Options: config.Options{
+			HealthchecksDir: dir,
+			Retry:           &config.Retry{Attempts: &attempts},
+		},
+		Channels: map[string]config.Channel{
+			"good": {URL: "logger://"},
+			"bad":  {URL: "logger://"},
+		},
+		Alerts: []config.Alert{
+			{
+				Name:        "disk",
+				Healthcheck: "file://check.sh",
+				Template:    "msg",
+				Cooldown:    "1h",
+				Events:      &config.Events{Healthy: []string{"ok"}},
+				Notify:      []config.NotifyTarget{{Channel: "good"}, {Channel: "bad"}},
+			},
+		},
+	}
+
+	m := metrics.New()
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	r.SetMetrics(m)
+	r.send = func(t notify.Target) error {
+		if t.ChannelName == "bad" {
+			return errors.New("unreachable")
+		}
+		return nil
+	}
+
+	opts := RunOpts{
+		State:       &AlertState{Healthy: true},
+		Cooldown:    cooldown.New(time.Now),
+		TriggerType: "interval",
+	}
+	for range 2 {
+		for range r.RunAlertOpts(context.Background(), &cfg.Alerts[0], opts) {
+		}
+	}
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		`sznuper_runs_total{alert="disk",trigger="interval"} 2`,
+		`sznuper_run_errors_total{alert="disk",stage="notify"} 1`,
+		`sznuper_healthcheck_duration_seconds_count{alert="disk"} 2`,
+		`sznuper_notifications_total{alert="disk",channel="good",outcome="sent"} 1`,
+		`sznuper_notifications_total{alert="disk",channel="bad",outcome="failed"} 1`,
+		`sznuper_notifications_total{alert="disk",channel="good",outcome="suppressed"} 1`,
+		`sznuper_notifications_total{alert="disk",channel="bad",outcome="suppressed"} 1`,
+		`sznuper_alert_healthy{alert="disk",key=""} 0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunAlert_MetricsDryRun(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\nprintf -- '--- event\\ntype=ok\\n'\n")
+
+	cfg := &config.Config{
+		Options:  config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+		Alerts: []config.Alert{
+			{
+				Name:        "disk",
+				Healthcheck: "file://check.sh",
+				Template:    "msg",
+				Notify:      []config.NotifyTarget{{Channel: "logger"}},
+			},
+		},
+	}
+
+	m := metrics.New()
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	r.SetMetrics(m)
+	for range r.RunAlert(context.Background(), &cfg.Alerts[0], true, nil, nil) {
+	}
+
+	out := scrape(t, m)
+	if !strings.Contains(out, `sznuper_runs_total{alert="disk",trigger="manual"} 1`) {
+		t.Errorf("manual run not counted:\n%s", out)
+	}
+	if strings.Contains(out, "sznuper_notifications_total{") {
+		t.Errorf("dry run counted notifications:\n%s", out)
+	}
+}
+
+type recorderFunc func(Result) error
+
+func (f recorderFunc) Record(res Result) error { return f(res) }
+
+func TestRunAlert_Recorder(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\nprintf -- '--- event\\ntype=critical_usage\\n'\n")
+
+	cfg := &config.Config{
+		Options:  config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+		Alerts: []config.Alert{
+			{
+				Name:        "disk",
+				Healthcheck: "file://check.sh",
+				Template:    "msg",
+				Cooldown:    "1h",
+				Notify:      []config.NotifyTarget{{Channel: "logger"}},
+			},
+		},
+	}
+
+	var recorded []Result
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	r.SetRecorder(recorderFunc(func(res Result) error {
+		recorded = append(recorded, res)
+		return errors.New("disk full") // logged, never fails the run
+	}))
+	r.send = func(notify.Target) error { return nil }
+
+	opts := RunOpts{Cooldown: cooldown.New(time.Now)}
+	for range 2 {
+		for res := range r.RunAlertOpts(context.Background(), &cfg.Alerts[0], opts) {
+			if res.Err != nil {
+				t.Fatalf("unexpected error: %v", res.Err)
+			}
+		}
+	}
+	for range r.RunAlertOpts(context.Background(), &cfg.Alerts[0], RunOpts{DryRun: true}) {
+	}
+
+	if len(recorded) != 2 {
+		t.Fatalf("recorded %d results, want 2 (dry runs are not recorded)", len(recorded))
+	}
+	if got := recorded[0]; len(got.Notified) != 1 || got.Rendered["logger"] != "msg" {
+		t.Errorf("first = %+v, want notified with rendered message", got)
+	}
+	if !recorded[1].Suppressed {
+		t.Errorf("second = %+v, want suppressed", recorded[1])
+	}
+}
@@ -5,20 +5,40 @@ import "time"
 // Result captures the outcome of running a single alert event through the pipeline.
 type Result struct {
 	AlertName       string
+	Digest          string // digest a digest notification was sent for; AlertName is empty
 	HealthcheckURI  string
 	HealthcheckPath string
 	EventType       string            // the event's type field
+	EntityKey       string            // events.key value this event was tracked under
 	Fields          map[string]string // parsed scalar pairs
 	Rendered        map[string]string // channel name -> rendered message
 	Notified        []string          // channels notified (or would-notify)
+	Failed          []string          // channels whose delivery failed after all retries
+	Grouped         []string          // channels the event was queued for, see config.Group
 	Env             []string
 	DryRun          bool
-	Suppressed      bool // notification suppressed by cooldown
-	IsRecovery      bool // recovery notification (unhealthy->healthy)
-	Dropped         bool // event dropped by on_unmatched: drop
-	SideEffectsRun  int
-	Duration        time.Duration
-	Err             error
-	ErrStage        string // "resolve", "exec", "parse", "template", "notify"
-	Stderr          string
+	Suppressed      bool   // notification suppressed by cooldown
+	Silenced        bool   // notification withheld by a silence
+	SilencedBy      string // ID of the ad-hoc silence or name of the maintenance window
+	Inhibited       bool   // notification withheld because a depends_on alert is unhealthy
+	InhibitedBy     string // name of the inhibiting alert
+	Acknowledged    bool   // notification withheld because the incident is acknowledged
+	AcknowledgedBy  string // author of the acknowledgement
+	// Channels the notification would have gone to when Suppressed, Silenced, Inhibited or Acknowledged.
+	SuppressedChannels []string
+	IsRecovery         bool   // recovery notification (unhealthy->healthy)
+	Pending            bool   // transition waiting for its consecutive-event threshold
+	Flapping           bool   // alert is flapping; notifications are withheld unless FlapChange is set
+	FlapChange         string // "start" or "stop" when this event changed the flapping state
+	Dropped            bool   // event dropped by on_unmatched: drop, drop_if or keep_if
+	DropReason         string // DropOnUnmatched, DropIf or DropKeepIf
+	SideEffectsRun     int
+	// Consecutive event counters from the state machine (zero without events.healthy).
+	ConsecutiveUnhealthy int
+	ConsecutiveHealthy   int
+	Healthy              *bool // state after this event (nil without events.healthy)
+	Duration             time.Duration
+	Err                  error
+	ErrStage             string // "resolve", "exec", "parse", "template", "notify"
+	Stderr               string
 }
@@ -2,27 +2,107 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/sznuper/sznuper/internal/config"
 	"github.com/sznuper/sznuper/internal/cooldown"
+	"github.com/sznuper/sznuper/internal/group"
 	"github.com/sznuper/sznuper/internal/healthcheck"
+	"github.com/sznuper/sznuper/internal/metrics"
 	"github.com/sznuper/sznuper/internal/notify"
 	"github.com/sznuper/sznuper/internal/sideeffect"
+	"github.com/sznuper/sznuper/internal/silence"
 )
 
 // Runner orchestrates the healthcheck -> parse -> template -> notify pipeline.
 type Runner struct {
-	cfg    *config.Config
-	logger *slog.Logger
+	cfg      *config.Config
+	logger   *slog.Logger
+	send     notify.SendFunc
+	metrics  *metrics.Metrics
+	silences *silence.Set
+	grouper  *group.Grouper
+	recorder ResultRecorder
+	output   OutputLogger
+	onResult ResultHook
+
+	escalations map[string]*escalationPolicy // compiled cfg.Escalations
+	thresholds  map[string]*thresholdPolicy  // compiled alert thresholds, by alert name
 }
 
+// OutputLogger gives each alert a logger for its healthcheck's stdout and
+// stderr.
+type OutputLogger interface {
+	For(alert string) *slog.Logger
+}
+
+// ResultRecorder persists finished event results, e.g. the event history.
+type ResultRecorder interface {
+	Record(Result) error
+}
+
+// ResultHook receives the results a runner produces on its own, outside the
+// channel of the run that started them, with that run's context.
+type ResultHook func(context.Context, Result)
+
 // New creates a Runner with the given config and logger.
 func New(cfg *config.Config, logger *slog.Logger) *Runner {
-	return &Runner{cfg: cfg, logger: logger}
+	return &Runner{
+		cfg:         cfg,
+		logger:      logger,
+		send:        notify.Send,
+		escalations: compileEscalations(cfg),
+		thresholds:  compileAlertThresholds(cfg),
+	}
+}
+
+// SetMetrics makes the runner record runs, errors, durations, notifications
+// and alert state into m. A nil m disables recording.
+func (r *Runner) SetMetrics(m *metrics.Metrics) {
+	r.metrics = m
+}
+
+// SetSilences makes the runner withhold notifications and side effects for
+// events matched by s. A nil s silences nothing.
+func (r *Runner) SetSilences(s *silence.Set) {
+	r.silences = s
+}
+
+// SetGrouper makes the runner queue notifications for targets with a group
+// config in g, to be sent as one message per group. A nil g notifies every
+// event right away.
+func (r *Runner) SetGrouper(g *group.Grouper) {
+	r.grouper = g
+}
+
+// SetRecorder makes the runner hand every finished event result to rec. A
+// nil rec records nothing.
+func (r *Runner) SetRecorder(rec ResultRecorder) {
+	r.recorder = rec
+}
+
+// SetResultHook makes the runner pass the results of group and escalation
+// notifications, which are sent on timers after their run has finished, to
+// fn. A nil fn only records them to metrics and the recorder.
+func (r *Runner) SetResultHook(fn ResultHook) {
+	r.onResult = fn
+}
+
+// SetOutputLogger makes the runner log the output of every healthcheck
+// execution to o. A nil o leaves output out of the logs, apart from stderr
+// at debug level.
+func (r *Runner) SetOutputLogger(o OutputLogger) {
+	r.output = o
+}
+
+// Metrics returns the metrics set by SetMetrics, or nil.
+func (r *Runner) Metrics() *metrics.Metrics {
+	return r.metrics
 }
 
 // FindAlert returns the alert with the given name, or nil if not found.
@@ -56,17 +136,13 @@ func (r *Runner) RunAll(ctx context.Context, dryRun bool) <-chan Result {
 	return out
 }
 
-// AlertState tracks the healthy/unhealthy binary state for an alert.
-// Used only when events.healthy is configured.
-type AlertState struct {
-	Healthy bool
-}
-
 // RunOpts holds optional parameters for RunAlertOpts.
 type RunOpts struct {
 	DryRun        bool
 	Cooldown      *cooldown.State
 	State         *AlertState // state machine (nil = no state tracking)
+	Entities      *EntitySet  // per-entity state for alerts with events.key (nil = use State/Cooldown)
+	Dependencies  StateLookup // state of other alerts for depends_on (nil = never inhibited)
 	Stdin         []byte
 	TriggerType   string            // e.g. "interval", "cron", "watch", "pipe", "lifecycle"
 	BuiltinParams map[string]string // params for builtin:// healthchecks
@@ -100,9 +176,14 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 		DryRun:         dryRun,
 	}
 
-	sendErr := func(r Result) {
-		r.Duration = time.Since(start)
-		out <- r
+	r.metrics.RunStarted(alert.Name, opts.TriggerType)
+	emit := func(res Result) {
+		r.recordResult(res)
+		out <- res
+	}
+	sendErr := func(res Result) {
+		res.Duration = time.Since(start)
+		emit(res)
 	}
 
 	// Stage 1: Resolve healthcheck URI.
@@ -124,11 +205,15 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 
 	// Stage 2: Execute healthcheck.
 	var execResult *healthcheck.ExecResult
+	timeout, _ := time.ParseDuration(alert.Timeout)
 	if resolved.Scheme == "builtin" {
 		log.Info("executing builtin healthcheck", "name", resolved.Path)
-		execResult, err = healthcheck.ExecBuiltin(resolved.Path, opts.BuiltinParams)
+		execResult, err = healthcheck.ExecBuiltin(ctx, resolved.Path, healthcheck.BuiltinOpts{
+			Params:  opts.BuiltinParams,
+			Args:    alert.Args,
+			Timeout: timeout,
+		})
 	} else {
-		timeout, _ := time.ParseDuration(alert.Timeout)
 		log.Info("executing healthcheck", "path", resolved.Path, "timeout", timeout)
 		execResult, err = healthcheck.Exec(ctx, healthcheck.ExecOpts{
 			Path:        resolved.Path,
@@ -139,6 +224,12 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 			Stdin:       opts.Stdin,
 		})
 	}
+	if execResult != nil {
+		r.metrics.ObserveDuration(alert.Name, execResult.Duration)
+		if resolved.Scheme != "builtin" {
+			r.logOutput(alert, opts.TriggerType, execResult, err)
+		}
+	}
 	if err != nil {
 		base.Err = err
 		base.ErrStage = "exec"
@@ -155,7 +246,10 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 
 	// Stage 3: Parse events.
 	log.Info("parsing output")
-	events, err := healthcheck.ParseEvents(execResult.Stdout)
+	events := execResult.Events
+	if events == nil {
+		events, err = healthcheck.ParseOutput(execResult.Stdout, alert.Output)
+	}
 	if err != nil {
 		base.Err = err
 		base.ErrStage = "parse"
@@ -169,14 +263,43 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 
 	// Stage 4: Process each event.
 	for _, ev := range events {
+		log := log
 		result := base
 		result.EventType = ev.Type
 		result.Fields = ev.Fields
+		stateView := StateView{Healthy: true}
+
+		// drop_if and keep_if discard events before they reach any state.
+		if reason := r.filterEvent(log, alert, ev); reason != "" {
+			log.Info("event dropped by rule", "type", ev.Type, "rule", reason)
+			result.Dropped, result.DropReason = true, reason
+			result.Duration = time.Since(start)
+			emit(result)
+			continue
+		}
 
-		// a. Resolve config: find matching override or apply on_unmatched rule.
+		// Alerts with events.key get an independent state machine and
+		// cooldown per entity.
+		state, cd := opts.State, opts.Cooldown
+		if isKeyed(alert) && opts.Entities != nil {
+			key := entityKey(alert, ev.Fields)
+			e := opts.Entities.Get(key, len(alert.Events.Healthy) > 0)
+			state, cd = e.State, e.Cooldown
+			result.EntityKey = key
+			log = log.With("key", key)
+		}
+
+		// Thresholds derive the event type from a numeric field.
+		if alert.Thresholds != nil {
+			ev = r.applyThresholds(log, alert, state, ev)
+			result.EventType, result.Fields = ev.Type, ev.Fields
+		}
+
+		// a. Resolve config: find matching override or apply on_unmatched
+		// rule. An override whose when condition does not hold does not match.
 		var override *config.EventOverride
 		if alert.Events != nil {
-			if o, ok := alert.Events.Override[ev.Type]; ok {
+			if o, ok := alert.Events.Override[ev.Type]; ok && r.overrideApplies(log, alert, &o, ev) {
 				override = &o
 			}
 		}
@@ -185,36 +308,56 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 
 		// b. State machine.
 		skipNotify := false
-		if opts.State != nil {
-			isHealthyEv := isHealthyEvent(alert, ev.Type)
-			if isHealthyEv {
-				if opts.State.Healthy {
-					// healthy -> healthy: no notification
-					log.Info("healthy event in healthy state, skipping", "type", ev.Type)
-					skipNotify = true
-				} else {
-					// unhealthy -> healthy: recovery
-					opts.State.Healthy = true
-					result.IsRecovery = true
-					if opts.Cooldown != nil {
-						opts.Cooldown.ResetAll()
-					}
-					log.Info("recovery transition", "type", ev.Type)
-					if dropped {
-						log.Info("recovery event dropped by on_unmatched", "type", ev.Type)
-						skipNotify = true
-					}
+		flapChange := ""
+		if state != nil {
+			tr := state.advance(alert, override, ev.Type, time.Now())
+			result.IsRecovery = tr.recovery
+			result.Pending = tr.pending
+			result.ConsecutiveUnhealthy = tr.view.ConsecutiveUnhealthy
+			result.ConsecutiveHealthy = tr.view.ConsecutiveHealthy
+			result.Flapping = tr.view.Flapping
+			result.FlapChange = tr.view.FlapChange
+			healthy := tr.view.Healthy
+			result.Healthy = &healthy
+			flapChange = tr.view.FlapChange
+			stateView = tr.view
+			switch {
+			case tr.recovery:
+				if cd != nil {
+					cd.ResetAll()
 				}
-			} else {
-				// unhealthy event
-				if opts.State.Healthy {
-					opts.State.Healthy = false
-					log.Info("unhealthy transition", "type", ev.Type)
+				log.Info("recovery transition", "type", ev.Type)
+			case tr.unhealthy:
+				log.Info("unhealthy transition", "type", ev.Type)
+			case tr.pending:
+				log.Info("transition pending consecutive threshold", "type", ev.Type,
+					"consecutive_unhealthy", tr.view.ConsecutiveUnhealthy, "consecutive_healthy", tr.view.ConsecutiveHealthy)
+			}
+			if tr.ackCleared {
+				log.Info("acknowledgement cleared by new event type", "type", ev.Type)
+			}
+			switch flapChange {
+			case FlapStart:
+				log.Info("alert started flapping", "type", ev.Type, "transitions", tr.view.FlapTransitions)
+			case FlapStop:
+				log.Info("alert stopped flapping", "type", ev.Type, "transitions", tr.view.FlapTransitions)
+			}
+			switch {
+			case tr.flapped:
+				log.Info("notification suppressed while flapping", "type", ev.Type)
+				skipNotify = true
+			case !tr.notify:
+				if !tr.pending {
+					log.Info("healthy event in healthy state, skipping", "type", ev.Type)
 				}
-				if dropped {
+				skipNotify = true
+			case dropped && flapChange == "":
+				if tr.recovery {
+					log.Info("recovery event dropped by on_unmatched", "type", ev.Type)
+				} else {
 					log.Info("event dropped by on_unmatched", "type", ev.Type)
-					skipNotify = true
 				}
+				skipNotify = true
 			}
 		} else if dropped {
 			log.Info("event dropped by on_unmatched", "type", ev.Type)
@@ -222,20 +365,99 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 		}
 
 		if skipNotify {
-			result.Dropped = dropped
+			if dropped {
+				result.Dropped, result.DropReason = true, DropOnUnmatched
+			}
+			result.Duration = time.Since(start)
+			emit(result)
+			continue
+		}
+
+		// Escalation. While unhealthy, notifications go to the channels of
+		// the steps reached so far and a timer reaches the following ones;
+		// the recovery goes to every channel that was reached. Skipped
+		// events, e.g. while flapping, don't start escalating.
+		var escalated []config.NotifyTarget
+		escalating := false
+		if state != nil {
+			switch {
+			case result.IsRecovery:
+				if p := r.escalationPolicy(stateView.Escalation); p != nil {
+					escalated, escalating = p.reached(stateView.EscalationLevel), true
+				}
+			case !stateView.Healthy:
+				if p := r.escalationPolicy(escalationName(alert, override)); p != nil {
+					esc := &escalator{r: r, ctx: ctx, alert: alert, policy: p, state: state, key: result.EntityKey, opts: opts}
+					stateView.Escalation = p.name
+					stateView.EscalationLevel = esc.escalate(ev, time.Now())
+					escalated, escalating = p.reached(stateView.EscalationLevel), true
+				}
+			}
+		}
+
+		effectiveNotify := alert.Notify
+		if override != nil && len(override.Notify) > 0 {
+			effectiveNotify = override.Notify
+		}
+		if escalating {
+			effectiveNotify = escalated
+		}
+
+		// Acknowledgement. An acknowledged incident notifies again on
+		// recovery and flap changes; an event of another type clears the
+		// acknowledgement in the state machine above.
+		if ack := stateView.Ack; ack != nil && !result.IsRecovery && flapChange == "" {
+			log.Info("notification withheld by acknowledgement", "type", ev.Type, "by", ack.By)
+			result.Acknowledged = true
+			result.AcknowledgedBy = ack.By
+			for _, n := range effectiveNotify {
+				result.SuppressedChannels = append(result.SuppressedChannels, n.Channel)
+			}
+			result.Duration = time.Since(start)
+			emit(result)
+			continue
+		}
+
+		// Dependencies. Like silences below, inhibited events keep their
+		// state but neither notify nor start a cooldown.
+		if parent, key, ok := r.inhibitor(alert, ev.Fields, opts.Dependencies); ok {
+			log.Info("notification inhibited by dependency", "type", ev.Type, "parent", parent, "parent_key", key)
+			result.Inhibited = true
+			result.InhibitedBy = parent
+			for _, n := range effectiveNotify {
+				result.SuppressedChannels = append(result.SuppressedChannels, n.Channel)
+			}
+			result.Duration = time.Since(start)
+			emit(result)
+			continue
+		}
+
+		// Silences. The state machine above still ran, but silenced events
+		// neither notify nor start a cooldown.
+		if sil, ok := r.silences.Match(alert.Name, ev.Type, ev.Fields, time.Now()); ok {
+			log.Info("notification silenced", "type", ev.Type, "silence", sil.ID)
+			result.Silenced = true
+			result.SilencedBy = sil.ID
+			for _, n := range effectiveNotify {
+				result.SuppressedChannels = append(result.SuppressedChannels, n.Channel)
+			}
 			result.Duration = time.Since(start)
-			out <- result
+			emit(result)
 			continue
 		}
 
-		// c. Cooldown.
+		// c. Cooldown. Flap start/stop notifications are sent exactly once
+		// and are not subject to cooldown.
 		effectiveDuration := resolveEffectiveCooldown(alert, override)
-		if opts.Cooldown != nil {
-			if !opts.Cooldown.Check(ev.Type, effectiveDuration) {
+		if cd != nil && flapChange == "" {
+			if !cd.Check(ev.Type, effectiveDuration) {
 				log.Info("notification suppressed by cooldown", "type", ev.Type)
 				result.Suppressed = true
+				for _, n := range effectiveNotify {
+					result.SuppressedChannels = append(result.SuppressedChannels, n.Channel)
+				}
 				result.Duration = time.Since(start)
-				out <- result
+				emit(result)
 				continue
 			}
 		}
@@ -245,6 +467,9 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 		if override != nil && override.Template != "" {
 			effectiveTemplate = override.Template
 		}
+		if flapChange != "" && alert.Events.Flap.Template != "" {
+			effectiveTemplate = alert.Events.Flap.Template
+		}
 
 		log.Info("rendering templates", "type", ev.Type)
 		tmplData := notify.BuildTemplateData(
@@ -253,11 +478,10 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 			ev.Fields,
 			alert.Args,
 		)
+		tmplData.Event = ev.TemplateValues()
+		stateView.Key = result.EntityKey
+		tmplData.State = stateView.templateData()
 
-		effectiveNotify := alert.Notify
-		if override != nil && len(override.Notify) > 0 {
-			effectiveNotify = override.Notify
-		}
 		refs := mapNotifyRefs(effectiveNotify)
 
 		targets, err := notify.ResolveTargets(refs, chans, effectiveTemplate, tmplData)
@@ -275,9 +499,12 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 		}
 		log.Debug("templates rendered", "targets", len(targets))
 
-		// e. Notify.
-		for _, t := range targets {
-			if dryRun {
+		// e. Notify. Targets with a group config are queued and sent later,
+		// one message per group, and so is the event for the digests.
+		r.queueDigests(log, alert, ev, effectiveTemplate, tmplData, dryRun)
+		targets = r.queueGrouped(log, alert, &result, effectiveNotify, targets, ev)
+		if dryRun {
+			for _, t := range targets {
 				if err := notify.Validate(t); err != nil {
 					result.Err = err
 					result.ErrStage = "notify"
@@ -287,19 +514,9 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 				}
 				result.Notified = append(result.Notified, t.ChannelName)
 				log.Debug("would notify (dry-run)", "channel", t.ChannelName, "message", t.Message)
-				continue
-			}
-
-			log.Info("sending notification", "channel", t.ChannelName)
-			if err := notify.Send(t); err != nil {
-				result.Err = err
-				result.ErrStage = "notify"
-				log.Error("notify failed", "channel", t.ChannelName, "error", err)
-				sendErr(result)
-				return
 			}
-			result.Notified = append(result.Notified, t.ChannelName)
-			log.Debug("notification sent", "channel", t.ChannelName)
+		} else {
+			r.deliver(ctx, log, alert, &result, targets)
 		}
 
 		// f. Side effects.
@@ -335,21 +552,100 @@ func (r *Runner) runAlert(ctx context.Context, alert *config.Alert, opts RunOpts
 
 		result.Duration = time.Since(start)
 		log.Info("event processed", "type", result.EventType, "duration", result.Duration)
-		out <- result
+		emit(result)
 	}
 }
 
-// isHealthyEvent returns true if the event type is in the alert's healthy list.
-func isHealthyEvent(alert *config.Alert, eventType string) bool {
-	if alert.Events == nil {
-		return false
+// deliver sends each target independently, retrying per the alert's retry
+// policy, or options.retry for a nil alert, so one failing channel neither
+// blocks nor aborts the others. Channels that still fail are recorded on the
+// result and appended to the failed-delivery log.
+func (r *Runner) deliver(ctx context.Context, log *slog.Logger, alert *config.Alert, result *Result, targets []notify.Target) {
+	var retry *config.Retry
+	if alert != nil {
+		retry = alert.Retry
 	}
-	for _, h := range alert.Events.Healthy {
-		if h == eventType {
-			return true
+	policy := resolveRetryPolicy(r.cfg.Options.Retry, retry)
+	if result.Rendered == nil {
+		result.Rendered = make(map[string]string, len(targets))
+		for _, t := range targets {
+			result.Rendered[t.ChannelName] = t.Message
 		}
 	}
-	return false
+
+	errs := make([]error, len(targets))
+	attempts := make([]int, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t notify.Target) {
+			defer wg.Done()
+			log.Info("sending notification", "channel", t.ChannelName)
+			attempts[i], errs[i] = notify.SendWithRetry(ctx, t, policy, r.send)
+		}(i, t)
+	}
+	wg.Wait()
+
+	var failed []error
+	for i, t := range targets {
+		if errs[i] == nil {
+			result.Notified = append(result.Notified, t.ChannelName)
+			log.Debug("notification sent", "channel", t.ChannelName, "attempts", attempts[i])
+			continue
+		}
+
+		result.Failed = append(result.Failed, t.ChannelName)
+		failed = append(failed, errs[i])
+		log.Error("notify failed", "channel", t.ChannelName, "attempts", attempts[i], "error", errs[i])
+
+		if r.cfg.Options.LogsDir == "" {
+			continue
+		}
+		entry := notify.FailedDelivery{
+			Time:      time.Now().UTC(),
+			Alert:     result.AlertName,
+			Digest:    result.Digest,
+			EventType: result.EventType,
+			Channel:   t.ChannelName,
+			Attempts:  attempts[i],
+			Error:     errs[i].Error(),
+		}
+		path := filepath.Join(r.cfg.Options.LogsDir, notify.FailedLogName)
+		if err := notify.AppendFailedDelivery(path, entry); err != nil {
+			log.Warn("writing failed-delivery log failed", "path", path, "error", err)
+		}
+	}
+
+	if len(failed) > 0 {
+		result.Err = errors.Join(failed...)
+		result.ErrStage = "notify"
+	}
+}
+
+// resolveRetryPolicy merges the alert retry settings over the global ones,
+// field by field, falling back to the built-in defaults. Config loading has
+// checked the durations.
+func resolveRetryPolicy(global, alert *config.Retry) notify.RetryPolicy {
+	p := notify.RetryPolicy{
+		Attempts:   config.DefaultRetryAttempts,
+		Backoff:    config.DefaultRetryBackoff,
+		MaxBackoff: config.DefaultRetryMaxBackoff,
+	}
+	for _, rc := range []*config.Retry{global, alert} {
+		if rc == nil {
+			continue
+		}
+		if rc.Attempts != nil {
+			p.Attempts = *rc.Attempts
+		}
+		if d, err := time.ParseDuration(rc.Backoff); err == nil {
+			p.Backoff = d
+		}
+		if d, err := time.ParseDuration(rc.MaxBackoff); err == nil {
+			p.MaxBackoff = d
+		}
+	}
+	return p
 }
 
 // resolveEffectiveCooldown returns the cooldown duration for an event type.
@@ -384,6 +680,26 @@ func mapNotifyRefs(targets []config.NotifyTarget) []notify.NotifyRef {
 	return refs
 }
 
+// logOutput writes a healthcheck execution's output to the alert's output
+// stream, if any.
+func (r *Runner) logOutput(alert *config.Alert, trigger string, res *healthcheck.ExecResult, err error) {
+	if r.output == nil {
+		return
+	}
+	attrs := []any{
+		"trigger", trigger,
+		"exit_code", res.ExitCode,
+		"duration", res.Duration,
+		"stdout", res.Stdout,
+		"stderr", res.Stderr,
+	}
+	if err != nil {
+		r.output.For(alert.Name).Error("healthcheck failed", append(attrs, "error", err)...)
+		return
+	}
+	r.output.For(alert.Name).Info("healthcheck output", attrs...)
+}
+
 func mapChannelDefs(channels map[string]config.Channel) map[string]notify.ChannelDef {
 	defs := make(map[string]notify.ChannelDef, len(channels))
 	for name, ch := range channels {
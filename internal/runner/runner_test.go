@@ -2,14 +2,20 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/cooldown"
+	"github.com/sznuper/sznuper/internal/notify"
+	"github.com/sznuper/sznuper/internal/silence"
 )
 
 func writeScript(t *testing.T, dir, content string) {
@@ -64,6 +70,37 @@ func TestRunAlert_EndToEnd(t *testing.T) {
 	}
 }
 
+func TestRunAlert_JSONOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\necho '{\"type\":\"unit_failed\",\"units\":[\"nginx\",\"redis\"],\"count\":2}'\n")
+
+	cfg := &config.Config{
+		Options:  config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+		Alerts: []config.Alert{
+			{
+				Name:        "units",
+				Healthcheck: "file://check.sh",
+				Output:      "json",
+				Template:    `{{event.count}} failed: {{join ", " event.units}}{{if gt event.count 1}} (multiple){{end}}`,
+				Notify:      []config.NotifyTarget{{Channel: "logger"}},
+			},
+		},
+	}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	result := <-r.RunAlert(context.Background(), &cfg.Alerts[0], true, nil, nil)
+	if result.Err != nil {
+		t.Fatalf("unexpected error at stage %q: %v", result.ErrStage, result.Err)
+	}
+	if result.Fields["units"] != `["nginx","redis"]` {
+		t.Errorf("units field = %q", result.Fields["units"])
+	}
+	if want := "2 failed: nginx, redis (multiple)"; result.Rendered["logger"] != want {
+		t.Errorf("rendered = %q, want %q", result.Rendered["logger"], want)
+	}
+}
+
 func TestRunAlert_ResolveFails(t *testing.T) {
 	cfg := &config.Config{
 		Options: config.Options{HealthchecksDir: t.TempDir()},
@@ -272,3 +309,279 @@ func TestRunAll(t *testing.T) {
 		}
 	}
 }
+
+func TestRunAlert_NotifyFailureDoesNotBlockOtherChannels(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\necho '--- event'\necho type=critical_usage\n")
+
+	logsDir := filepath.Join(dir, "logs")
+	outFile := filepath.Join(dir, "se-output.txt")
+	attempts := 1
+	cfg := &config.Config{
+		Options: config.Options{
+			HealthchecksDir: dir,
+			LogsDir:         logsDir,
+			Retry:           &config.Retry{Attempts: &attempts, Backoff: "1ms"},
+		},
+		Globals: map[string]any{"hostname": "host"},
+		Channels: map[string]config.Channel{
+			"good": {URL: "logger://"},
+			"bad":  {URL: "logger://"},
+		},
+		Alerts: []config.Alert{
+			{
+				Name:        "disk",
+				Healthcheck: "file://check.sh",
+				Template:    "msg",
+				Notify:      []config.NotifyTarget{{Channel: "bad"}, {Channel: "good"}},
+				SideEffects: []string{fmt.Sprintf("echo ran > %s", outFile)},
+			},
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	r := New(cfg, logger)
+	var mu sync.Mutex
+	calls := map[string]int{}
+	r.send = func(t notify.Target) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[t.ChannelName]++
+		if t.ChannelName == "bad" {
+			return errors.New("unreachable")
+		}
+		return nil
+	}
+
+	result := <-r.RunAlertOpts(context.Background(), &cfg.Alerts[0], RunOpts{})
+	if result.ErrStage != "notify" || result.Err == nil {
+		t.Fatalf("err = %v (stage %q), want notify error", result.Err, result.ErrStage)
+	}
+	if len(result.Notified) != 1 || result.Notified[0] != "good" {
+		t.Errorf("notified = %v, want [good]", result.Notified)
+	}
+	if len(result.Failed) != 1 || result.Failed[0] != "bad" {
+		t.Errorf("failed = %v, want [bad]", result.Failed)
+	}
+	if calls["bad"] != 2 {
+		t.Errorf("bad attempts = %d, want 2 (1 retry)", calls["bad"])
+	}
+	if result.SideEffectsRun != 1 {
+		t.Errorf("SideEffectsRun = %d, want 1", result.SideEffectsRun)
+	}
+
+	data, err := os.ReadFile(filepath.Join(logsDir, notify.FailedLogName))
+	if err != nil {
+		t.Fatalf("reading failed-delivery log: %v", err)
+	}
+	if !strings.Contains(string(data), `"channel":"bad"`) || !strings.Contains(string(data), `"event_type":"critical_usage"`) {
+		t.Errorf("failed-delivery log = %q", data)
+	}
+}
+
+func TestResolveRetryPolicy(t *testing.T) {
+	zero, five := 0, 5
+	tests := []struct {
+		name   string
+		global *config.Retry
+		alert  *config.Retry
+		want   notify.RetryPolicy
+	}{
+		{"defaults", nil, nil, notify.RetryPolicy{Attempts: 3, Backoff: time.Second, MaxBackoff: 30 * time.Second}},
+		{"global", &config.Retry{Attempts: &five, Backoff: "2s"}, nil, notify.RetryPolicy{Attempts: 5, Backoff: 2 * time.Second, MaxBackoff: 30 * time.Second}},
+		{"alert disables", &config.Retry{Attempts: &five}, &config.Retry{Attempts: &zero}, notify.RetryPolicy{Attempts: 0, Backoff: time.Second, MaxBackoff: 30 * time.Second}},
+		{"alert backoff over global", &config.Retry{Backoff: "2s", MaxBackoff: "1m"}, &config.Retry{Backoff: "5s"}, notify.RetryPolicy{Attempts: 3, Backoff: 5 * time.Second, MaxBackoff: time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveRetryPolicy(tt.global, tt.alert); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunAlert_Silenced(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\nprintf -- '--- event\\ntype=high_usage\\nmount=/backup\\n'\n")
+	outFile := filepath.Join(dir, "side_effect.txt")
+
+	cfg := &config.Config{
+		Options:  config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+		Alerts: []config.Alert{
+			{
+				Name:        "disk",
+				Healthcheck: "file://check.sh",
+				Template:    "msg",
+				Cooldown:    "1h",
+				Events:      &config.Events{Healthy: []string{"ok"}},
+				Notify:      []config.NotifyTarget{{Channel: "logger"}},
+				SideEffects: []string{fmt.Sprintf("echo ran > %s", outFile)},
+			},
+		},
+	}
+
+	store := silence.NewStore("")
+	now := time.Now()
+	sil, err := store.Add(silence.Silence{
+		Match: silence.Matcher{Alert: "disk", Fields: map[string]string{"mount": "/backup"}},
+		End:   now.Add(time.Hour),
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	r.SetSilences(silence.NewSet(nil, store))
+	var sent int
+	r.send = func(notify.Target) error {
+		sent++
+		return nil
+	}
+	opts := RunOpts{State: &AlertState{Healthy: true}, Cooldown: cooldown.New(nil)}
+
+	result := <-r.RunAlertOpts(context.Background(), &cfg.Alerts[0], opts)
+	if !result.Silenced || result.SilencedBy != sil.ID {
+		t.Fatalf("result = %+v, want silenced by %s", result, sil.ID)
+	}
+	if sent != 0 || len(result.Notified) != 0 || result.SideEffectsRun != 0 {
+		t.Errorf("sent = %d, notified = %v, side effects = %d; want none", sent, result.Notified, result.SideEffectsRun)
+	}
+	if _, err := os.Stat(outFile); err == nil {
+		t.Error("side effect ran while silenced")
+	}
+	// The state machine still tracks the incident.
+	if result.Healthy == nil || *result.Healthy {
+		t.Errorf("healthy = %v, want false", result.Healthy)
+	}
+
+	// Once the silence is gone, the event notifies: silenced events do not
+	// start a cooldown.
+	if err := store.Expire(sil.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	result = <-r.RunAlertOpts(context.Background(), &cfg.Alerts[0], opts)
+	if result.Silenced || result.Suppressed || sent != 1 {
+		t.Errorf("after expiry: silenced = %v, suppressed = %v, sent = %d", result.Silenced, result.Suppressed, sent)
+	}
+}
+
+func TestRunAlert_Acknowledged(t *testing.T) {
+	dir := t.TempDir()
+	typeFile := filepath.Join(dir, "type")
+	writeScript(t, dir, fmt.Sprintf("#!/bin/sh\nprintf -- '--- event\\ntype=%%s\\n' \"$(cat %s)\"\n", typeFile))
+	setType := func(typ string) {
+		if err := os.WriteFile(typeFile, []byte(typ), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{
+		Options:  config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+		Alerts: []config.Alert{
+			{
+				Name:        "uplink",
+				Healthcheck: "file://check.sh",
+				Template:    "{{event.type}}{{if state.acknowledged}} acked by {{state.ack.by}}{{end}}",
+				Events:      &config.Events{Healthy: []string{"ok"}},
+				Notify:      []config.NotifyTarget{{Channel: "logger"}},
+			},
+		},
+	}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	var sent []string
+	r.send = func(t notify.Target) error {
+		sent = append(sent, t.Message)
+		return nil
+	}
+	state := &AlertState{Healthy: true}
+	opts := RunOpts{State: state, Cooldown: cooldown.New(nil)}
+	run := func(typ string) Result {
+		t.Helper()
+		setType(typ)
+		res := <-r.RunAlertOpts(context.Background(), &cfg.Alerts[0], opts)
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		return res
+	}
+
+	run("degraded")
+	if _, ok := state.Acknowledge("alice", "", time.Now()); !ok {
+		t.Fatal("acknowledge failed on an unhealthy alert")
+	}
+	res := run("degraded")
+	if !res.Acknowledged || res.AcknowledgedBy != "alice" || len(res.Notified) != 0 {
+		t.Errorf("result = %+v, want withheld by alice's ack", res)
+	}
+	if len(res.SuppressedChannels) != 1 || res.SuppressedChannels[0] != "logger" {
+		t.Errorf("suppressed channels = %v, want [logger]", res.SuppressedChannels)
+	}
+
+	// Another event type ends the acknowledgement.
+	if res := run("down"); res.Acknowledged || len(res.Notified) != 1 {
+		t.Errorf("result = %+v, want a notification for the new type", res)
+	}
+
+	state.Acknowledge("bob", "", time.Now())
+	if res := run("ok"); !res.IsRecovery || len(res.Notified) != 1 {
+		t.Errorf("result = %+v, want the recovery notified", res)
+	}
+	want := []string{"degraded", "down", "ok acked by bob"}
+	if strings.Join(sent, "|") != strings.Join(want, "|") {
+		t.Errorf("sent = %q, want %q", sent, want)
+	}
+}
+
+type outputLogs struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (o *outputLogs) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.Write(p)
+}
+
+func (o *outputLogs) For(alert string) *slog.Logger {
+	return slog.New(slog.NewTextHandler(o, nil)).With("alert", alert)
+}
+
+func TestRunAlert_OutputLogger(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\necho 'checking' >&2\necho '--- event'\necho type=ok\nexit 3\n")
+
+	cfg := &config.Config{
+		Options:  config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+		Alerts: []config.Alert{
+			{
+				Name:        "disk",
+				Healthcheck: "file://check.sh",
+				Template:    "msg",
+				Notify:      []config.NotifyTarget{{Channel: "logger"}},
+			},
+		},
+	}
+
+	out := &outputLogs{}
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	r.SetOutputLogger(out)
+	for range r.RunAlertOpts(context.Background(), &cfg.Alerts[0], RunOpts{DryRun: true, TriggerType: "interval"}) {
+	}
+
+	got := out.buf.String()
+	for _, want := range []string{
+		`msg="healthcheck output" alert=disk trigger=interval exit_code=3`,
+		`stdout="--- event\ntype=ok\n"`,
+		`stderr="checking\n"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output log %q does not contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package runner
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/healthcheck"
+)
+
+// AlertState tracks the healthy/unhealthy binary state for an alert.
+// Used only when events.healthy is configured. It is shared by all triggers
+// of an alert and serialized to JSON by the state store.
+type AlertState struct {
+	mu                   sync.Mutex
+	Healthy              bool        `json:"healthy"`
+	ConsecutiveUnhealthy int         `json:"consecutive_unhealthy,omitempty"`
+	ConsecutiveHealthy   int         `json:"consecutive_healthy,omitempty"`
+	Flapping             bool        `json:"flapping,omitempty"`
+	Transitions          []time.Time `json:"transitions,omitempty"` // within the flap window
+	// Escalation of the current incident, see escalation.go.
+	UnhealthySince  time.Time `json:"unhealthy_since,omitzero"`
+	Escalation      string    `json:"escalation,omitempty"` // policy name
+	EscalationLevel int       `json:"escalation_level,omitempty"`
+	EscalatedAt     time.Time `json:"escalated_at,omitzero"`
+	// EscalationFields are the fields of the latest unhealthy event, so
+	// escalation notifications can render it after a restart.
+	EscalationFields map[string]string `json:"escalation_fields,omitempty"`
+	// UnhealthyType is the type of the latest unhealthy event while
+	// unhealthy; Ack, if set, acknowledges the incident.
+	UnhealthyType string `json:"unhealthy_type,omitempty"`
+	Ack           *Ack   `json:"ack,omitempty"`
+	// ThresholdType is the type the thresholds last derived, for hysteresis.
+	ThresholdType string `json:"threshold_type,omitempty"`
+
+	escTimer *time.Timer       // next escalation step or repeat
+	escGen   uint64            // invalidates fired timers of a replaced or cancelled escalation
+	escEvent healthcheck.Event // latest unhealthy event, rendered by escalation notifications
+}
+
+// MarshalJSON encodes the state while holding its lock.
+func (s *AlertState) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	type plain AlertState
+	return json.Marshal((*plain)(s))
+}
+
+// UnmarshalJSON decodes the state while holding its lock.
+func (s *AlertState) UnmarshalJSON(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	type plain AlertState
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// Ack acknowledges an unhealthy incident: its notifications are withheld
+// until recovery, or until an unhealthy event of another type arrives.
+type Ack struct {
+	By        string    `json:"by,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	At        time.Time `json:"at"`
+	EventType string    `json:"event_type,omitempty"` // the acknowledged event type
+}
+
+// Acknowledge acknowledges the current incident and pauses its escalation.
+// It reports false if the alert is healthy.
+func (s *AlertState) Acknowledge(by, comment string, now time.Time) (Ack, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Healthy {
+		return Ack{}, false
+	}
+	s.Ack = &Ack{By: by, Comment: comment, At: now, EventType: s.UnhealthyType}
+	s.stopEscalationLocked()
+	return *s.Ack, true
+}
+
+// idle reports whether the state is indistinguishable from a fresh one:
+// healthy, with no pending transition, flapping history, incident,
+// acknowledgement or threshold range to remember.
+func (s *AlertState) idle() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Healthy && s.ConsecutiveUnhealthy == 0 && !s.Flapping && len(s.Transitions) == 0 &&
+		s.UnhealthySince.IsZero() && s.Escalation == "" && s.UnhealthyType == "" && s.Ack == nil &&
+		s.ThresholdType == ""
+}
+
+// View returns a copy of the current state.
+func (s *AlertState) View() StateView {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.viewLocked()
+}
+
+func (s *AlertState) viewLocked() StateView {
+	v := StateView{
+		Healthy:              s.Healthy,
+		ConsecutiveUnhealthy: s.ConsecutiveUnhealthy,
+		ConsecutiveHealthy:   s.ConsecutiveHealthy,
+		Flapping:             s.Flapping,
+		FlapTransitions:      len(s.Transitions),
+		Escalation:           s.Escalation,
+		EscalationLevel:      s.EscalationLevel,
+	}
+	if s.Ack != nil {
+		ack := *s.Ack
+		v.Ack = &ack
+	}
+	return v
+}
+
+// Flap state changes reported on transitions and results.
+const (
+	FlapStart = "start"
+	FlapStop  = "stop"
+)
+
+// StateView is a point-in-time copy of an AlertState, taken right after an
+// event was applied. It is exposed to templates as {{state.*}}.
+type StateView struct {
+	Healthy              bool
+	ConsecutiveUnhealthy int
+	ConsecutiveHealthy   int
+	Flapping             bool
+	FlapTransitions      int
+	FlapChange           string // FlapStart, FlapStop, or ""
+	Key                  string // entity key when events.key is set
+	Escalation           string // escalation policy of the current incident
+	EscalationLevel      int    // escalation steps reached; on recovery, the steps reached before it
+	Ack                  *Ack   // acknowledgement of the current incident; on recovery, of the one that ended
+}
+
+func (v StateView) templateData() map[string]any {
+	return map[string]any{
+		"healthy":               v.Healthy,
+		"consecutive_unhealthy": v.ConsecutiveUnhealthy,
+		"consecutive_healthy":   v.ConsecutiveHealthy,
+		"flapping":              v.Flapping,
+		"flap_transitions":      v.FlapTransitions,
+		"flap_change":           v.FlapChange,
+		"key":                   v.Key,
+		"escalation":            v.Escalation,
+		"escalation_level":      v.EscalationLevel,
+		"acknowledged":          v.Ack != nil,
+		"ack":                   ackData(v.Ack),
+	}
+}
+
+// ackData exposes an Ack to templates as {{state.ack.*}}; nil without one.
+func ackData(a *Ack) map[string]any {
+	if a == nil {
+		return nil
+	}
+	return map[string]any{
+		"by":         a.By,
+		"comment":    a.Comment,
+		"at":         a.At,
+		"event_type": a.EventType,
+	}
+}
+
+// transition is the outcome of applying one event to an AlertState.
+type transition struct {
+	notify    bool // event continues to cooldown and notification
+	recovery  bool // unhealthy -> healthy
+	unhealthy bool // healthy -> unhealthy
+	pending   bool // a transition is waiting for its consecutive threshold
+	flapped   bool // notification withheld because the alert is flapping
+	view      StateView
+	// Escalation policy, level and acknowledgement of the incident a
+	// recovery ended.
+	escalation      string
+	escalationLevel int
+	ack             *Ack
+	ackCleared      bool // an event of another type ended the acknowledgement
+}
+
+// advance applies an event received at now to the state machine.
+//
+// Unhealthy events only flip a healthy alert once unhealthy_threshold of them
+// arrive in a row; healthy events only recover an unhealthy alert after
+// healthy_threshold in a row. Any event of the opposite kind resets the
+// other counter. Events that arrive while a transition is pending are not
+// notified.
+//
+// With events.flap configured, every transition is recorded. Crossing the
+// flap threshold notifies once and withholds further notifications until the
+// alert settles, which notifies once more.
+func (s *AlertState) advance(alert *config.Alert, override *config.EventOverride, eventType string, now time.Time) transition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tr transition
+	if isHealthyEvent(alert, eventType) {
+		s.ConsecutiveHealthy++
+		s.ConsecutiveUnhealthy = 0
+		switch {
+		case s.Healthy:
+			// healthy -> healthy: no notification
+		case s.ConsecutiveHealthy >= healthyThreshold(alert, override):
+			s.Healthy = true
+			tr.recovery = true
+			tr.notify = true
+			tr.escalation, tr.escalationLevel, tr.ack = s.Escalation, s.EscalationLevel, s.Ack
+			s.resetEscalationLocked()
+			s.UnhealthyType, s.Ack = "", nil
+		default:
+			tr.pending = true
+		}
+	} else {
+		s.ConsecutiveUnhealthy++
+		s.ConsecutiveHealthy = 0
+		switch {
+		case !s.Healthy:
+			tr.notify = true
+		case s.ConsecutiveUnhealthy >= unhealthyThreshold(alert, override):
+			s.Healthy = false
+			s.UnhealthySince = now
+			tr.unhealthy = true
+			tr.notify = true
+		default:
+			tr.pending = true
+		}
+		if !s.Healthy {
+			s.UnhealthyType = eventType
+			switch {
+			case s.Ack == nil:
+			case s.Ack.EventType == "":
+				s.Ack.EventType = eventType // acknowledged before the type was tracked
+			case s.Ack.EventType != eventType:
+				s.Ack = nil
+				tr.ackCleared = true
+			}
+		}
+	}
+
+	flapChange := s.detectFlap(alert, tr.recovery || tr.unhealthy, now)
+	switch {
+	case flapChange != "":
+		tr.notify = true
+	case s.Flapping && tr.notify:
+		tr.notify = false
+		tr.flapped = true
+	}
+
+	tr.view = s.viewLocked()
+	tr.view.FlapChange = flapChange
+	if tr.recovery {
+		tr.view.Escalation, tr.view.EscalationLevel, tr.view.Ack = tr.escalation, tr.escalationLevel, tr.ack
+	}
+	return tr
+}
+
+// detectFlap records a transition (if one happened), trims the sliding
+// window, and returns FlapStart or FlapStop when the flapping state changes.
+// Without a valid flap config, which config loading ensures, flap detection
+// is off.
+func (s *AlertState) detectFlap(alert *config.Alert, transitioned bool, now time.Time) string {
+	var flap *config.Flap
+	var window time.Duration
+	if alert.Events != nil && alert.Events.Flap != nil {
+		if d, err := time.ParseDuration(alert.Events.Flap.Window); err == nil && d > 0 {
+			flap, window = alert.Events.Flap, d
+		}
+	}
+	if flap == nil {
+		s.Flapping = false
+		s.Transitions = nil
+		return ""
+	}
+
+	if transitioned {
+		s.Transitions = append(s.Transitions, now)
+	}
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(s.Transitions) && !s.Transitions[i].After(cutoff) {
+		i++
+	}
+	s.Transitions = s.Transitions[i:]
+
+	switch {
+	case !s.Flapping && len(s.Transitions) >= flap.Threshold:
+		s.Flapping = true
+		return FlapStart
+	case s.Flapping && len(s.Transitions) <= flap.Settle:
+		s.Flapping = false
+		return FlapStop
+	}
+	return ""
+}
+
+// isHealthyEvent returns true if the event type is in the alert's healthy list.
+func isHealthyEvent(alert *config.Alert, eventType string) bool {
+	if alert.Events == nil {
+		return false
+	}
+	return slices.Contains(alert.Events.Healthy, eventType)
+}
+
+// unhealthyThreshold returns how many consecutive unhealthy events are needed
+// before a healthy alert transitions. Override takes precedence; default 1.
+func unhealthyThreshold(alert *config.Alert, override *config.EventOverride) int {
+	if override != nil && override.UnhealthyThreshold > 0 {
+		return override.UnhealthyThreshold
+	}
+	if alert.Events != nil && alert.Events.UnhealthyThreshold > 0 {
+		return alert.Events.UnhealthyThreshold
+	}
+	return 1
+}
+
+// healthyThreshold returns how many consecutive healthy events are needed
+// before an unhealthy alert recovers. Override takes precedence; default 1.
+func healthyThreshold(alert *config.Alert, override *config.EventOverride) int {
+	if override != nil && override.HealthyThreshold > 0 {
+		return override.HealthyThreshold
+	}
+	if alert.Events != nil && alert.Events.HealthyThreshold > 0 {
+		return alert.Events.HealthyThreshold
+	}
+	return 1
+}
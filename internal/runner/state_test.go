@@ -0,0 +1,191 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func thresholdAlert(unhealthy, healthy int) *config.Alert {
+	return &config.Alert{
+		Name: "cpu",
+		Events: &config.Events{
+			Healthy:            []string{"ok"},
+			UnhealthyThreshold: unhealthy,
+			HealthyThreshold:   healthy,
+		},
+	}
+}
+
+func TestAdvance_DefaultThresholdTransitionsImmediately(t *testing.T) {
+	s := &AlertState{Healthy: true}
+	a := thresholdAlert(0, 0)
+
+	tr := s.advance(a, nil, "high_usage", t0)
+	if !tr.unhealthy || !tr.notify || s.Healthy {
+		t.Fatalf("transition = %+v, want immediate unhealthy", tr)
+	}
+	tr = s.advance(a, nil, "ok", t0)
+	if !tr.recovery || !tr.notify || !s.Healthy {
+		t.Fatalf("transition = %+v, want immediate recovery", tr)
+	}
+}
+
+func TestAdvance_UnhealthyThreshold(t *testing.T) {
+	s := &AlertState{Healthy: true}
+	a := thresholdAlert(3, 0)
+
+	for i := 1; i <= 2; i++ {
+		tr := s.advance(a, nil, "high_usage", t0)
+		if tr.notify || !tr.pending || !s.Healthy {
+			t.Fatalf("event %d: transition = %+v, want pending", i, tr)
+		}
+		if tr.view.ConsecutiveUnhealthy != i {
+			t.Errorf("event %d: consecutive_unhealthy = %d", i, tr.view.ConsecutiveUnhealthy)
+		}
+	}
+	tr := s.advance(a, nil, "high_usage", t0)
+	if !tr.unhealthy || !tr.notify || s.Healthy {
+		t.Fatalf("third event: transition = %+v, want unhealthy", tr)
+	}
+	// Already unhealthy: every further unhealthy event notifies (subject to cooldown).
+	if tr := s.advance(a, nil, "high_usage", t0); !tr.notify || tr.unhealthy {
+		t.Errorf("fourth event: transition = %+v, want notify without transition", tr)
+	}
+}
+
+func TestAdvance_HealthyEventResetsUnhealthyCount(t *testing.T) {
+	s := &AlertState{Healthy: true}
+	a := thresholdAlert(2, 0)
+
+	s.advance(a, nil, "high_usage", t0)
+	if tr := s.advance(a, nil, "ok", t0); tr.notify || tr.pending {
+		t.Fatalf("transition = %+v, want healthy->healthy skip", tr)
+	}
+	if tr := s.advance(a, nil, "high_usage", t0); tr.notify || !tr.pending {
+		t.Errorf("transition = %+v, want counter restarted", tr)
+	}
+}
+
+func TestAdvance_HealthyThreshold(t *testing.T) {
+	s := &AlertState{Healthy: false}
+	a := thresholdAlert(0, 2)
+
+	if tr := s.advance(a, nil, "ok", t0); tr.notify || !tr.pending || s.Healthy {
+		t.Fatalf("transition = %+v, want recovery pending", tr)
+	}
+	if tr := s.advance(a, nil, "ok", t0); !tr.recovery || !s.Healthy {
+		t.Fatalf("transition = %+v, want recovery", tr)
+	}
+}
+
+func TestAdvance_OverrideThresholdTakesPrecedence(t *testing.T) {
+	s := &AlertState{Healthy: true}
+	a := thresholdAlert(5, 0)
+	critical := &config.EventOverride{UnhealthyThreshold: 1}
+
+	if tr := s.advance(a, critical, "critical_usage", t0); !tr.unhealthy {
+		t.Errorf("transition = %+v, want override threshold of 1 to apply", tr)
+	}
+}
+
+func flapAlert() *config.Alert {
+	return &config.Alert{
+		Name: "cpu",
+		Events: &config.Events{
+			Healthy: []string{"ok"},
+			Flap:    &config.Flap{Window: "10m", Threshold: 4},
+		},
+	}
+}
+
+func TestAdvance_FlapStartSuppressAndStop(t *testing.T) {
+	s := &AlertState{Healthy: true}
+	a := flapAlert()
+	now := t0
+
+	// Three transitions: below the threshold, all notify.
+	for i, typ := range []string{"high_usage", "ok", "high_usage"} {
+		now = now.Add(time.Minute)
+		if tr := s.advance(a, nil, typ, now); !tr.notify || tr.view.Flapping {
+			t.Fatalf("event %d: transition = %+v, want normal notify", i, tr)
+		}
+	}
+
+	// Fourth transition crosses the threshold: one flap-start notification.
+	now = now.Add(time.Minute)
+	tr := s.advance(a, nil, "ok", now)
+	if !tr.notify || tr.view.FlapChange != FlapStart || !tr.view.Flapping {
+		t.Fatalf("transition = %+v, want flap start", tr)
+	}
+	if tr.view.FlapTransitions != 4 {
+		t.Errorf("flap_transitions = %d, want 4", tr.view.FlapTransitions)
+	}
+
+	// Further transitions while flapping are withheld.
+	now = now.Add(time.Minute)
+	if tr := s.advance(a, nil, "high_usage", now); tr.notify || !tr.flapped {
+		t.Fatalf("transition = %+v, want suppressed while flapping", tr)
+	}
+
+	// Once the window holds no transitions, the alert settles and notifies once.
+	now = now.Add(11 * time.Minute)
+	tr = s.advance(a, nil, "high_usage", now)
+	if !tr.notify || tr.view.FlapChange != FlapStop || tr.view.Flapping {
+		t.Fatalf("transition = %+v, want flap stop", tr)
+	}
+	if tr := s.advance(a, nil, "high_usage", now.Add(time.Minute)); !tr.notify || tr.view.FlapChange != "" {
+		t.Errorf("transition = %+v, want normal notify after settling", tr)
+	}
+}
+
+func TestAdvance_FlapWindowSlides(t *testing.T) {
+	s := &AlertState{Healthy: true}
+	a := flapAlert()
+	now := t0
+
+	// Transitions spaced wider than window/threshold never accumulate.
+	for i := range 10 {
+		now = now.Add(4 * time.Minute)
+		typ := "high_usage"
+		if i%2 == 1 {
+			typ = "ok"
+		}
+		if tr := s.advance(a, nil, typ, now); tr.view.Flapping {
+			t.Fatalf("event %d: flapping with %d transitions in window", i, tr.view.FlapTransitions)
+		}
+	}
+}
+
+func TestAcknowledge(t *testing.T) {
+	s := &AlertState{Healthy: true}
+	a := thresholdAlert(0, 0)
+
+	if _, ok := s.Acknowledge("alice", "", t0); ok {
+		t.Fatal("acknowledged a healthy alert")
+	}
+	s.advance(a, nil, "high_usage", t0)
+	ack, ok := s.Acknowledge("alice", "on it", t0)
+	if !ok || ack.EventType != "high_usage" || ack.By != "alice" {
+		t.Fatalf("ack = %+v, %v; want high_usage by alice", ack, ok)
+	}
+
+	if tr := s.advance(a, nil, "high_usage", t0); tr.view.Ack == nil || tr.ackCleared {
+		t.Errorf("transition = %+v, want ack kept for the same type", tr)
+	}
+	if tr := s.advance(a, nil, "critical", t0); tr.view.Ack != nil || !tr.ackCleared {
+		t.Errorf("transition = %+v, want ack cleared by another type", tr)
+	}
+
+	s.Acknowledge("bob", "", t0)
+	tr := s.advance(a, nil, "ok", t0)
+	if !tr.recovery || tr.view.Ack == nil || tr.view.Ack.By != "bob" {
+		t.Errorf("recovery view = %+v, want the ended ack", tr.view)
+	}
+	if s.Ack != nil || s.UnhealthyType != "" {
+		t.Errorf("state after recovery = ack %+v, type %q; want cleared", s.Ack, s.UnhealthyType)
+	}
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"log/slog"
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/healthcheck"
+)
+
+// thresholdPolicy is a compiled config.Thresholds.
+type thresholdPolicy struct {
+	field  string
+	ranges []thresholdRange
+}
+
+type thresholdRange struct {
+	typ        string
+	min, max   float64 // min inclusive, max exclusive; ±Inf when unbounded
+	hysteresis float64
+}
+
+// compileThresholds compiles a thresholds config. Config loading has
+// checked the ranges. The field name is lowercased like event field keys.
+func compileThresholds(cfg config.Thresholds) *thresholdPolicy {
+	p := &thresholdPolicy{field: strings.ToLower(cfg.Field)}
+	for _, rc := range cfg.Ranges {
+		rg := thresholdRange{typ: rc.Type, min: math.Inf(-1), max: math.Inf(1), hysteresis: cfg.Hysteresis}
+		if rc.Min != nil {
+			rg.min = *rc.Min
+		}
+		if rc.Max != nil {
+			rg.max = *rc.Max
+		}
+		if rc.Hysteresis != nil {
+			rg.hysteresis = *rc.Hysteresis
+		}
+		p.ranges = append(p.ranges, rg)
+	}
+	return p
+}
+
+// compileAlertThresholds compiles the thresholds of every alert of cfg that
+// has them, by alert name.
+func compileAlertThresholds(cfg *config.Config) map[string]*thresholdPolicy {
+	out := make(map[string]*thresholdPolicy)
+	for _, a := range cfg.Alerts {
+		if a.Thresholds != nil {
+			out[a.Name] = compileThresholds(*a.Thresholds)
+		}
+	}
+	return out
+}
+
+func (rg thresholdRange) contains(v, margin float64) bool {
+	return v >= rg.min-margin && v < rg.max+margin
+}
+
+// classify returns the type of the first range v falls in. The previous
+// type's range is widened by its hysteresis, so an event only leaves it
+// once the value crosses a bound by that margin; ranges listed before it
+// still take over as soon as they match. It reports false if no range
+// matches.
+func (p *thresholdPolicy) classify(v float64, prev string) (string, bool) {
+	for _, rg := range p.ranges {
+		if rg.contains(v, 0) || rg.typ == prev && rg.contains(v, rg.hysteresis) {
+			return rg.typ, true
+		}
+	}
+	return "", false
+}
+
+// applyThresholds rewrites the event type from the alert's thresholds.
+// Events without a numeric value in the field, or whose value is in no
+// range, keep their type. state remembers the range for hysteresis; it may
+// be nil.
+func (r *Runner) applyThresholds(log *slog.Logger, alert *config.Alert, state *AlertState, ev healthcheck.Event) healthcheck.Event {
+	p := r.thresholds[alert.Name]
+	if p == nil {
+		// An alert from outside the runner's config.
+		p = compileThresholds(*alert.Thresholds)
+	}
+	raw, ok := ev.Fields[p.field]
+	if !ok {
+		log.Debug("threshold field missing, keeping event type", "type", ev.Type, "field", p.field)
+		return ev
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || math.IsNaN(v) {
+		log.Warn("threshold field is not a number, keeping event type", "type", ev.Type, "field", p.field, "value", raw)
+		return ev
+	}
+
+	var typ string
+	if state != nil {
+		typ, ok = state.classify(p, v)
+	} else {
+		typ, ok = p.classify(v, "")
+	}
+	if !ok || typ == ev.Type {
+		return ev
+	}
+	log.Debug("event type derived from threshold", "reported_type", ev.Type, "type", typ, "field", p.field, "value", v)
+
+	ev.Type = typ
+	ev.Fields = maps.Clone(ev.Fields)
+	ev.Fields["type"] = typ
+	if ev.Values != nil {
+		ev.Values = maps.Clone(ev.Values)
+		ev.Values["type"] = typ
+	}
+	return ev
+}
+
+// classify classifies v against the range the state was last in and
+// remembers the result.
+func (s *AlertState) classify(p *thresholdPolicy, v float64) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	typ, ok := p.classify(v, s.ThresholdType)
+	if ok {
+		s.ThresholdType = typ
+	}
+	return typ, ok
+}
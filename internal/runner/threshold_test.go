@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/cooldown"
+	"github.com/sznuper/sznuper/internal/notify"
+)
+
+func ptr(f float64) *float64 { return &f }
+
+func usageThresholds() config.Thresholds {
+	return config.Thresholds{
+		Field: "usage_percent",
+		Ranges: []config.ThresholdRange{
+			{Type: "critical_usage", Min: ptr(95)},
+			{Type: "high_usage", Min: ptr(80), Hysteresis: ptr(5)},
+			{Type: "ok"},
+		},
+		Hysteresis: 2,
+	}
+}
+
+func TestThresholdClassify(t *testing.T) {
+	p := compileThresholds(usageThresholds())
+	cases := []struct {
+		v    float64
+		prev string
+		want string
+	}{
+		{50, "", "ok"},
+		{80, "", "high_usage"},
+		{95, "", "critical_usage"},
+		{94, "", "high_usage"},
+		{94, "critical_usage", "critical_usage"}, // within 2 of 95
+		{92.5, "critical_usage", "high_usage"},
+		{96, "high_usage", "critical_usage"}, // earlier ranges take over at once
+		{75, "high_usage", "high_usage"},     // per-range hysteresis of 5
+		{74, "high_usage", "ok"},
+		{79, "ok", "ok"},
+	}
+	for _, c := range cases {
+		if got, ok := p.classify(c.v, c.prev); !ok || got != c.want {
+			t.Errorf("classify(%g, %q) = %q, %v; want %q", c.v, c.prev, got, ok, c.want)
+		}
+	}
+
+	bounded := compileThresholds(config.Thresholds{Field: "x", Ranges: []config.ThresholdRange{{Type: "low", Max: ptr(10)}}})
+	if _, ok := bounded.classify(10, ""); ok {
+		t.Error("classify outside every range = ok, want none")
+	}
+}
+
+func TestRunAlert_Thresholds(t *testing.T) {
+	dir := t.TempDir()
+	valueFile := filepath.Join(dir, "value")
+	writeScript(t, dir, fmt.Sprintf("#!/bin/sh\nprintf -- '--- event\\ntype=usage\\nusage_percent=%%s\\n' \"$(cat %s)\"\n", valueFile))
+
+	th := usageThresholds()
+	cfg := &config.Config{
+		Options:  config.Options{HealthchecksDir: dir},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+		Alerts: []config.Alert{
+			{
+				Name:        "disk",
+				Healthcheck: "file://check.sh",
+				Template:    "{{event.type}} {{event.usage_percent}}",
+				Events:      &config.Events{Healthy: []string{"ok"}},
+				Notify:      []config.NotifyTarget{{Channel: "logger"}},
+				Thresholds:  &th,
+			},
+		},
+	}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	var sent []string
+	r.send = func(t notify.Target) error {
+		sent = append(sent, t.Message)
+		return nil
+	}
+	state := &AlertState{Healthy: true}
+	opts := RunOpts{State: state, Cooldown: cooldown.New(nil)}
+
+	var types []string
+	for _, v := range []string{"42", "96", "94", "90", "not-a-number", "60"} {
+		if err := os.WriteFile(valueFile, []byte(v), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		res := <-r.RunAlertOpts(context.Background(), &cfg.Alerts[0], opts)
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		if res.Fields["type"] != res.EventType {
+			t.Errorf("fields type = %q, event type %q", res.Fields["type"], res.EventType)
+		}
+		types = append(types, res.EventType)
+	}
+	want := []string{"ok", "critical_usage", "critical_usage", "high_usage", "usage", "ok"}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Errorf("types = %v, want %v", types, want)
+	}
+	if !state.View().Healthy {
+		t.Error("state unhealthy after the value dropped to ok")
+	}
+	if len(sent) == 0 || sent[0] != "critical_usage 96" {
+		t.Errorf("sent = %q, want the critical notification first", sent)
+	}
+}
+
+func TestRunAlert_ThresholdFieldCase(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "#!/bin/sh\nprintf -- '--- event\\ntype=usage\\nusage_percent=96\\n'\n")
+
+	th := usageThresholds()
+	th.Field = "Usage_Percent" // event keys are lowercased
+	alert := config.Alert{Name: "disk", Healthcheck: "file://check.sh", Thresholds: &th}
+	cfg := &config.Config{Options: config.Options{HealthchecksDir: dir}, Alerts: []config.Alert{alert}}
+
+	r := New(cfg, slog.New(slog.DiscardHandler))
+	res := <-r.RunAlertOpts(context.Background(), &cfg.Alerts[0], RunOpts{Cooldown: cooldown.New(nil)})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if res.EventType != "critical_usage" {
+		t.Errorf("event type = %q, want critical_usage", res.EventType)
+	}
+}
@@ -17,6 +17,7 @@ func (s *Scheduler) runPipeLoop(ctx context.Context, alert *config.Alert, trigge
 			return
 		}
 		s.logger.Warn("pipe: command exited, restarting", "alert", alert.Name, "error", err)
+		s.runner.Metrics().TriggerRestarted(alert.Name, "pipe")
 		select {
 		case <-ctx.Done():
 			return
@@ -85,15 +86,14 @@ func (s *Scheduler) runPipeOnce(ctx context.Context, alert *config.Alert, trigge
 			}
 
 		case res, ok := <-resultCh:
-			if s.onResult != nil {
-				s.onResult(res)
-			}
 			if !ok {
 				resultCh = nil
 				if len(buf) > 0 {
 					fire()
 				}
+				continue
 			}
+			s.handle(ctx, res)
 		}
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/sznuper/sznuper/internal/config"
 	"github.com/sznuper/sznuper/internal/cooldown"
 	"github.com/sznuper/sznuper/internal/runner"
+	"github.com/sznuper/sznuper/internal/state"
 )
 
 // OnResult is called with each alert result as it completes.
@@ -21,23 +22,39 @@ type Scheduler struct {
 	runner   *runner.Runner
 	logger   *slog.Logger
 	onResult OnResult
+
+	mu sync.Mutex
+	// Set by Start for re-evaluating alerts when a dependency recovers.
+	alerts    []config.Alert
+	opts      StartOpts
+	inhibited map[string]map[string]bool // parent alert -> alerts it inhibited
+	bg        sync.WaitGroup             // re-evaluation runs
 }
 
-// New creates a Scheduler.
+// New creates a Scheduler. It takes over r's result hook, so results r
+// produces on its own, e.g. group and escalation notifications, reach
+// onResult too.
 func New(r *runner.Runner, logger *slog.Logger, onResult OnResult) *Scheduler {
-	return &Scheduler{runner: r, logger: logger, onResult: onResult}
+	s := &Scheduler{runner: r, logger: logger, onResult: onResult, inhibited: make(map[string]map[string]bool)}
+	r.SetResultHook(s.handle)
+	return s
 }
 
 // StartOpts holds options for Scheduler.Start.
 type StartOpts struct {
 	DryRun        bool
-	SkipLifecycle bool // suppress started/stopped lifecycle events (used during reload)
+	SkipLifecycle bool         // suppress started/stopped lifecycle events (used during reload)
+	Store         *state.Store // carries alert state across reloads (nil = fresh state per Start)
 }
 
 // Start launches one goroutine per alert and blocks until ctx is done.
 // Lifecycle alerts fire at start (before loops) and stop (after loops exit),
 // unless SkipLifecycle is set.
 func (s *Scheduler) Start(ctx context.Context, alerts []config.Alert, opts StartOpts) {
+	s.mu.Lock()
+	s.alerts, s.opts = alerts, opts
+	s.mu.Unlock()
+
 	var lifecycle, regular []config.Alert
 	for _, a := range alerts {
 		if HasLifecycleTrigger(a.Triggers) {
@@ -49,6 +66,13 @@ func (s *Scheduler) Start(ctx context.Context, alerts []config.Alert, opts Start
 
 	totalAlerts := len(alerts)
 
+	// Incidents restored from the store keep escalating.
+	if opts.Store != nil {
+		for i := range alerts {
+			s.runner.ResumeEscalations(ctx, &alerts[i], buildRunOpts(&alerts[i], opts.DryRun, opts.Store))
+		}
+	}
+
 	if !opts.SkipLifecycle {
 		// Fire lifecycle alerts with event=started (blocking).
 		s.FireLifecycle(ctx, lifecycle, "started", totalAlerts, opts.DryRun)
@@ -60,10 +84,11 @@ func (s *Scheduler) Start(ctx context.Context, alerts []config.Alert, opts Start
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
-			s.runAlertLoop(ctx, &regular[i], opts.DryRun)
+			s.runAlertLoop(ctx, &regular[i], opts.DryRun, opts.Store)
 		}(i)
 	}
 	wg.Wait()
+	s.bg.Wait()
 
 	if !opts.SkipLifecycle {
 		// Fire lifecycle alerts with event=stopped (fresh context so HTTP still works).
@@ -82,17 +107,92 @@ func (s *Scheduler) FireLifecycle(ctx context.Context, alerts []config.Alert, ev
 	for i := range alerts {
 		for result := range s.runner.RunAlertOpts(ctx, &alerts[i], runner.RunOpts{
 			DryRun:        dryRun,
+			TriggerType:   "lifecycle",
 			BuiltinParams: params,
 		}) {
-			if s.onResult != nil {
-				s.onResult(result)
+			s.handle(ctx, result)
+		}
+	}
+}
+
+// Trigger runs alert once, outside its schedule. With opts.Store set it
+// shares cooldown and state with the alert's scheduled runs. Results are
+// passed to the OnResult callback and returned.
+func (s *Scheduler) Trigger(ctx context.Context, alert *config.Alert, opts StartOpts) []runner.Result {
+	return s.runOnce(ctx, alert, opts, "manual")
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, alert *config.Alert, opts StartOpts, triggerType string) []runner.Result {
+	callOpts := buildRunOpts(alert, opts.DryRun, opts.Store)
+	callOpts.TriggerType = triggerType
+	var results []runner.Result
+	for res := range s.runner.RunAlertOpts(ctx, alert, callOpts) {
+		s.handle(ctx, res)
+		results = append(results, res)
+	}
+	return results
+}
+
+// handle passes a result to the OnResult callback. It also remembers which
+// alerts a dependency inhibited, and re-evaluates those with
+// depends_on.reevaluate once the dependency recovers.
+func (s *Scheduler) handle(ctx context.Context, res runner.Result) {
+	if s.onResult != nil {
+		s.onResult(res)
+	}
+	switch {
+	case res.Inhibited:
+		s.mu.Lock()
+		if s.inhibited[res.InhibitedBy] == nil {
+			s.inhibited[res.InhibitedBy] = make(map[string]bool)
+		}
+		s.inhibited[res.InhibitedBy][res.AlertName] = true
+		s.mu.Unlock()
+	case res.IsRecovery:
+		s.mu.Lock()
+		children := s.inhibited[res.AlertName]
+		delete(s.inhibited, res.AlertName)
+		alerts, opts := s.alerts, s.opts
+		s.mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		for i := range alerts {
+			alert := &alerts[i]
+			if children[alert.Name] && reevaluates(alert, res.AlertName) {
+				s.logger.Info("re-evaluating alert after dependency recovered", "alert", alert.Name, "dependency", res.AlertName)
+				s.bg.Add(1)
+				go func() {
+					defer s.bg.Done()
+					s.runOnce(ctx, alert, opts, "reevaluate")
+				}()
 			}
 		}
 	}
 }
 
-func (s *Scheduler) runAlertLoop(ctx context.Context, alert *config.Alert, dryRun bool) {
-	opts := buildRunOpts(alert, dryRun)
+// reevaluates reports whether alert asks to be re-run when parent recovers.
+// Alerts fed by watch or pipe triggers need their input and are never re-run.
+func reevaluates(alert *config.Alert, parent string) bool {
+	scheduled := false
+	for _, t := range alert.Triggers {
+		if t.Interval != "" || t.Cron != "" {
+			scheduled = true
+		}
+	}
+	if !scheduled {
+		return false
+	}
+	for _, dep := range alert.DependsOn {
+		if dep.Alert == parent && dep.Reevaluate {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scheduler) runAlertLoop(ctx context.Context, alert *config.Alert, dryRun bool, store *state.Store) {
+	opts := buildRunOpts(alert, dryRun, store)
 
 	if len(alert.Triggers) == 0 {
 		s.logger.Warn("skipping: no triggers configured", "alert", alert.Name)
@@ -117,9 +217,7 @@ func (s *Scheduler) runTrigger(ctx context.Context, alert *config.Alert, trigger
 		callOpts := opts
 		callOpts.TriggerType = triggerType
 		for result := range s.runner.RunAlertOpts(ctx, alert, callOpts) {
-			if s.onResult != nil {
-				s.onResult(result)
-			}
+			s.handle(ctx, result)
 		}
 	}
 
@@ -177,11 +275,8 @@ func detectTriggerType(t config.Trigger) string {
 	}
 }
 
-// cronParser accepts both 5-field (minute-level) and 6-field (with seconds) expressions.
-var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-
 func (s *Scheduler) runCronLoop(ctx context.Context, alertName, expr string, fire func()) {
-	cr := cron.New(cron.WithParser(cronParser))
+	cr := cron.New(cron.WithParser(config.CronParser))
 	if _, err := cr.AddFunc(expr, fire); err != nil {
 		s.logger.Warn("skipping: invalid cron expression", "alert", alertName, "cron", expr, "error", err)
 		return
@@ -191,7 +286,17 @@ func (s *Scheduler) runCronLoop(ctx context.Context, alertName, expr string, fir
 	cr.Stop()
 }
 
-func buildRunOpts(alert *config.Alert, dryRun bool) runner.RunOpts {
+func buildRunOpts(alert *config.Alert, dryRun bool, store *state.Store) runner.RunOpts {
+	if store != nil {
+		e := store.Entry(alert)
+		return runner.RunOpts{
+			DryRun:       dryRun,
+			Cooldown:     e.Cooldown,
+			State:        e.State,
+			Entities:     e.Entities,
+			Dependencies: store,
+		}
+	}
 	opts := runner.RunOpts{
 		DryRun:   dryRun,
 		Cooldown: cooldown.New(nil),
@@ -199,5 +304,8 @@ func buildRunOpts(alert *config.Alert, dryRun bool) runner.RunOpts {
 	if alert.Events != nil && len(alert.Events.Healthy) > 0 {
 		opts.State = &runner.AlertState{Healthy: true}
 	}
+	if alert.Events != nil && len(alert.Events.Key) > 0 {
+		opts.Entities = runner.NewEntitySet()
+	}
 	return opts
 }
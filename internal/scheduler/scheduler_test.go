@@ -13,6 +13,7 @@ import (
 
 	"github.com/sznuper/sznuper/internal/config"
 	"github.com/sznuper/sznuper/internal/runner"
+	"github.com/sznuper/sznuper/internal/state"
 )
 
 func writeScript(t *testing.T, dir string) {
@@ -99,7 +100,7 @@ func TestScheduler_CronFires(t *testing.T) {
 }
 
 func TestScheduler_CronFivefield_Fires(t *testing.T) {
-	schedule, err := cronParser.Parse("* * * * *")
+	schedule, err := config.CronParser.Parse("* * * * *")
 	if err != nil {
 		t.Fatalf("parsing 5-field cron: %v", err)
 	}
@@ -644,3 +645,163 @@ func TestScheduler_SkipLifecycle_NoStartedStopped(t *testing.T) {
 		t.Error("interval alert did not fire with SkipLifecycle: true")
 	}
 }
+
+func TestScheduler_Store_CarriesStateAcrossStarts(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "check.sh"), []byte("#!/bin/sh\necho '--- event'\necho type=high_usage\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Options: config.Options{HealthchecksDir: dir},
+		Globals: map[string]any{},
+		Alerts: []config.Alert{
+			{
+				Name:        "disk",
+				Healthcheck: "file://check.sh",
+				Triggers:    []config.Trigger{{Interval: "1h"}},
+				Template:    "test",
+				Cooldown:    "inf",
+				Notify:      []config.NotifyTarget{{Channel: "logger"}},
+				Events:      &config.Events{Healthy: []string{"ok"}},
+			},
+		},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+	}
+
+	store := state.New("")
+	run := func() runner.Result {
+		var mu sync.Mutex
+		var got runner.Result
+		sched := New(newRunner(t, cfg), slog.Default(), func(res runner.Result) {
+			mu.Lock()
+			got = res
+			mu.Unlock()
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		sched.Start(ctx, cfg.Alerts, StartOpts{DryRun: true, SkipLifecycle: true, Store: store})
+		mu.Lock()
+		defer mu.Unlock()
+		return got
+	}
+
+	if first := run(); first.Suppressed {
+		t.Fatal("first start: want notification, got suppressed")
+	}
+	if second := run(); !second.Suppressed {
+		t.Error("second start: want suppressed by restored cooldown")
+	}
+	if store.Entry(&cfg.Alerts[0]).State.Healthy {
+		t.Error("want alert to remain unhealthy across starts")
+	}
+}
+
+func TestScheduler_Trigger_SharesStoreCooldown(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir)
+
+	cfg := &config.Config{
+		Options: config.Options{HealthchecksDir: dir},
+		Globals: map[string]any{},
+		Alerts: []config.Alert{
+			{
+				Name:        "manual",
+				Healthcheck: "file://check.sh",
+				Triggers:    []config.Trigger{{Interval: "1h"}},
+				Cooldown:    "1h",
+				Template:    "test",
+				Notify:      []config.NotifyTarget{{Channel: "logger"}},
+			},
+		},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+	}
+
+	var seen atomic.Int32
+	sched := New(newRunner(t, cfg), slog.Default(), func(runner.Result) {
+		seen.Add(1)
+	})
+	opts := StartOpts{DryRun: true, Store: state.New("")}
+
+	first := sched.Trigger(context.Background(), &cfg.Alerts[0], opts)
+	if len(first) != 1 || first[0].Suppressed {
+		t.Fatalf("first trigger = %+v, want one notified result", first)
+	}
+	second := sched.Trigger(context.Background(), &cfg.Alerts[0], opts)
+	if len(second) != 1 || !second[0].Suppressed {
+		t.Fatalf("second trigger = %+v, want suppressed by the shared cooldown", second)
+	}
+	if seen.Load() != 2 {
+		t.Errorf("onResult calls = %d, want 2", seen.Load())
+	}
+}
+
+func TestScheduler_DependsOn_ReevaluatesOnRecovery(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir)
+
+	cfg := &config.Config{
+		Options: config.Options{HealthchecksDir: dir},
+		Globals: map[string]any{},
+		Alerts: []config.Alert{
+			{
+				Name:        "uplink",
+				Healthcheck: "builtin://ok",
+				Triggers:    []config.Trigger{{Cron: "0 0 1 1 *"}}, // not during the test
+				Events:      &config.Events{Healthy: []string{"ok"}},
+				Template:    "test",
+			},
+			{
+				Name:        "nginx",
+				Healthcheck: "file://check.sh",
+				Triggers:    []config.Trigger{{Interval: "1h"}},
+				DependsOn:   []config.Dependency{{Alert: "uplink", Reevaluate: true}},
+				Template:    "test",
+				Notify:      []config.NotifyTarget{{Channel: "logger"}},
+			},
+		},
+		Channels: map[string]config.Channel{"logger": {URL: "logger://"}},
+	}
+
+	store := state.New("")
+	store.Entry(&cfg.Alerts[0]).State.Healthy = false
+
+	results := make(chan runner.Result, 10)
+	sched := New(newRunner(t, cfg), slog.Default(), func(res runner.Result) {
+		results <- res
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := StartOpts{DryRun: true, SkipLifecycle: true, Store: store}
+	done := make(chan struct{})
+	go func() {
+		sched.Start(ctx, cfg.Alerts, opts)
+		close(done)
+	}()
+
+	next := func() runner.Result {
+		t.Helper()
+		select {
+		case res := <-results:
+			return res
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a result")
+			return runner.Result{}
+		}
+	}
+
+	if res := next(); res.AlertName != "nginx" || !res.Inhibited || res.InhibitedBy != "uplink" {
+		t.Fatalf("first result = %+v, want nginx inhibited by uplink", res)
+	}
+
+	sched.Trigger(ctx, &cfg.Alerts[0], opts)
+	if res := next(); res.AlertName != "uplink" || !res.IsRecovery {
+		t.Fatalf("parent result = %+v, want recovery", res)
+	}
+	if res := next(); res.AlertName != "nginx" || res.Inhibited || len(res.Notified) != 1 {
+		t.Fatalf("re-evaluated result = %+v, want nginx notified", res)
+	}
+
+	cancel()
+	<-done
+}
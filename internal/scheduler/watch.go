@@ -71,6 +71,7 @@ func (s *Scheduler) runWatchLoop(ctx context.Context, alert *config.Alert, trigg
 				if info, err := os.Stat(path); err == nil && info.Size() < offset {
 					offset = 0
 					_, _ = f.Seek(0, io.SeekStart)
+					s.runner.Metrics().TriggerRestarted(alert.Name, "watch")
 				}
 				newData, _ := io.ReadAll(f)
 				offset += int64(len(newData))
@@ -95,6 +96,7 @@ func (s *Scheduler) runWatchLoop(ctx context.Context, alert *config.Alert, trigg
 				offset = 0
 				if f != nil {
 					_ = watcher.Add(path)
+					s.runner.Metrics().TriggerRestarted(alert.Name, "watch")
 				}
 			}
 
@@ -108,15 +110,14 @@ func (s *Scheduler) runWatchLoop(ctx context.Context, alert *config.Alert, trigg
 			s.logger.Warn("watch: fsnotify error", "alert", alert.Name, "error", err)
 
 		case res, ok := <-resultCh:
-			if s.onResult != nil {
-				s.onResult(res)
-			}
 			if !ok {
 				resultCh = nil
 				if len(buf) > 0 {
 					fire()
 				}
+				continue
 			}
+			s.handle(ctx, res)
 		}
 	}
 }
@@ -0,0 +1,136 @@
+// Package secret tracks values read from secret files and the credentials of
+// channel URLs, and redacts them from text the program shows or stores:
+// logs, command output, error messages.
+//
+// The set is process-wide and only grows, so output produced after a
+// reload still hides secrets the previous config used.
+package secret
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secret values.
+const Redacted = "[REDACTED]"
+
+// MinLength is the shortest value that is tracked. Shorter values would
+// redact unrelated text.
+const MinLength = 4
+
+var (
+	mu       sync.RWMutex
+	values   []string // longest first, so overlapping secrets redact fully
+	replacer *strings.Replacer
+)
+
+// Register marks v as secret. Its URL-escaped forms are tracked too, since
+// secrets often end up in channel URLs.
+func Register(v string) {
+	if len(v) < MinLength {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, form := range []string{v, url.QueryEscape(v), url.PathEscape(v)} {
+		if !slices.Contains(values, form) {
+			values = append(values, form)
+		}
+	}
+	slices.SortFunc(values, func(a, b string) int { return len(b) - len(a) })
+	pairs := make([]string, 0, 2*len(values))
+	for _, s := range values {
+		pairs = append(pairs, s, Redacted)
+	}
+	replacer = strings.NewReplacer(pairs...)
+}
+
+// RegisterURL marks the credentials in a URL as secret: the password of its
+// userinfo, or the user name when there is none, and the values of query
+// parameters with credential names, see IsCredentialName.
+func RegisterURL(raw string) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return
+	}
+	if u.User != nil {
+		if p, ok := u.User.Password(); ok {
+			Register(p)
+		} else {
+			Register(u.User.Username())
+		}
+	}
+	for name, vs := range u.Query() {
+		if IsCredentialName(name) {
+			for _, v := range vs {
+				Register(v)
+			}
+		}
+	}
+}
+
+// IsCredentialName reports whether a parameter name suggests a credential:
+// it mentions a token, password, secret or key.
+func IsCredentialName(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"token", "password", "secret", "key"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns s with every secret replaced by Redacted.
+func Redact(s string) string {
+	mu.RLock()
+	r := replacer
+	mu.RUnlock()
+	if r == nil {
+		return s
+	}
+	return r.Replace(s)
+}
+
+// Active reports whether any secret is registered.
+func Active() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return replacer != nil
+}
+
+// Contains reports whether s contains a secret.
+func Contains(s string) bool {
+	return Redact(s) != s
+}
+
+// RedactError returns err with a redacted message. errors.Is and errors.As
+// still see the original error. A nil err stays nil, and an error without
+// secrets is returned as is.
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if red := Redact(msg); red != msg {
+		return &redactedError{msg: red, err: err}
+	}
+	return err
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// reset clears the set, for tests.
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	values, replacer = nil, nil
+}
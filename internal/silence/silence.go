@@ -0,0 +1,239 @@
+// Package silence suppresses notifications and side effects for matching
+// events: recurring or one-off maintenance windows declared in the config,
+// and ad-hoc silences created at runtime and persisted in the state dir.
+package silence
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+var (
+	// ErrNotFound is returned for unknown silence IDs.
+	ErrNotFound = errors.New("silence not found")
+	// ErrInvalid is returned by Store.Add for malformed silences.
+	ErrInvalid = errors.New("invalid silence")
+)
+
+// Matcher selects events by alert name, event type and event fields. Each
+// value is a glob pattern ("*" and "?"); empty matches anything.
+type Matcher struct {
+	Alert     string            `json:"alert,omitempty"`
+	EventType string            `json:"event_type,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// Empty reports whether the matcher matches every event.
+func (m Matcher) Empty() bool {
+	return m.Alert == "" && m.EventType == "" && len(m.Fields) == 0
+}
+
+// Matches reports whether an event matches. A field pattern never matches
+// an event that lacks the field.
+func (m Matcher) Matches(alert, eventType string, fields map[string]string) bool {
+	if m.Alert != "" && !glob(m.Alert, alert) {
+		return false
+	}
+	if m.EventType != "" && !glob(m.EventType, eventType) {
+		return false
+	}
+	for k, pattern := range m.Fields {
+		v, ok := fields[k]
+		if !ok || !glob(pattern, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Silence is a silence over a time range. Ad-hoc silences carry a
+// generated ID; active config windows are reported with their name as ID.
+type Silence struct {
+	ID        string    `json:"id"`
+	Match     Matcher   `json:"match"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	Config    bool      `json:"config,omitempty"` // maintenance window from the config file
+}
+
+// Active reports whether the silence applies at now.
+func (s Silence) Active(now time.Time) bool {
+	return !now.Before(s.Start) && now.Before(s.End)
+}
+
+// Window is a maintenance window from the config file.
+type Window struct {
+	Name     string
+	Match    Matcher
+	Comment  string
+	schedule cron.Schedule // recurring windows
+	duration time.Duration
+	start    time.Time // one-off windows; zero = already started
+	end      time.Time
+}
+
+// Compile parses the config's silences. Invalid entries are reported
+// together and left out of the returned windows.
+func Compile(silences []config.Silence) ([]*Window, error) {
+	var windows []*Window
+	var errs []error
+	for _, s := range silences {
+		w, err := compile(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("silence %q: %w", s.Name, err))
+			continue
+		}
+		windows = append(windows, w)
+	}
+	return windows, errors.Join(errs...)
+}
+
+func compile(s config.Silence) (*Window, error) {
+	cw, err := s.Window()
+	if err != nil {
+		return nil, err
+	}
+	return &Window{
+		Name:    s.Name,
+		Comment: s.Comment,
+		Match: Matcher{
+			Alert:     s.Match.Alert,
+			EventType: s.Match.EventType,
+			Fields:    s.Match.Fields,
+		},
+		schedule: cw.Schedule,
+		duration: cw.Duration,
+		start:    cw.Start,
+		end:      cw.End,
+	}, nil
+}
+
+// maxOccurrences bounds the scan for overlapping occurrences of a recurring
+// window whose schedule fires more often than its duration.
+const maxOccurrences = 10000
+
+// Current returns the window's occurrence that is active at now.
+func (w *Window) Current(now time.Time) (Silence, bool) {
+	sil := Silence{ID: w.Name, Match: w.Match, Comment: w.Comment, Config: true}
+	if w.schedule == nil {
+		sil.Start, sil.End = w.start, w.end
+		return sil, sil.Active(now)
+	}
+
+	// An occurrence at t covers [t, t+duration), so the active ones fired
+	// in (now-duration, now]. The first gives the start, the last the end.
+	t := w.schedule.Next(now.Add(-w.duration))
+	if t.After(now) {
+		return Silence{}, false
+	}
+	sil.Start = t
+	for range maxOccurrences {
+		sil.End = t.Add(w.duration)
+		if t = w.schedule.Next(t); t.After(now) {
+			break
+		}
+	}
+	return sil, true
+}
+
+// Set combines the config's maintenance windows with ad-hoc silences. A nil
+// Set silences nothing.
+type Set struct {
+	windows []*Window
+	store   *Store
+}
+
+// NewSet creates a Set. store may be nil.
+func NewSet(windows []*Window, store *Store) *Set {
+	return &Set{windows: windows, store: store}
+}
+
+// Match returns the silence that applies to an event at now.
+func (s *Set) Match(alert, eventType string, fields map[string]string, now time.Time) (Silence, bool) {
+	if s == nil {
+		return Silence{}, false
+	}
+	for _, w := range s.windows {
+		if !w.Match.Matches(alert, eventType, fields) {
+			continue
+		}
+		if sil, ok := w.Current(now); ok {
+			return sil, true
+		}
+	}
+	if s.store != nil {
+		return s.store.Match(alert, eventType, fields, now)
+	}
+	return Silence{}, false
+}
+
+// List returns the active config windows followed by the ad-hoc silences
+// that have not ended yet.
+func (s *Set) List(now time.Time) []Silence {
+	if s == nil {
+		return nil
+	}
+	var out []Silence
+	for _, w := range s.windows {
+		if sil, ok := w.Current(now); ok {
+			out = append(out, sil)
+		}
+	}
+	if s.store != nil {
+		out = append(out, s.store.List(now)...)
+	}
+	return out
+}
+
+// glob matches s against pattern, where "*" matches any run of characters
+// (including "/") and "?" matches one character.
+func glob(pattern, s string) bool {
+	p, str := []rune(pattern), []rune(s)
+	pi, si := 0, 0
+	star, mark := -1, 0
+	for si < len(str) {
+		switch {
+		case pi < len(p) && (p[pi] == '?' || p[pi] == str[si]):
+			pi++
+			si++
+		case pi < len(p) && p[pi] == '*':
+			star, mark = pi, si
+			pi++
+		case star >= 0:
+			pi = star + 1
+			mark++
+			si = mark
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// cloneMatcher copies m so stored silences don't share maps with callers.
+func cloneMatcher(m Matcher) Matcher {
+	m.Fields = maps.Clone(m.Fields)
+	return m
+}
+
+func sortSilences(list []Silence) {
+	slices.SortFunc(list, func(a, b Silence) int {
+		if c := a.Start.Compare(b.Start); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
@@ -0,0 +1,218 @@
+package silence
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"disk", "disk", true},
+		{"disk", "disks", false},
+		{"disk_*", "disk_root", true},
+		{"*_usage", "high_usage", true},
+		{"*", "", true},
+		{"/backup*", "/backup/2026", true},
+		{"/var/*/log", "/var/lib/x/log", true},
+		{"h?gh", "high", true},
+		{"h?gh", "hgh", false},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*b*c", "aXbYbZ", false},
+	}
+	for _, tt := range tests {
+		if got := glob(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("glob(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	m := Matcher{Alert: "disk_*", EventType: "high_usage", Fields: map[string]string{"mount": "/backup*"}}
+	tests := []struct {
+		name      string
+		alert     string
+		eventType string
+		fields    map[string]string
+		want      bool
+	}{
+		{"all match", "disk_check", "high_usage", map[string]string{"mount": "/backup/a"}, true},
+		{"alert differs", "memory", "high_usage", map[string]string{"mount": "/backup"}, false},
+		{"type differs", "disk_check", "ok", map[string]string{"mount": "/backup"}, false},
+		{"field differs", "disk_check", "high_usage", map[string]string{"mount": "/"}, false},
+		{"field missing", "disk_check", "high_usage", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Matches(tt.alert, tt.eventType, tt.fields); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if !(Matcher{}).Matches("anything", "any", nil) {
+		t.Error("empty matcher should match everything")
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		silence config.Silence
+		want    string
+	}{
+		{"nothing", config.Silence{}, "one of cron, end or duration is required"},
+		{"cron without duration", config.Silence{Cron: "0 2 * * *"}, "cron requires a duration"},
+		{"cron with start", config.Silence{Cron: "0 2 * * *", Duration: "1h", Start: "2026-03-01T02:00:00Z"}, "cannot be combined"},
+		{"bad cron", config.Silence{Cron: "every night", Duration: "1h"}, "invalid cron"},
+		{"bad duration", config.Silence{Cron: "0 2 * * *", Duration: "soon"}, "invalid duration"},
+		{"duration alone", config.Silence{Duration: "1h"}, "duration requires cron or start"},
+		{"end and duration", config.Silence{Start: "2026-03-01T02:00:00Z", End: "2026-03-01T03:00:00Z", Duration: "1h"}, "mutually exclusive"},
+		{"bad start", config.Silence{Start: "tomorrow", End: "2026-03-01T03:00:00Z"}, "invalid start"},
+		{"end before start", config.Silence{Start: "2026-03-01T03:00:00Z", End: "2026-03-01T02:00:00Z"}, "end must be after start"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.silence.Name = "w"
+			windows, err := Compile([]config.Silence{tt.silence})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+			if !strings.HasPrefix(err.Error(), `silence "w": `) {
+				t.Errorf("err = %q, want silence name prefix", err)
+			}
+			if len(windows) != 0 {
+				t.Errorf("windows = %v, want none", windows)
+			}
+		})
+	}
+}
+
+func TestCompile_KeepsValid(t *testing.T) {
+	windows, err := Compile([]config.Silence{
+		{Name: "bad", Duration: "1h"},
+		{Name: "nightly", Cron: "0 2 * * *", Duration: "1h"},
+	})
+	if err == nil {
+		t.Fatal("expected error for the invalid window")
+	}
+	if len(windows) != 1 || windows[0].Name != "nightly" {
+		t.Errorf("windows = %v, want [nightly]", windows)
+	}
+}
+
+func TestWindow_Recurring(t *testing.T) {
+	windows, err := Compile([]config.Silence{{Name: "nightly", Cron: "0 2 * * *", Duration: "1h"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := windows[0]
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		at   time.Duration
+		want bool
+	}{
+		{time.Hour + 59*time.Minute, false},
+		{2 * time.Hour, true},
+		{2*time.Hour + 30*time.Minute, true},
+		{3 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		sil, ok := w.Current(day.Add(tt.at))
+		if ok != tt.want {
+			t.Errorf("at %v: active = %v, want %v", tt.at, ok, tt.want)
+		}
+		if ok && (!sil.Start.Equal(day.Add(2*time.Hour)) || !sil.End.Equal(day.Add(3*time.Hour)) || !sil.Config || sil.ID != "nightly") {
+			t.Errorf("at %v: silence = %+v", tt.at, sil)
+		}
+	}
+}
+
+func TestWindow_RecurringOverlapping(t *testing.T) {
+	// Fires every 15 minutes with a 1h duration: always active, and the end
+	// follows the latest occurrence.
+	windows, err := Compile([]config.Silence{{Name: "busy", Cron: "*/15 * * * *", Duration: "1h"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 1, 12, 40, 0, 0, time.Local)
+	sil, ok := windows[0].Current(now)
+	if !ok {
+		t.Fatal("expected active")
+	}
+	if want := now.Add(-55 * time.Minute); !sil.Start.Equal(want) {
+		t.Errorf("start = %v, want %v", sil.Start, want)
+	}
+	if want := time.Date(2026, 3, 1, 13, 30, 0, 0, time.Local); !sil.End.Equal(want) {
+		t.Errorf("end = %v, want %v", sil.End, want)
+	}
+}
+
+func TestWindow_OneOff(t *testing.T) {
+	windows, err := Compile([]config.Silence{
+		{Name: "migration", Start: "2026-03-01T02:00:00Z", Duration: "2h"},
+		{Name: "freeze", End: "2026-03-01T00:00:00Z"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migration, freeze := windows[0], windows[1]
+	at := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	if _, ok := migration.Current(at("2026-03-01T01:59:59Z")); ok {
+		t.Error("migration active before start")
+	}
+	if sil, ok := migration.Current(at("2026-03-01T03:00:00Z")); !ok || !sil.End.Equal(at("2026-03-01T04:00:00Z")) {
+		t.Errorf("migration = %+v, %v", sil, ok)
+	}
+	if _, ok := migration.Current(at("2026-03-01T04:00:00Z")); ok {
+		t.Error("migration active at end")
+	}
+	if _, ok := freeze.Current(at("2026-02-01T00:00:00Z")); !ok {
+		t.Error("freeze without start should be active until its end")
+	}
+}
+
+func TestSet_Match(t *testing.T) {
+	windows, err := Compile([]config.Silence{{Name: "nightly", Match: config.SilenceMatch{Alert: "backup"}, Cron: "0 2 * * *", Duration: "1h"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore("")
+	now := time.Date(2026, 3, 1, 2, 30, 0, 0, time.Local)
+	adhoc, err := store.Add(Silence{Match: Matcher{EventType: "high_usage"}, End: now.Add(time.Hour)}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := NewSet(windows, store)
+
+	if sil, ok := set.Match("backup", "failed", nil, now); !ok || sil.ID != "nightly" {
+		t.Errorf("window match = %+v, %v", sil, ok)
+	}
+	if sil, ok := set.Match("disk", "high_usage", nil, now); !ok || sil.ID != adhoc.ID {
+		t.Errorf("ad-hoc match = %+v, %v", sil, ok)
+	}
+	if _, ok := set.Match("disk", "ok", nil, now); ok {
+		t.Error("unexpected match")
+	}
+	if list := set.List(now); len(list) != 2 || !list[0].Config || list[1].ID != adhoc.ID {
+		t.Errorf("list = %+v", list)
+	}
+
+	var nilSet *Set
+	if _, ok := nilSet.Match("disk", "high_usage", nil, now); ok {
+		t.Error("nil set matched")
+	}
+}
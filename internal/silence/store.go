@@ -0,0 +1,169 @@
+package silence
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the ad-hoc silence file inside options.state_dir.
+const FileName = "silences.json"
+
+// Store holds ad-hoc silences, persisted to dir/silences.json so they
+// survive restarts. Silences that have ended are dropped on save.
+type Store struct {
+	path     string // empty = in-memory only
+	mu       sync.Mutex
+	silences []Silence
+}
+
+// NewStore creates an empty Store backed by dir/silences.json. An empty dir
+// yields an in-memory store that never persists.
+func NewStore(dir string) *Store {
+	s := &Store{}
+	if dir != "" {
+		s.path = filepath.Join(dir, FileName)
+	}
+	return s
+}
+
+// Load reads previously saved silences. A missing file is not an error.
+func (s *Store) Load() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading silences: %w", err)
+	}
+	var list []Silence
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing silences %s: %w", s.path, err)
+	}
+	s.mu.Lock()
+	s.silences = list
+	s.mu.Unlock()
+	return nil
+}
+
+// Path returns the silence file path, or "" for an in-memory store.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Add validates sil, assigns it an ID and saves the store. A zero Start
+// means now.
+func (s *Store) Add(sil Silence, now time.Time) (Silence, error) {
+	if sil.Match.Empty() {
+		return Silence{}, fmt.Errorf("%w: at least one matcher is required (use alert \"*\" to silence everything)", ErrInvalid)
+	}
+	if sil.Start.IsZero() {
+		sil.Start = now
+	}
+	if !sil.End.After(sil.Start) {
+		return Silence{}, fmt.Errorf("%w: end must be after start", ErrInvalid)
+	}
+	if !sil.End.After(now) {
+		return Silence{}, fmt.Errorf("%w: end is in the past", ErrInvalid)
+	}
+	sil.ID = newID()
+	sil.Match = cloneMatcher(sil.Match)
+	sil.CreatedAt = now
+	sil.Config = false
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.save(append(slices.Clone(s.silences), sil), now); err != nil {
+		return Silence{}, err
+	}
+	return sil, nil
+}
+
+// Expire removes the silence with the given ID and saves the store.
+func (s *Store) Expire(id string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sil := range s.silences {
+		if sil.ID == id {
+			return s.save(slices.Delete(slices.Clone(s.silences), i, i+1), now)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// List returns the silences that have not ended at now, active or
+// scheduled, ordered by start.
+func (s *Store) List(now time.Time) []Silence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Silence
+	for _, sil := range s.silences {
+		if now.Before(sil.End) {
+			out = append(out, sil)
+		}
+	}
+	sortSilences(out)
+	return out
+}
+
+// Match returns the first silence that is active at now and matches the
+// event.
+func (s *Store) Match(alert, eventType string, fields map[string]string, now time.Time) (Silence, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sil := range s.silences {
+		if sil.Active(now) && sil.Match.Matches(alert, eventType, fields) {
+			return sil, true
+		}
+	}
+	return Silence{}, false
+}
+
+// save drops ended silences from list, atomically writes the rest and makes
+// them the store's silences. If writing fails the store is left as it was.
+// Caller holds s.mu.
+func (s *Store) save(list []Silence, now time.Time) error {
+	kept := make([]Silence, 0, len(list))
+	for _, sil := range list {
+		if now.Before(sil.End) {
+			kept = append(kept, sil)
+		}
+	}
+
+	if s.path == "" {
+		s.silences = kept
+		return nil
+	}
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding silences: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing silences: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("writing silences: %w", err)
+	}
+	s.silences = kept
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,136 @@
+package silence
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_AddValidation(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := NewStore("")
+	tests := []struct {
+		name string
+		sil  Silence
+	}{
+		{"no matcher", Silence{End: now.Add(time.Hour)}},
+		{"end before start", Silence{Match: Matcher{Alert: "*"}, Start: now, End: now.Add(-time.Minute)}},
+		{"ended", Silence{Match: Matcher{Alert: "*"}, Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Add(tt.sil, now); !errors.Is(err, ErrInvalid) {
+				t.Errorf("err = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestStore_AddSaveFails(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	// The state directory is a file, so the silence can't be saved.
+	dir := filepath.Join(t.TempDir(), "state")
+	if err := os.WriteFile(dir, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(dir)
+	if _, err := s.Add(Silence{Match: Matcher{Alert: "*"}, End: now.Add(time.Hour)}, now); err == nil {
+		t.Fatal("Add with an unwritable state dir = nil error")
+	}
+	if got := s.List(now); len(got) != 0 {
+		t.Errorf("silences after failed Add = %+v, want none", got)
+	}
+	if _, ok := s.Match("disk", "high", nil, now); ok {
+		t.Error("silence from failed Add matches")
+	}
+}
+
+func TestStore_PersistsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	s := NewStore(dir)
+	fields := map[string]string{"mount": "/"}
+	kept, err := s.Add(Silence{Match: Matcher{Alert: "disk", Fields: fields}, End: now.Add(time.Hour), Comment: "resize"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields["mount"] = "/changed"
+	if kept.Start != now || kept.ID == "" {
+		t.Errorf("kept = %+v", kept)
+	}
+	scheduled, err := s.Add(Silence{Match: Matcher{Alert: "cpu"}, Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode = %v, want 0600", perm)
+	}
+
+	restored := NewStore(dir)
+	if err := restored.Load(); err != nil {
+		t.Fatal(err)
+	}
+	list := restored.List(now)
+	if len(list) != 2 || list[0].ID != kept.ID || list[1].ID != scheduled.ID {
+		t.Fatalf("list = %+v", list)
+	}
+	if list[0].Match.Fields["mount"] != "/" || list[0].Comment != "resize" {
+		t.Errorf("restored = %+v", list[0])
+	}
+
+	if _, ok := restored.Match("disk", "high_usage", map[string]string{"mount": "/"}, now); !ok {
+		t.Error("active silence did not match")
+	}
+	if _, ok := restored.Match("cpu", "high_usage", nil, now); ok {
+		t.Error("scheduled silence matched before its start")
+	}
+	if _, ok := restored.Match("cpu", "high_usage", nil, now.Add(90*time.Minute)); !ok {
+		t.Error("scheduled silence did not match once started")
+	}
+}
+
+func TestStore_ExpireAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := NewStore(dir)
+
+	short, _ := s.Add(Silence{Match: Matcher{Alert: "a"}, End: now.Add(time.Minute)}, now)
+	long, _ := s.Add(Silence{Match: Matcher{Alert: "b"}, End: now.Add(time.Hour)}, now)
+
+	if err := s.Expire("nope", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+	// Expiring after the short silence ended also drops it from disk.
+	if err := s.Expire(long.ID, now.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewStore(dir)
+	if err := restored.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if list := restored.List(now); len(list) != 0 {
+		t.Errorf("list = %+v, want empty (short %s ended, long %s expired)", list, short.ID, long.ID)
+	}
+}
+
+func TestStore_LoadMissingAndCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewStore(dir).Load(); err != nil {
+		t.Errorf("missing file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewStore(dir).Load(); err == nil {
+		t.Error("expected error for corrupt file")
+	}
+}
@@ -0,0 +1,237 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+	"github.com/sznuper/sznuper/internal/cooldown"
+	"github.com/sznuper/sznuper/internal/runner"
+)
+
+// FileName is the name of the state snapshot inside options.state_dir.
+const FileName = "state.json"
+
+// EntityRetention is how long an idle entity of an alert with events.key is
+// kept in memory after its last event before Save drops it.
+const EntityRetention = time.Hour
+
+// formatVersion is bumped whenever the on-disk layout changes incompatibly.
+const formatVersion = 1
+
+// Entry holds the runtime state shared by all triggers of one alert.
+type Entry struct {
+	State    *runner.AlertState // nil when events.healthy is not configured
+	Cooldown *cooldown.State
+	Entities *runner.EntitySet // per-entity state; nil unless events.key is set
+}
+
+// Store keeps per-alert runtime state keyed by alert name, so it survives
+// SIGHUP reloads and, when backed by a directory, daemon restarts.
+type Store struct {
+	path   string // empty = in-memory only
+	mu     sync.Mutex
+	alerts map[string]*Entry
+	saved  []byte // encoded alerts of the last write, to skip unchanged saves
+}
+
+type snapshot struct {
+	Version int                      `json:"version"`
+	SavedAt time.Time                `json:"saved_at"`
+	Alerts  map[string]alertSnapshot `json:"alerts"`
+}
+
+type alertSnapshot struct {
+	State     *runner.AlertState        `json:"state,omitempty"`
+	Cooldowns map[string]cooldown.Timer `json:"cooldowns,omitempty"`
+	Entities  map[string]entitySnapshot `json:"entities,omitempty"`
+}
+
+type entitySnapshot struct {
+	State     *runner.AlertState        `json:"state,omitempty"`
+	Cooldowns map[string]cooldown.Timer `json:"cooldowns,omitempty"`
+}
+
+// New creates an empty Store backed by dir/state.json. An empty dir yields
+// an in-memory store that never persists.
+func New(dir string) *Store {
+	s := &Store{alerts: make(map[string]*Entry)}
+	if dir != "" {
+		s.path = filepath.Join(dir, FileName)
+	}
+	return s
+}
+
+// Load restores a previously saved snapshot. A missing file is not an error.
+func (s *Store) Load() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading state: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("parsing state %s: %w", s.path, err)
+	}
+	if snap.Version != formatVersion {
+		return fmt.Errorf("state %s: unsupported version %d", s.path, snap.Version)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, a := range snap.Alerts {
+		e := &Entry{State: a.State, Cooldown: restoreCooldown(a.Cooldowns)}
+		if len(a.Entities) > 0 {
+			e.Entities = runner.NewEntitySet()
+			for key, es := range a.Entities {
+				e.Entities.Put(key, &runner.Entity{State: es.State, Cooldown: restoreCooldown(es.Cooldowns)})
+			}
+		}
+		s.alerts[name] = e
+	}
+	return nil
+}
+
+// Path returns the snapshot file path, or "" for an in-memory store.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Entry returns the state for alert, creating it on first use. The state
+// machine is attached or detached to match the alert's current config, so
+// toggling events.healthy across a reload behaves like a fresh start.
+func (s *Store) Entry(alert *config.Alert) *Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.alerts[alert.Name]
+	if !ok {
+		e = &Entry{Cooldown: cooldown.New(nil)}
+		s.alerts[alert.Name] = e
+	}
+
+	tracked := alert.Events != nil && len(alert.Events.Healthy) > 0
+	switch {
+	case tracked && e.State == nil:
+		e.State = &runner.AlertState{Healthy: true}
+	case !tracked && e.State != nil:
+		e.State = nil
+	}
+
+	keyed := alert.Events != nil && len(alert.Events.Key) > 0
+	switch {
+	case keyed && e.Entities == nil:
+		e.Entities = runner.NewEntitySet()
+	case !keyed && e.Entities != nil:
+		e.Entities = nil
+	}
+	return e
+}
+
+// Lookup returns the state of an alert without creating it. It implements
+// runner.StateLookup for depends_on.
+func (s *Store) Lookup(alert string) (*runner.AlertState, *runner.EntitySet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.alerts[alert]
+	if !ok {
+		return nil, nil
+	}
+	return e.State, e.Entities
+}
+
+// Prune drops state for alerts that are no longer in alerts.
+func (s *Store) Prune(alerts []config.Alert) {
+	keep := make(map[string]bool, len(alerts))
+	for _, a := range alerts {
+		keep[a.Name] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name := range s.alerts {
+		if !keep[name] {
+			delete(s.alerts, name)
+		}
+	}
+}
+
+// Save drops idle entities older than EntityRetention and atomically writes
+// the current state to disk. Writing is skipped for in-memory stores and
+// when nothing has changed since the last write, so Save can be called
+// after every run.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-EntityRetention)
+	for _, e := range s.alerts {
+		if e.Entities != nil {
+			e.Entities.Prune(cutoff)
+		}
+	}
+	if s.path == "" {
+		return nil
+	}
+
+	alerts := make(map[string]alertSnapshot, len(s.alerts))
+	for name, e := range s.alerts {
+		as := alertSnapshot{
+			State:     e.State,
+			Cooldowns: e.Cooldown.Snapshot(),
+		}
+		if e.Entities != nil {
+			as.Entities = make(map[string]entitySnapshot)
+			e.Entities.Each(func(key string, ent *runner.Entity) {
+				as.Entities[key] = entitySnapshot{State: ent.State, Cooldowns: ent.Cooldown.Snapshot()}
+			})
+		}
+		alerts[name] = as
+	}
+
+	encoded, err := json.Marshal(alerts)
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+	if bytes.Equal(encoded, s.saved) {
+		return nil
+	}
+
+	snap := snapshot{Version: formatVersion, SavedAt: time.Now().UTC(), Alerts: alerts}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	s.saved = encoded
+	return nil
+}
+
+func restoreCooldown(timers map[string]cooldown.Timer) *cooldown.State {
+	cd := cooldown.New(nil)
+	cd.Restore(timers)
+	return cd
+}
@@ -0,0 +1,199 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sznuper/sznuper/internal/config"
+)
+
+func healthyAlert(name string) config.Alert {
+	return config.Alert{Name: name, Events: &config.Events{Healthy: []string{"ok"}}}
+}
+
+func TestStore_SaveLoadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	disk := healthyAlert("disk")
+	ssh := config.Alert{Name: "ssh"}
+
+	s := New(dir)
+	e := s.Entry(&disk)
+	e.State.Healthy = false
+	e.Cooldown.Check("high_usage", time.Hour)
+	s.Entry(&ssh).Cooldown.Check("login", time.Hour)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	restored := New(dir)
+	if err := restored.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	re := restored.Entry(&disk)
+	if re.State == nil || re.State.Healthy {
+		t.Errorf("disk state = %+v, want unhealthy", re.State)
+	}
+	if re.Cooldown.Check("high_usage", time.Hour) {
+		t.Error("want high_usage cooldown restored")
+	}
+	if restored.Entry(&ssh).Cooldown.Check("login", time.Hour) {
+		t.Error("want login cooldown restored")
+	}
+	if restored.Entry(&ssh).State != nil {
+		t.Error("want nil state for alert without events.healthy")
+	}
+}
+
+func TestStore_LoadMissingFile(t *testing.T) {
+	s := New(t.TempDir())
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+}
+
+func TestStore_LoadCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(dir).Load(); err == nil {
+		t.Fatal("expected error for corrupt state")
+	}
+}
+
+func TestStore_InMemoryNeverWrites(t *testing.T) {
+	s := New("")
+	a := healthyAlert("disk")
+	s.Entry(&a)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if s.Path() != "" {
+		t.Errorf("path = %q, want empty", s.Path())
+	}
+}
+
+func TestStore_SaveSkipsUnchangedState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	s := New(dir)
+	a := healthyAlert("disk")
+	e := s.Entry(&a)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("unchanged state was written again (stat error %v)", err)
+	}
+
+	e.State.Healthy = false
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("changed state was not written: %v", err)
+	}
+}
+
+func TestStore_EntryReusedAcrossCalls(t *testing.T) {
+	s := New("")
+	a := healthyAlert("disk")
+	first := s.Entry(&a)
+	first.State.Healthy = false
+	if second := s.Entry(&a); second != first {
+		t.Error("want the same entry for the same alert name")
+	}
+}
+
+func TestStore_EntryFollowsConfigChanges(t *testing.T) {
+	s := New("")
+	a := config.Alert{Name: "disk"}
+	if s.Entry(&a).State != nil {
+		t.Fatal("want nil state without events.healthy")
+	}
+
+	a.Events = &config.Events{Healthy: []string{"ok"}}
+	e := s.Entry(&a)
+	if e.State == nil || !e.State.Healthy {
+		t.Fatalf("state = %+v, want healthy", e.State)
+	}
+
+	a.Events = nil
+	if s.Entry(&a).State != nil {
+		t.Error("want state dropped once events.healthy is removed")
+	}
+}
+
+func TestStore_PruneRemovedAlerts(t *testing.T) {
+	dir := t.TempDir()
+	keep := healthyAlert("keep")
+	gone := healthyAlert("gone")
+
+	s := New(dir)
+	s.Entry(&keep).State.Healthy = false
+	s.Entry(&gone).State.Healthy = false
+	s.Prune([]config.Alert{keep})
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New(dir)
+	if err := restored.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Entry(&keep).State.Healthy {
+		t.Error("want kept alert to stay unhealthy")
+	}
+	if !restored.Entry(&gone).State.Healthy {
+		t.Error("want pruned alert to start fresh")
+	}
+}
+
+func TestStore_EntitiesRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	a := healthyAlert("units")
+	a.Events.Key = config.FieldList{"unit"}
+
+	s := New(dir)
+	e := s.Entry(&a)
+	if e.Entities == nil {
+		t.Fatal("want entity set for keyed alert")
+	}
+	nginx := e.Entities.Get("nginx", true)
+	nginx.State.Healthy = false
+	nginx.Cooldown.Check("failed", time.Hour)
+	e.Entities.Get("idle", true)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New(dir)
+	if err := restored.Load(); err != nil {
+		t.Fatal(err)
+	}
+	re := restored.Entry(&a).Entities.Get("nginx", true)
+	if re.State.Healthy {
+		t.Error("want nginx entity restored unhealthy")
+	}
+	if re.Cooldown.Check("failed", time.Hour) {
+		t.Error("want nginx cooldown restored")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"idle"`) {
+		t.Error("idle entity should not be persisted")
+	}
+}